@@ -0,0 +1,241 @@
+package goliteflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// WorkflowBuilder defines a workflow in Go:
+//
+//	workflow, err := goliteflow.NewWorkflow("etl").
+//		Schedule("0 2 * * *").
+//		Task(goliteflow.NewTask("extract").Command("python extract.py").Retry(3)).
+//		Task(goliteflow.NewTask("load").Func("load").DependsOn("extract")).
+//		Build()
+type WorkflowBuilder struct {
+	workflow Workflow
+}
+
+// NewWorkflow starts the definition of a workflow
+func NewWorkflow(name string) *WorkflowBuilder {
+	return &WorkflowBuilder{workflow: Workflow{Name: name}}
+}
+
+// Schedule sets the cron expression the workflow runs on
+func (b *WorkflowBuilder) Schedule(schedule string) *WorkflowBuilder {
+	b.workflow.Schedule = schedule
+	return b
+}
+
+// TriggeredBy runs the workflow when another workflow finishes with the given
+// status: TriggerOnCompleted, TriggerOnFailed or TriggerOnAny
+func (b *WorkflowBuilder) TriggeredBy(workflow, status string) *WorkflowBuilder {
+	b.workflow.TriggeredBy = append(b.workflow.TriggeredBy, WorkflowTrigger{Workflow: workflow, Status: status})
+	return b
+}
+
+// Param sets the default value of a param
+func (b *WorkflowBuilder) Param(name, value string) *WorkflowBuilder {
+	if b.workflow.Params == nil {
+		b.workflow.Params = make(map[string]string)
+	}
+	b.workflow.Params[name] = value
+	return b
+}
+
+// Resources sets the default resource limits of every task
+func (b *WorkflowBuilder) Resources(resources Resources) *WorkflowBuilder {
+	b.workflow.Resources = &resources
+	return b
+}
+
+// RunAs sets the default user and group of every task; group may be empty
+func (b *WorkflowBuilder) RunAs(user, group string) *WorkflowBuilder {
+	b.workflow.RunAs = &RunAs{User: user, Group: group}
+	return b
+}
+
+// Sandbox sets the default sandbox of every task
+func (b *WorkflowBuilder) Sandbox(sandbox Sandbox) *WorkflowBuilder {
+	b.workflow.Sandbox = &sandbox
+	return b
+}
+
+// RunsOn sets the default worker labels of every task
+func (b *WorkflowBuilder) RunsOn(labels ...string) *WorkflowBuilder {
+	b.workflow.RunsOn = labels
+	return b
+}
+
+// Notifications sets the channels notified about the workflow's runs
+func (b *WorkflowBuilder) Notifications(notifications Notifications) *WorkflowBuilder {
+	b.workflow.Notifications = &notifications
+	return b
+}
+
+// SLA sets the expected duration, deadline and reliability of runs
+func (b *WorkflowBuilder) SLA(sla SLA) *WorkflowBuilder {
+	b.workflow.SLA = &sla
+	return b
+}
+
+// Task adds tasks to the workflow
+func (b *WorkflowBuilder) Task(tasks ...*TaskBuilder) *WorkflowBuilder {
+	for _, task := range tasks {
+		b.workflow.Tasks = append(b.workflow.Tasks, task.Build())
+	}
+	return b
+}
+
+// Build validates the workflow on its own. References to other workflows and
+// pools are checked when it is added with AddWorkflow. Workflows without a
+// schedule or triggers are validated as sub-workflows.
+func (b *WorkflowBuilder) Build() (Workflow, error) {
+	workflow := b.workflow
+	workflow.Tasks = append([]Task(nil), b.workflow.Tasks...)
+
+	yamlParser := parser.NewYAMLParser()
+	var err error
+	if workflow.Schedule == "" && len(workflow.TriggeredBy) == 0 {
+		err = yamlParser.ValidateSubWorkflow(&workflow, 0)
+	} else {
+		err = yamlParser.ValidateWorkflow(&workflow, 0)
+	}
+	if err != nil {
+		return Workflow{}, fmt.Errorf("invalid workflow '%s': %w", workflow.Name, err)
+	}
+	return workflow, nil
+}
+
+// MustBuild is like Build but panics if the workflow is invalid
+func (b *WorkflowBuilder) MustBuild() Workflow {
+	workflow, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return workflow
+}
+
+// TaskBuilder defines a task for WorkflowBuilder.Task
+type TaskBuilder struct {
+	task Task
+}
+
+// NewTask starts the definition of a task
+func NewTask(id string) *TaskBuilder {
+	return &TaskBuilder{task: Task{ID: id}}
+}
+
+// Command sets the command the task runs
+func (b *TaskBuilder) Command(command string) *TaskBuilder {
+	b.task.Command = command
+	return b
+}
+
+// Func runs a function registered with RegisterTaskFunc instead of a command
+func (b *TaskBuilder) Func(name string) *TaskBuilder {
+	b.task.Func = name
+	return b
+}
+
+// HTTP sends a request instead of running a command
+func (b *TaskBuilder) HTTP(request HTTPRequest) *TaskBuilder {
+	b.task.HTTP = &request
+	return b
+}
+
+// Image runs the command in a container of the image
+func (b *TaskBuilder) Image(image string) *TaskBuilder {
+	b.task.Image = image
+	return b
+}
+
+// Container sets the runtime, mounts, env and working directory of the container
+func (b *TaskBuilder) Container(container Container) *TaskBuilder {
+	b.task.Container = &container
+	return b
+}
+
+// SubWorkflow runs another workflow as a child run instead of a command
+func (b *TaskBuilder) SubWorkflow(name string) *TaskBuilder {
+	b.task.Workflow = name
+	return b
+}
+
+// Param sets a param passed to the sub-workflow
+func (b *TaskBuilder) Param(name, value string) *TaskBuilder {
+	if b.task.Params == nil {
+		b.task.Params = make(map[string]string)
+	}
+	b.task.Params[name] = value
+	return b
+}
+
+// DependsOn adds tasks that must succeed before this one runs
+func (b *TaskBuilder) DependsOn(ids ...string) *TaskBuilder {
+	b.task.DependsOn = append(b.task.DependsOn, ids...)
+	return b
+}
+
+// Retry sets the number of attempts
+func (b *TaskBuilder) Retry(attempts int) *TaskBuilder {
+	b.task.Retry = attempts
+	return b
+}
+
+// Timeout bounds all attempts of the task
+func (b *TaskBuilder) Timeout(timeout time.Duration) *TaskBuilder {
+	b.task.Timeout = timeout.String()
+	return b
+}
+
+// WaitFor waits for a successful run of another workflow before the task runs
+func (b *TaskBuilder) WaitFor(dependency ExternalDependency) *TaskBuilder {
+	b.task.WaitFor = &dependency
+	return b
+}
+
+// Resources sets the resource limits of the task
+func (b *TaskBuilder) Resources(resources Resources) *TaskBuilder {
+	b.task.Resources = &resources
+	return b
+}
+
+// RunAs sets the user and group of the task; group may be empty
+func (b *TaskBuilder) RunAs(user, group string) *TaskBuilder {
+	b.task.RunAs = &RunAs{User: user, Group: group}
+	return b
+}
+
+// Sandbox sets the sandbox of the task
+func (b *TaskBuilder) Sandbox(sandbox Sandbox) *TaskBuilder {
+	b.task.Sandbox = &sandbox
+	return b
+}
+
+// RunsOn sets the labels a worker needs to run the task; without labels the
+// scheduler runs it
+func (b *TaskBuilder) RunsOn(labels ...string) *TaskBuilder {
+	b.task.RunsOn = labels
+	return b
+}
+
+// SLA sets the expected duration, deadline and reliability of the task
+func (b *TaskBuilder) SLA(sla SLA) *TaskBuilder {
+	b.task.SLA = &sla
+	return b
+}
+
+// Pool makes the task take slots of a pool of the configuration while it
+// runs; slots of 0 means one
+func (b *TaskBuilder) Pool(name string, slots int) *TaskBuilder {
+	b.task.Pool, b.task.PoolSlots = name, slots
+	return b
+}
+
+// Build returns the task
+func (b *TaskBuilder) Build() Task {
+	return b.task
+}
@@ -0,0 +1,138 @@
+package goliteflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkflowBuilder_Build(t *testing.T) {
+	workflow, err := NewWorkflow("etl").
+		Schedule("0 2 * * *").
+		Param("env", "prod").
+		RunsOn("etl").
+		Task(
+			NewTask("extract").Command("echo extract {{ .Params.env }}").Retry(3).Timeout(10*time.Minute).RunsOn("gpu-free", "etl"),
+			NewTask("load").Func("load").DependsOn("extract"),
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if workflow.Name != "etl" || workflow.Params["env"] != "prod" || len(workflow.Tasks) != 2 {
+		t.Fatalf("Unexpected workflow: %+v", workflow)
+	}
+	if workflow.RunsOn[0] != "etl" || len(workflow.Tasks[0].RunsOn) != 2 {
+		t.Errorf("Unexpected labels: workflow %v, task %v", workflow.RunsOn, workflow.Tasks[0].RunsOn)
+	}
+	if workflow.Tasks[0].Timeout != "10m0s" || workflow.Tasks[1].DependsOn[0] != "extract" {
+		t.Errorf("Unexpected tasks: %+v", workflow.Tasks)
+	}
+}
+
+func TestWorkflowBuilder_Build_Invalid(t *testing.T) {
+	_, err := NewWorkflow("broken").
+		Schedule("not cron").
+		Task(NewTask("a").DependsOn("missing")).
+		Build()
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	if len(validationErrs) != 3 {
+		t.Errorf("Expected schedule, command and dependency errors, got %v", validationErrs)
+	}
+
+	// Without a schedule or triggers a workflow is a sub-workflow
+	if _, err := NewWorkflow("child").Task(NewTask("a").Command("echo a")).Build(); err != nil {
+		t.Errorf("Expected a valid sub-workflow, got %v", err)
+	}
+}
+
+func TestGoliteFlow_LoadConfigBytes(t *testing.T) {
+	gf := New()
+	err := gf.LoadConfigFromReader(strings.NewReader(`version: "1.0"
+workflows:
+  - name: from_reader
+    schedule: "0 0 * * *"
+    tasks:
+      - id: a
+        command: echo a
+`))
+	if err != nil || gf.config.Workflows[0].Name != "from_reader" {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+
+	if err := gf.LoadConfigBytes([]byte("version: \"1.0\"\nworkflows: []\n")); err == nil {
+		t.Error("Expected an error for a configuration without workflows")
+	}
+}
+
+func TestGoliteFlow_AddRemoveWorkflow(t *testing.T) {
+	gf := New()
+	if err := gf.LoadConfig("testdata/simple-workflow.yml"); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := gf.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer gf.Stop()
+
+	child := NewWorkflow("child").Task(NewTask("step").Command("echo child")).MustBuild()
+	parent := NewWorkflow("parent").
+		Schedule("*/5 * * * *").
+		Task(NewTask("call").SubWorkflow("child")).
+		MustBuild()
+
+	// The sub-workflow alone has neither a schedule nor a caller
+	if err := gf.AddWorkflow(child); err == nil {
+		t.Fatal("Expected an error for a workflow that never runs")
+	}
+	if err := gf.AddWorkflow(parent, child); err != nil {
+		t.Fatalf("AddWorkflow() error = %v", err)
+	}
+	if err := gf.AddWorkflow(parent); err == nil || !strings.Contains(err.Error(), "duplicate workflow name 'parent'") {
+		t.Errorf("Expected a duplicate name error, got %v", err)
+	}
+
+	if _, ok := gf.GetNextRunTimes()["parent"]; !ok {
+		t.Error("Expected the added workflow to be scheduled")
+	}
+	execution, err := gf.scheduler.ExecuteWorkflowNow("parent")
+	if err != nil || execution.Status != "completed" {
+		t.Fatalf("Expected the added workflow to run, got %v %+v", err, execution)
+	}
+
+	if err := gf.RemoveWorkflow("child"); err == nil {
+		t.Error("Expected an error removing a workflow that is still called")
+	}
+	if err := gf.RemoveWorkflow("parent"); err == nil {
+		t.Error("Expected an error leaving a sub-workflow that never runs")
+	}
+	if err := gf.RemoveWorkflow("parent", "child"); err != nil {
+		t.Fatalf("RemoveWorkflow() error = %v", err)
+	}
+	if _, ok := gf.GetNextRunTimes()["parent"]; ok {
+		t.Error("Expected the removed workflow to be unscheduled")
+	}
+	if len(gf.config.Workflows) != 1 || len(gf.scheduler.GetWorkflows()) != 1 {
+		t.Errorf("Expected only the loaded workflow to remain, got %d", len(gf.config.Workflows))
+	}
+}
+
+func TestGoliteFlow_AddWorkflow_WithoutConfig(t *testing.T) {
+	gf := New()
+	gf.RegisterTaskFunc("noop", func(ctx context.Context, tc TaskContext) (Outputs, error) { return nil, nil })
+	workflow := NewWorkflow("only").Schedule("0 0 * * *").Task(NewTask("a").Func("noop")).MustBuild()
+
+	if err := gf.AddWorkflow(workflow); err != nil {
+		t.Fatalf("AddWorkflow() error = %v", err)
+	}
+	if err := gf.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sintakaridina/goliteflow/internal/control"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/notify"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/scheduler"
+	"github.com/sintakaridina/goliteflow/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// statusFormat is the status output format, kept apart from the format of
+// validate and lint so its table default does not become theirs
+var statusFormat string
+
+// lockFileName is the name of the daemon's lock file in the state directory
+const lockFileName = "goliteflow.lock"
+
+// daemonLockFile returns the lock file given with --lock-file, or the one of the state directory
+func daemonLockFile() string {
+	if lockFile != "" {
+		return lockFile
+	}
+	return filepath.Join(stateDir, lockFileName)
+}
+
+// socketPath returns the control socket given with --socket, or the one of the state directory
+func socketPath() string {
+	if socketFile != "" {
+		return socketFile
+	}
+	return control.SocketPath(stateDir)
+}
+
+// connectDaemon connects to the running daemon. It returns a nil client when
+// no daemon is running, so the command can fall back to working offline.
+func connectDaemon(cmd *cobra.Command) (*control.Client, error) {
+	client, err := control.Dial(socketPath())
+	if errors.Is(err, control.ErrNoDaemon) {
+		return nil, nil
+	}
+	if err != nil {
+		cmd.SilenceUsage = true
+		return nil, err
+	}
+	return client, nil
+}
+
+// dialDaemon connects to the running daemon, for commands that need one
+func dialDaemon(cmd *cobra.Command) (*control.Client, error) {
+	client, err := control.Dial(socketPath())
+	if err != nil {
+		cmd.SilenceUsage = true
+		return nil, err
+	}
+	return client, nil
+}
+
+// loadConfig parses the configuration file for commands working offline
+func loadConfig() (*parser.WorkflowConfig, error) {
+	config, err := parser.NewYAMLParser().ParseFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	return config, nil
+}
+
+// findWorkflow checks that the configuration defines a workflow
+func findWorkflow(config *parser.WorkflowConfig, name string) error {
+	for _, workflow := range config.Workflows {
+		if workflow.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("workflow '%s' not found in %s", name, configFile)
+}
+
+func retryRun(cmd *cobra.Command, args []string) error {
+	if verbose {
+		logger.SetGlobalLevel(zerolog.DebugLevel)
+	} else {
+		logger.SetGlobalLevel(zerolog.InfoLevel)
+	}
+	log := logger.GetGlobalLogger()
+
+	client, err := connectDaemon(cmd)
+	if err != nil {
+		return err
+	}
+
+	var execution *parser.WorkflowExecution
+	if client != nil {
+		defer client.Close()
+		cmd.SilenceUsage = true
+		log.Debugf("Retrying run %s in the daemon", args[0])
+		if execution, err = client.Retry(args[0], retryFrom); err != nil {
+			return err
+		}
+	} else {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		history := store.NewFileStore(stateDir)
+		original, err := history.Get(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load run '%s': %w", args[0], err)
+		}
+		if original == nil {
+			return fmt.Errorf("run '%s' not found", args[0])
+		}
+
+		// Only the workflows the retry can run are checked for the
+		// privileges and functions their tasks need
+		sched := scheduler.NewScheduler()
+		sched.SetStore(history)
+		sched.SetNotifier(notify.NewNotifier(config.Notifications, sched))
+		sched.SetLimits(config.MaxConcurrentTasks, config.Pools)
+		if err := sched.AddWorkflows(reachableWorkflows(config.Workflows, original.WorkflowID)); err != nil {
+			return fmt.Errorf("failed to add workflows to scheduler: %w", err)
+		}
+		defer sched.Stop()
+
+		cmd.SilenceUsage = true
+		if execution, err = sched.RetryExecution(args[0], retryFrom); err != nil {
+			return err
+		}
+	}
+
+	reused := 0
+	for _, result := range execution.TaskResults {
+		if result.Reused {
+			reused++
+		}
+	}
+	log.WithExecution(execution.ID).Infof("Retry of run %s of workflow '%s' %s (%d task(s) reused, %d run)",
+		args[0], execution.WorkflowID, execution.Status, reused, len(execution.TaskResults)-reused)
+	if execution.Status != "completed" {
+		return fmt.Errorf("retry %s: %s", execution.Status, execution.ErrorMessage)
+	}
+	return nil
+}
+
+func cancelRun(cmd *cobra.Command, args []string) error {
+	// Only the daemon running it can cancel a run
+	client, err := dialDaemon(cmd)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	cmd.SilenceUsage = true
+	if err := client.Cancel(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Cancelled run %s\n", args[0])
+	return nil
+}
+
+func pauseWorkflow(cmd *cobra.Command, args []string) error {
+	return setPaused(cmd, args[0], true)
+}
+
+func resumeWorkflow(cmd *cobra.Command, args []string) error {
+	return setPaused(cmd, args[0], false)
+}
+
+// setPaused pauses or resumes a workflow in the daemon, or in the state
+// directory the daemon loads its pauses from when none is running
+func setPaused(cmd *cobra.Command, name string, paused bool) error {
+	verb := "Resumed"
+	if paused {
+		verb = "Paused"
+	}
+
+	client, err := connectDaemon(cmd)
+	if err != nil {
+		return err
+	}
+	if client != nil {
+		defer client.Close()
+		cmd.SilenceUsage = true
+		if paused {
+			err = client.Pause(name)
+		} else {
+			err = client.Resume(name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s workflow %s\n", verb, name)
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	if err := findWorkflow(config, name); err != nil {
+		return err
+	}
+	if err := store.NewFileStore(stateDir).SetPaused(name, paused); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s workflow %s (no daemon is running; applies when it starts)\n", verb, name)
+	return nil
+}
+
+func showStatus(cmd *cobra.Command, args []string) error {
+	if statusFormat != "table" && statusFormat != "json" {
+		return fmt.Errorf("unsupported format '%s' (expected table or json)", statusFormat)
+	}
+
+	client, err := connectDaemon(cmd)
+	if err != nil {
+		return err
+	}
+
+	var status *control.Status
+	if client != nil {
+		defer client.Close()
+		if status, err = client.Status(); err != nil {
+			return err
+		}
+	} else if status, err = offlineStatus(); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if statusFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(status); err != nil {
+			return fmt.Errorf("failed to write status: %w", err)
+		}
+		return nil
+	}
+
+	if status.PID == 0 {
+		fmt.Fprintf(out, "Daemon: not running, %d workflow(s) in %s\n\n", status.Workflows, configFile)
+	} else {
+		role := ""
+		if status.Role != "" {
+			role = ", " + status.Role
+		}
+		fmt.Fprintf(out, "Daemon: pid %d, up %s, %d workflow(s)%s\n\n", status.PID,
+			time.Since(status.StartedAt).Round(time.Second), status.Workflows, role)
+	}
+
+	if queue := status.Queue; queue != nil && (queue.MaxConcurrentTasks > 0 || len(queue.Pools) > 0) {
+		limit := ""
+		if queue.MaxConcurrentTasks > 0 {
+			limit = fmt.Sprintf(" (max %d)", queue.MaxConcurrentTasks)
+		}
+		fmt.Fprintf(out, "Tasks: %d running, %d queued%s\n", queue.Running, queue.Queued, limit)
+		if len(queue.Pools) > 0 {
+			names := make([]string, 0, len(queue.Pools))
+			for name := range queue.Pools {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(table, "POOL\tSLOTS\tUSED\tQUEUED")
+			for _, name := range names {
+				pool := queue.Pools[name]
+				fmt.Fprintf(table, "%s\t%d\t%d\t%d\n", name, pool.Slots, pool.Used, pool.Queued)
+			}
+			table.Flush()
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(status.Running) == 0 {
+		fmt.Fprintln(out, "No runs in progress")
+	} else {
+		table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "RUN ID\tWORKFLOW\tTRIGGER\tELAPSED\tTASK\tATTEMPT\tTASK ELAPSED")
+		for _, run := range status.Running {
+			task, attempt, taskElapsed := orDash(run.CurrentTask), "-", "-"
+			if run.CurrentTask != "" {
+				attempt = fmt.Sprint(run.Attempt)
+				taskElapsed = run.TaskElapsed.Round(time.Second).String()
+			}
+			if run.Queued {
+				task, attempt = run.CurrentTask+" (queued)", "-"
+			}
+			workflow := run.WorkflowID
+			if run.ParentRunID != "" {
+				workflow += " (sub-workflow of " + run.ParentRunID + ")"
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", run.RunID, workflow, orDash(run.Trigger),
+				run.Elapsed.Round(time.Second), task, attempt, taskElapsed)
+		}
+		table.Flush()
+	}
+
+	if len(status.NextRuns) > 0 {
+		names := make([]string, 0, len(status.NextRuns))
+		for name := range status.NextRuns {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return status.NextRuns[names[i]].Before(status.NextRuns[names[j]]) })
+		paused := make(map[string]bool, len(status.Paused))
+		for _, name := range status.Paused {
+			paused[name] = true
+		}
+
+		fmt.Fprintln(out)
+		table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "WORKFLOW\tNEXT RUN\tIN")
+		for _, name := range names {
+			next := status.NextRuns[name]
+			if paused[name] {
+				fmt.Fprintf(table, "%s\tpaused\t-\n", name)
+				continue
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\n", name, next.Format("2006-01-02 15:04:05 MST"), time.Until(next).Round(time.Second))
+		}
+		table.Flush()
+	}
+	return nil
+}
+
+// offlineStatus computes the status from the configuration and the state
+// directory when no daemon is running. Its PID is 0 and nothing is running.
+// Next runs come from the schedules alone, so tasks that need privileges or
+// registered functions do not stop the status from being shown.
+func offlineStatus() (*control.Status, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	paused, err := store.NewFileStore(stateDir).Paused()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	nextRuns := make(map[string]time.Time)
+	for _, workflow := range config.Workflows {
+		if next := scheduler.NextRunTimes(workflow.Schedule, now, 1); len(next) > 0 {
+			nextRuns[workflow.Name] = next[0]
+		}
+	}
+
+	return &control.Status{
+		Workflows: len(config.Workflows),
+		Running:   []scheduler.RunningExecution{},
+		NextRuns:  nextRuns,
+		Paused:    paused,
+	}, nil
+}
+
+// reachableWorkflows returns the workflow name and the workflows a run of it
+// can start: its sub-workflows and the workflows its runs trigger, in turn
+func reachableWorkflows(workflows []parser.Workflow, name string) []parser.Workflow {
+	byName := make(map[string]parser.Workflow, len(workflows))
+	for _, workflow := range workflows {
+		byName[workflow.Name] = workflow
+	}
+
+	reached := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		var next []string
+		for _, task := range byName[current].Tasks {
+			if task.Workflow != "" {
+				next = append(next, task.Workflow)
+			}
+		}
+		for _, workflow := range workflows {
+			for _, trigger := range workflow.TriggeredBy {
+				if trigger.Workflow == current {
+					next = append(next, workflow.Name)
+				}
+			}
+		}
+		for _, n := range next {
+			if !reached[n] {
+				reached[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	var result []parser.Workflow
+	for _, workflow := range workflows {
+		if reached[workflow.Name] {
+			result = append(result, workflow)
+		}
+	}
+	return result
+}
+
+// loadHistory reads the runs recorded in the state directory, by workflow
+func loadHistory(history *store.FileStore) (map[string][]parser.WorkflowExecution, error) {
+	names, err := history.Workflows()
+	if err != nil {
+		return nil, err
+	}
+
+	executions := make(map[string][]parser.WorkflowExecution, len(names))
+	for _, name := range names {
+		runs, err := history.List(name)
+		if err != nil {
+			return nil, err
+		}
+		executions[name] = runs
+	}
+	return executions, nil
+}
+
+// workflowChanges compares the workflows of the configuration file with the
+// ones loaded by the daemon
+type workflowChanges struct {
+	Added   []string `json:"added"`   // in the file but not loaded by the daemon
+	Removed []string `json:"removed"` // loaded by the daemon but no longer in the file
+	Changed []string `json:"changed"` // defined differently in the file
+}
+
+// compareWorkflows lists the differences between the file's workflows and the daemon's
+func compareWorkflows(file, loaded []parser.Workflow) workflowChanges {
+	changes := workflowChanges{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	byName := make(map[string]parser.Workflow, len(loaded))
+	for _, workflow := range loaded {
+		byName[workflow.Name] = workflow
+	}
+	for _, workflow := range file {
+		current, ok := byName[workflow.Name]
+		if !ok {
+			changes.Added = append(changes.Added, workflow.Name)
+			continue
+		}
+		delete(byName, workflow.Name)
+
+		// The daemon's definitions went through JSON, so compare them as JSON
+		want, _ := json.Marshal(workflow)
+		got, _ := json.Marshal(current)
+		if string(want) != string(got) {
+			changes.Changed = append(changes.Changed, workflow.Name)
+		}
+	}
+	for name := range byName {
+		changes.Removed = append(changes.Removed, name)
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+	return changes
+}
+
+// daemonChanges compares the configuration with the running daemon, if any
+func daemonChanges(cmd *cobra.Command, config *parser.WorkflowConfig) *workflowChanges {
+	client, err := connectDaemon(cmd)
+	if err != nil || client == nil {
+		return nil
+	}
+	defer client.Close()
+
+	loaded, err := client.Workflows()
+	if err != nil {
+		return nil
+	}
+	changes := compareWorkflows(config.Workflows, loaded)
+	return &changes
+}
@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/sintakaridina/goliteflow/internal/control"
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/graph"
+	"github.com/sintakaridina/goliteflow/internal/leader"
+	"github.com/sintakaridina/goliteflow/internal/lint"
 	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/notify"
 	"github.com/sintakaridina/goliteflow/internal/parser"
 	"github.com/sintakaridina/goliteflow/internal/reporter"
 	"github.com/sintakaridina/goliteflow/internal/scheduler"
+	"github.com/sintakaridina/goliteflow/internal/store"
+	"github.com/sintakaridina/goliteflow/internal/worker"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +36,21 @@ var (
 	verbose    bool
 	daemon     bool
 	version    bool
+	format     string
+	lintConfig string
+	failOn     string
+	dryRun     bool
+	planFormat string
+	planRuns   int
+	stateDir   string
+	graphFmt   string
+	graphOut   string
+	withStatus bool
+	retryFrom  string
+	socketFile string
+	lockFile   string
+	leaseFile  string
+	leaseTTL   time.Duration
 )
 
 func main() {
@@ -58,8 +87,9 @@ var runCmd = &cobra.Command{
 var reportCmd = &cobra.Command{
 	Use:   "report",
 	Short: "Generate HTML report from execution data",
-	Long:  `Generate an HTML report containing workflow execution history and statistics.`,
-	RunE:  generateReport,
+	Long: `Generate an HTML report containing the workflow execution history recorded
+in the state directory, and the runs in progress when a daemon is running.`,
+	RunE: generateReport,
 }
 
 var validateCmd = &cobra.Command{
@@ -69,26 +99,181 @@ var validateCmd = &cobra.Command{
 	RunE:  validateConfig,
 }
 
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long: `Print the JSON Schema of the workflow configuration file. Point your editor
+at it to get autocompletion and validation, e.g. with the VS Code YAML extension.`,
+	RunE: printSchema,
+}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check workflow configuration against best practices",
+	Long: `Check a valid workflow configuration for common mistakes such as missing
+timeouts, excessive retries, unreachable tasks, too-frequent schedules and
+missing scripts. Rules and severities can be configured in .goliteflow-lint.yml.`,
+	RunE: lintWorkflows,
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan [workflow...]",
+	Short: "Show what workflows would run without running them",
+	Long: `Show the execution plan of each workflow without running anything: the
+templated commands, the parallel stages of the dependency graph, effective
+timeouts and retry policies, and the next scheduled runs.`,
+	RunE: planWorkflows,
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [workflow]",
+	Short: "Export the task dependency graph of a workflow",
+	Long: `Render the task dependency graph of a workflow as Graphviz DOT, Mermaid
+or a standalone SVG image. With --status, tasks are coloured by the result
+of the workflow's last recorded run.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: exportGraph,
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the runs in progress and the next scheduled runs",
+	Long: `Ask the daemon started with 'run --daemon' for the same state directory
+which runs are in progress, the task each one is running with its attempt and
+elapsed time, and when each workflow runs next. Without a daemon, the next runs
+are computed from the configuration file.`,
+	RunE: showStatus,
+}
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel <run-id>",
+	Short: "Cancel a run in progress in a running daemon",
+	Long: `Cancel one run of a running daemon: its current task is killed, the
+remaining tasks are skipped and the run is recorded as cancelled. Other runs
+and the daemon carry on. Run IDs are listed by 'goliteflow status'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: cancelRun,
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <workflow>",
+	Short: "Stop the scheduled and triggered runs of a workflow",
+	Long: `Pause a workflow. Its cron and triggered runs are skipped until it is
+resumed; runs in progress finish. The pause is kept in the state directory and
+outlasts restarts; without a running daemon it applies when the daemon starts.`,
+	Args: cobra.ExactArgs(1),
+	RunE: pauseWorkflow,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <workflow>",
+	Short: "Resume a paused workflow",
+	Long:  `Let a paused workflow run on its schedule and triggers again.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  resumeWorkflow,
+}
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <run-id>",
+	Short: "Run a finished run again from its failed task",
+	Long: `Load a run from the state directory and run it again from the task that
+failed, or from --from. That task and the tasks downstream of it run again with
+the run's params; the other tasks that succeeded are reused, outputs included.
+The new run is recorded with retry_of set to the original run. A running daemon
+performs the retry; otherwise it runs in this process.`,
+	Args: cobra.ExactArgs(1),
+	RunE: retryRun,
+}
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run the tasks of a scheduler on this host",
+	Long: `Claim tasks from a scheduler started with 'run --worker-listen' and run
+them on this host. A worker runs the tasks whose runs_on labels are all among
+its --labels, up to --concurrency at a time, and sends heartbeats while it runs
+them. When a worker stops sending heartbeats its tasks are handed to another
+worker. On SIGINT or SIGTERM it stops claiming tasks and finishes the ones in
+progress.`,
+	RunE: runWorker,
+}
+
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "lite-workflows.yml", "Configuration file path")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().StringVar(&stateDir, "state-dir", store.DefaultDir, "Directory for run history")
+	rootCmd.PersistentFlags().StringVar(&socketFile, "socket", "", "Control socket of the daemon (default: "+control.SocketName+" in the state directory)")
 	rootCmd.Flags().BoolVar(&version, "version", false, "Show version information")
 
 	// Run command flags
 	runCmd.Flags().BoolVarP(&daemon, "daemon", "d", false, "Run as daemon (continuous execution)")
 	runCmd.Flags().StringVarP(&outputFile, "output", "o", "report.html", "Output file for HTML report")
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the execution plan instead of running workflows")
+	runCmd.Flags().StringVar(&planFormat, "format", "table", "Dry-run output format (table or json)")
+	runCmd.Flags().IntVar(&planRuns, "runs", 3, "Number of upcoming runs to show in the dry-run plan")
+	runCmd.Flags().StringVar(&lockFile, "lock-file", "", "Lock file that stops a second daemon from starting (default: "+lockFileName+" in the state directory)")
+	runCmd.Flags().StringVar(&leaseFile, "leader-lease", "", "Lease file on a shared filesystem; only the daemon holding it fires cron runs")
+	runCmd.Flags().DurationVar(&leaseTTL, "leader-ttl", leader.DefaultTTL, "How long the leader keeps the lease without renewing it")
+	runCmd.Flags().StringVar(&workerListen, "worker-listen", "", "Address to serve workers on, e.g. :7070; tasks with runs_on wait for a worker")
+	runCmd.Flags().StringVar(&workerToken, "worker-token", "", "Token workers must send (default: $"+worker.TokenEnv+")")
+	runCmd.Flags().BoolVar(&workerInsecure, "worker-insecure", false, "Serve workers without a token")
 
 	// Report command flags
 	reportCmd.Flags().StringVarP(&outputFile, "output", "o", "report.html", "Output file for HTML report")
 
+	// Validate command flags
+	validateCmd.Flags().StringVarP(&format, "format", "f", "text", "Output format (text or json)")
+
+	// Lint command flags
+	lintCmd.Flags().StringVarP(&format, "format", "f", "text", "Output format (text or json)")
+	lintCmd.Flags().StringVar(&lintConfig, "lint-config", "", "Lint configuration file (default: "+lint.DefaultConfigFile+" next to the config file or in the working directory)")
+	lintCmd.Flags().StringVar(&failOn, "fail-on", "error", "Exit with an error when findings reach this severity (info, warning, error or off)")
+
+	// Plan command flags
+	planCmd.Flags().StringVarP(&planFormat, "format", "f", "table", "Output format (table or json)")
+	planCmd.Flags().IntVar(&planRuns, "runs", 3, "Number of upcoming runs to show per workflow")
+
+	// Status command flags
+	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", "table", "Output format (table or json)")
+
+	// Retry command flags
+	retryCmd.Flags().StringVar(&retryFrom, "from", "", "Task to run again from (default: the task that failed)")
+
+	// Graph command flags
+	graphCmd.Flags().StringVarP(&graphFmt, "format", "f", "dot", "Output format (dot, mermaid or svg)")
+	graphCmd.Flags().StringVarP(&graphOut, "output", "o", "", "Write the graph to a file instead of stdout")
+	graphCmd.Flags().BoolVar(&withStatus, "status", false, "Colour tasks by the status of the last recorded run")
+
 	// Add commands
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(cancelCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(retryCmd)
+
+	// Worker command flags
+	workerCmd.Flags().StringVarP(&workerServer, "server", "s", "", "URL of the scheduler's worker API, e.g. http://scheduler:7070")
+	workerCmd.Flags().StringSliceVarP(&workerLabels, "labels", "l", nil, "Labels of the tasks to run, e.g. gpu-free,etl")
+	workerCmd.Flags().IntVar(&workerConcurrency, "concurrency", 1, "Number of tasks to run at once")
+	workerCmd.Flags().StringVar(&workerToken, "token", "", "Token the scheduler expects (default: $"+worker.TokenEnv+")")
+	workerCmd.Flags().StringVar(&workerID, "id", "", "Worker ID shown in the scheduler's logs (default: host:pid)")
+	workerCmd.MarkFlagRequired("server")
+	rootCmd.AddCommand(workerCmd)
 }
 
 func runWorkflows(cmd *cobra.Command, args []string) error {
+	// Nothing is logged in dry-run mode, so JSON plans stay parseable
+	if dryRun {
+		return planWorkflows(cmd, args)
+	}
+
 	// Initialize logger
 	if verbose {
 		logger.SetGlobalLevel(zerolog.DebugLevel)
@@ -108,8 +293,39 @@ func runWorkflows(cmd *cobra.Command, args []string) error {
 
 	log.Infof("Loaded %d workflows from %s", len(config.Workflows), configFile)
 
-	// Create scheduler
+	// One daemon per host and state directory, so no job runs twice
+	if daemon {
+		lock, err := leader.AcquireLockFile(daemonLockFile())
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		defer lock.Release()
+		log.Debugf("Holding lock file %s", lock.Path())
+	}
+
+	// Create scheduler, recording every run in the state directory
 	sched := scheduler.NewScheduler()
+	sched.SetStore(store.NewFileStore(stateDir))
+	sched.SetNotifier(notify.NewNotifier(config.Notifications, sched))
+	sched.SetLimits(config.MaxConcurrentTasks, config.Pools)
+	if daemon && leaseFile != "" {
+		// Daemons on several hosts share the lease; the others stand by
+		sched.SetElector(leader.NewFileLease(leaseFile, leaseTTL))
+		log.Infof("Electing a leader through %s", leaseFile)
+	}
+	if workerListen != "" {
+		// Tasks with runs_on labels go to workers, the others run here
+		dispatcher := worker.NewDispatcher(nil)
+		stopWorkers, err := serveWorkers(dispatcher)
+		if err != nil {
+			return err
+		}
+		defer stopWorkers()
+		sched.SetWorkQueue(dispatcher)
+	} else if usesWorkers(config) {
+		log.Warnf("Tasks with runs_on run in this process; start with --worker-listen to hand them to workers")
+	}
 
 	// Add workflows to scheduler
 	if err := sched.AddWorkflows(config.Workflows); err != nil {
@@ -141,26 +357,44 @@ func runWorkflows(cmd *cobra.Command, args []string) error {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				if err := generateHTMLReport(sched, outputFile); err != nil {
+				if err := generateHTMLReport(sched.GetWorkflows(), sched.GetAllExecutions(), outputFile); err != nil {
 					log.Errorf("Failed to generate report: %v", err)
 				}
 			case execution := <-sched.GetReportChannel():
-				log.Infof("Workflow '%s' completed with status: %s", execution.WorkflowID, execution.Status)
+				log.WithExecution(execution.ID).Infof("Workflow '%s' completed with status: %s", execution.WorkflowID, execution.Status)
 			}
 		}
 	}()
 
 	if daemon {
+		// Let status and other commands reach the daemon
+		server, err := control.Listen(socketPath(), sched)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		log.Infof("Control socket listening on %s", server.Path())
+
 		log.Info("Running in daemon mode. Press Ctrl+C to stop.")
 
 		// Wait for signal
 		<-sigChan
 		log.Info("Received shutdown signal, stopping...")
 	} else {
-		// Run once - execute all workflows immediately
+		// Run once - execute all workflows immediately, upstream workflows first
 		log.Info("Running workflows once...")
 
-		for _, workflow := range config.Workflows {
+		ordered, err := yamlParser.SortWorkflows(config)
+		if err != nil {
+			return fmt.Errorf("failed to order workflows: %w", err)
+		}
+
+		for _, workflow := range ordered {
+			// Triggered workflows and sub-workflows run as part of another run
+			if !workflow.IsEntryPoint() {
+				continue
+			}
+
 			log.Infof("Executing workflow: %s", workflow.Name)
 			execution, err := sched.ExecuteWorkflowNow(workflow.Name)
 			if err != nil {
@@ -168,11 +402,11 @@ func runWorkflows(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			log.Infof("Workflow '%s' completed with status: %s", workflow.Name, execution.Status)
+			log.WithExecution(execution.ID).Infof("Workflow '%s' completed with status: %s", workflow.Name, execution.Status)
 		}
 
 		// Generate final report
-		if err := generateHTMLReport(sched, outputFile); err != nil {
+		if err := generateHTMLReport(sched.GetWorkflows(), sched.GetAllExecutions(), outputFile); err != nil {
 			log.Errorf("Failed to generate final report: %v", err)
 		} else {
 			log.Infof("Report generated: %s", outputFile)
@@ -193,11 +427,57 @@ func generateReport(cmd *cobra.Command, args []string) error {
 	log := logger.GetGlobalLogger()
 	log.Info("Generating HTML report")
 
-	// For now, we'll create a mock scheduler with empty data
-	// In a real implementation, this would load execution data from storage
-	sched := scheduler.NewScheduler()
+	// A running daemon knows the workflows it runs and the runs in progress
+	client, err := connectDaemon(cmd)
+	if err != nil {
+		return err
+	}
+	var workflows []parser.Workflow
+	var running []scheduler.RunningExecution
+	if client != nil {
+		defer client.Close()
+		if workflows, err = client.Workflows(); err != nil {
+			return err
+		}
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		running = status.Running
+	} else if config, err := loadConfig(); err == nil {
+		workflows = config.Workflows
+	} else {
+		// The history is reported all the same, without task graphs
+		log.Debugf("Reporting without workflow definitions: %v", err)
+	}
+
+	executions, err := loadHistory(store.NewFileStore(stateDir))
+	if err != nil {
+		return fmt.Errorf("failed to load run history: %w", err)
+	}
+	recorded := make(map[string]bool)
+	for _, runs := range executions {
+		for _, execution := range runs {
+			recorded[execution.ID] = true
+		}
+	}
+	for _, run := range running {
+		// A run may have finished and been recorded since the daemon reported it
+		if recorded[run.RunID] {
+			continue
+		}
+		executions[run.WorkflowID] = append(executions[run.WorkflowID], parser.WorkflowExecution{
+			ID:         run.RunID,
+			ParentID:   run.ParentRunID,
+			Trigger:    run.Trigger,
+			WorkflowID: run.WorkflowID,
+			StartTime:  run.StartTime,
+			Duration:   run.Elapsed,
+			Status:     "running",
+		})
+	}
 
-	if err := generateHTMLReport(sched, outputFile); err != nil {
+	if err := generateHTMLReport(workflows, executions, outputFile); err != nil {
 		return fmt.Errorf("failed to generate report: %w", err)
 	}
 
@@ -205,7 +485,54 @@ func generateReport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// validationResult is the machine-readable output of the validate command
+type validationResult struct {
+	Valid     bool                     `json:"valid"`
+	File      string                   `json:"file"`
+	Errors    []parser.ValidationError `json:"errors"`
+	Workflows []string                 `json:"workflows,omitempty"`
+	Daemon    *workflowChanges         `json:"daemon,omitempty"` // differences with the running daemon, if any
+}
+
 func validateConfig(cmd *cobra.Command, args []string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format '%s' (expected text or json)", format)
+	}
+
+	// Parse and validate configuration
+	yamlParser := parser.NewYAMLParser()
+	config, err := yamlParser.ParseFile(configFile)
+
+	// Every problem is listed, not just the first one
+	var problems parser.ValidationErrors
+	if err != nil && !errors.As(err, &problems) {
+		problems = parser.ValidationErrors{{File: configFile, Message: err.Error()}}
+	}
+
+	if format == "json" {
+		result := validationResult{Valid: err == nil, File: configFile, Errors: problems}
+		if result.Errors == nil {
+			result.Errors = []parser.ValidationError{}
+		}
+		if config != nil {
+			for _, workflow := range config.Workflows {
+				result.Workflows = append(result.Workflows, workflow.Name)
+			}
+			result.Daemon = daemonChanges(cmd, config)
+		}
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(result); encodeErr != nil {
+			return fmt.Errorf("failed to write validation result: %w", encodeErr)
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("configuration has %d problem(s)", len(problems))
+		}
+		return nil
+	}
+
 	// Initialize logger
 	if verbose {
 		logger.SetGlobalLevel(zerolog.DebugLevel)
@@ -216,11 +543,12 @@ func validateConfig(cmd *cobra.Command, args []string) error {
 	log := logger.GetGlobalLogger()
 	log.Infof("Validating configuration file: %s", configFile)
 
-	// Parse and validate configuration
-	yamlParser := parser.NewYAMLParser()
-	config, err := yamlParser.ParseFile(configFile)
 	if err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		for _, problem := range problems {
+			fmt.Fprintln(cmd.ErrOrStderr(), problem.Error())
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("validation failed: configuration has %d problem(s)", len(problems))
 	}
 
 	log.Infof("Configuration is valid!")
@@ -228,18 +556,329 @@ func validateConfig(cmd *cobra.Command, args []string) error {
 
 	for _, workflow := range config.Workflows {
 		log.Infof("  - %s (schedule: %s, tasks: %d)", workflow.Name, workflow.Schedule, len(workflow.Tasks))
+		for _, trigger := range workflow.TriggeredBy {
+			status := trigger.Status
+			if status == "" {
+				status = parser.TriggerOnCompleted
+			}
+			log.Infof("      triggered by %s (on %s)", trigger.Workflow, status)
+		}
+		for _, task := range workflow.Tasks {
+			if task.WaitFor != nil {
+				log.Infof("      task %s waits for %s", task.ID, task.WaitFor.Workflow)
+			}
+		}
+	}
+
+	if changes := daemonChanges(cmd, config); changes != nil {
+		if len(changes.Added)+len(changes.Removed)+len(changes.Changed) == 0 {
+			log.Infof("The running daemon has loaded this configuration")
+		} else {
+			log.Warnf("The running daemon has loaded a different configuration; restart it to apply this one")
+			for _, name := range changes.Added {
+				log.Infof("  + %s (not loaded by the daemon)", name)
+			}
+			for _, name := range changes.Removed {
+				log.Infof("  - %s (loaded by the daemon, no longer in the file)", name)
+			}
+			for _, name := range changes.Changed {
+				log.Infof("  ~ %s (changed since the daemon loaded it)", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lintResult is the machine-readable output of the lint command
+type lintResult struct {
+	File     string                `json:"file"`
+	Findings []lint.Finding        `json:"findings"`
+	Summary  map[lint.Severity]int `json:"summary"`
+}
+
+func lintWorkflows(cmd *cobra.Command, args []string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format '%s' (expected text or json)", format)
+	}
+	threshold, err := lint.ParseSeverity(failOn)
+	if err != nil {
+		return fmt.Errorf("invalid --fail-on: %w", err)
+	}
+
+	yamlParser := parser.NewYAMLParser()
+	config, err := yamlParser.ParseFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	rules, err := loadLintConfig()
+	if err != nil {
+		return err
+	}
+
+	findings, err := lint.NewLinter(rules).Lint(config)
+	if err != nil {
+		return fmt.Errorf("lint failed: %w", err)
+	}
+
+	summary := map[lint.Severity]int{lint.SeverityError: 0, lint.SeverityWarning: 0, lint.SeverityInfo: 0}
+	for _, finding := range findings {
+		summary[finding.Severity]++
+	}
+
+	if format == "json" {
+		result := lintResult{File: configFile, Findings: findings, Summary: summary}
+		if result.Findings == nil {
+			result.Findings = []lint.Finding{}
+		}
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to write lint result: %w", err)
+		}
+	} else {
+		for _, finding := range findings {
+			fmt.Fprintln(cmd.OutOrStdout(), finding.String())
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d error(s), %d warning(s), %d info\n",
+			summary[lint.SeverityError], summary[lint.SeverityWarning], summary[lint.SeverityInfo])
+	}
+
+	if lint.HasFindingsAtOrAbove(findings, threshold) {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("lint found problems at or above severity %s", threshold)
+	}
+	return nil
+}
+
+// loadLintConfig reads --lint-config, or the default lint file next to the
+// configuration or in the working directory when present
+func loadLintConfig() (*lint.Config, error) {
+	if lintConfig != "" {
+		return lint.LoadConfig(lintConfig)
+	}
+
+	for _, candidate := range []string{filepath.Join(filepath.Dir(configFile), lint.DefaultConfigFile), lint.DefaultConfigFile} {
+		if _, err := os.Stat(candidate); err == nil {
+			return lint.LoadConfig(candidate)
+		}
+	}
+	return nil, nil
+}
+
+func planWorkflows(cmd *cobra.Command, args []string) error {
+	yamlParser := parser.NewYAMLParser()
+	config, err := yamlParser.ParseFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	return printPlan(cmd, config, args)
+}
+
+// printPlan prints the execution plan of the named workflows, or all of them
+func printPlan(cmd *cobra.Command, config *parser.WorkflowConfig, names []string) error {
+	if planFormat != "table" && planFormat != "json" {
+		return fmt.Errorf("unsupported format '%s' (expected table or json)", planFormat)
+	}
+
+	plans := scheduler.PlanWorkflows(config.Workflows, time.Now(), planRuns)
+	if len(names) > 0 {
+		byName := make(map[string]executor.WorkflowPlan, len(plans))
+		for _, plan := range plans {
+			byName[plan.Name] = plan
+		}
+		plans = plans[:0]
+		for _, name := range names {
+			plan, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("workflow '%s' not found", name)
+			}
+			plans = append(plans, plan)
+		}
+	}
+
+	if planFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plans); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		return nil
+	}
+
+	for i, plan := range plans {
+		if i > 0 {
+			fmt.Fprintln(cmd.OutOrStdout())
+		}
+		printWorkflowPlan(cmd, plan, "")
+	}
+	return nil
+}
+
+// printWorkflowPlan prints one workflow plan as a table; sub-workflows follow indented
+func printWorkflowPlan(cmd *cobra.Command, plan executor.WorkflowPlan, indent string) {
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "%sWorkflow: %s\n", indent, plan.Name)
+	if plan.Schedule != "" {
+		fmt.Fprintf(out, "%sSchedule: %s\n", indent, plan.Schedule)
+	}
+	for _, trigger := range plan.TriggeredBy {
+		fmt.Fprintf(out, "%sTriggered by: %s\n", indent, trigger)
+	}
+	if len(plan.NextRuns) > 0 {
+		runs := make([]string, len(plan.NextRuns))
+		for i, run := range plan.NextRuns {
+			runs[i] = run.Format("2006-01-02 15:04 MST")
+		}
+		fmt.Fprintf(out, "%sNext runs: %s\n", indent, strings.Join(runs, ", "))
+	}
+	if len(plan.Params) > 0 {
+		keys := make([]string, 0, len(plan.Params))
+		for key := range plan.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		params := make([]string, len(keys))
+		for i, key := range keys {
+			params[i] = key + "=" + plan.Params[key]
+		}
+		fmt.Fprintf(out, "%sParams: %s\n", indent, strings.Join(params, ", "))
+	}
+	if plan.Error != "" {
+		fmt.Fprintf(out, "%sError: %s\n", indent, plan.Error)
+		return
+	}
+
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "%sSTAGE\tTASK\tCOMMAND\tTIMEOUT\tATTEMPTS\tBACKOFF\tDEPENDS ON\n", indent)
+	for _, task := range plan.Tasks {
+		command := task.Command
+		switch {
+		case task.Error != "":
+			command = "error: " + task.Error
+		case task.SubWorkflow != nil:
+			command = "workflow: " + task.SubWorkflow.Name
+		case task.Image != "":
+			command = strings.TrimSpace("[" + task.Image + "] " + command)
+		case task.HTTP != "":
+			command = "http: " + task.HTTP
+		case task.Func != "":
+			command = "func: " + task.Func
+		case task.WaitFor != "":
+			command = strings.TrimSpace("wait for " + task.WaitFor + "; " + command)
+		}
+		fmt.Fprintf(table, "%s%d\t%s\t%s\t%s\t%d\t%s\t%s\n", indent, task.Stage, task.ID, command,
+			task.Timeout, task.Attempts, orDash(strings.Join(task.Backoff, ", ")), orDash(strings.Join(task.DependsOn, ", ")))
+	}
+	table.Flush()
+
+	for _, task := range plan.Tasks {
+		for _, warning := range task.Warnings {
+			fmt.Fprintf(out, "%sWarning: task %s: %s\n", indent, task.ID, warning)
+		}
+	}
+	for _, task := range plan.Tasks {
+		if task.SubWorkflow != nil {
+			fmt.Fprintf(out, "%s  ↳ task %s runs sub-workflow:\n", indent, task.ID)
+			printWorkflowPlan(cmd, *task.SubWorkflow, indent+"    ")
+		}
 	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
 
+func exportGraph(cmd *cobra.Command, args []string) error {
+	if graphFmt != "dot" && graphFmt != "mermaid" && graphFmt != "svg" {
+		return fmt.Errorf("unsupported format '%s' (expected dot, mermaid or svg)", graphFmt)
+	}
+
+	yamlParser := parser.NewYAMLParser()
+	config, err := yamlParser.ParseFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	// The workflow may be omitted when the config defines only one
+	var workflow *parser.Workflow
+	if len(args) == 0 {
+		if len(config.Workflows) != 1 {
+			names := make([]string, len(config.Workflows))
+			for i, wf := range config.Workflows {
+				names[i] = wf.Name
+			}
+			return fmt.Errorf("configuration has %d workflows, name one of: %s", len(config.Workflows), strings.Join(names, ", "))
+		}
+		workflow = &config.Workflows[0]
+	} else {
+		for i := range config.Workflows {
+			if config.Workflows[i].Name == args[0] {
+				workflow = &config.Workflows[i]
+				break
+			}
+		}
+		if workflow == nil {
+			return fmt.Errorf("workflow '%s' not found", args[0])
+		}
+	}
+
+	var lastRun *parser.WorkflowExecution
+	if withStatus {
+		lastRun, err = store.NewFileStore(stateDir).Latest(workflow.Name, "")
+		if err != nil {
+			return fmt.Errorf("failed to load last run: %w", err)
+		}
+	}
+
+	g, err := graph.Build(*workflow, lastRun)
+	if err != nil {
+		return fmt.Errorf("failed to build graph: %w", err)
+	}
+
+	var rendered string
+	switch graphFmt {
+	case "mermaid":
+		rendered = g.Mermaid()
+	case "svg":
+		rendered = g.SVG()
+	default:
+		rendered = g.DOT()
+	}
+
+	if graphOut == "" {
+		_, err = fmt.Fprint(cmd.OutOrStdout(), rendered)
+		return err
+	}
+	if err := os.WriteFile(graphOut, []byte(rendered), 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
 	return nil
 }
 
-func generateHTMLReport(sched *scheduler.Scheduler, outputFile string) error {
+func printSchema(cmd *cobra.Command, args []string) error {
+	data, err := parser.SchemaJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func generateHTMLReport(workflows []parser.Workflow, executions map[string][]parser.WorkflowExecution, outputFile string) error {
 	htmlReporter, err := reporter.NewHTMLReporter()
 	if err != nil {
 		return fmt.Errorf("failed to create HTML reporter: %w", err)
 	}
 
-	executions := sched.GetAllExecutions()
+	htmlReporter.SetWorkflows(workflows)
 	if err := htmlReporter.GenerateReport(executions, outputFile); err != nil {
 		return fmt.Errorf("failed to generate HTML report: %w", err)
 	}
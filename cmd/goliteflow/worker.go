@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the worker command and of run's worker API
+var (
+	workerServer      string
+	workerID          string
+	workerLabels      []string
+	workerConcurrency int
+	workerToken       string
+	workerListen      string
+	workerInsecure    bool
+)
+
+// workerTokenOrEnv returns the token given with --worker-token or --token,
+// or the one of the environment
+func workerTokenOrEnv() string {
+	if workerToken != "" {
+		return workerToken
+	}
+	return os.Getenv(worker.TokenEnv)
+}
+
+// usesWorkers reports whether a workflow or task of config has runs_on labels
+func usesWorkers(config *parser.WorkflowConfig) bool {
+	for _, workflow := range config.Workflows {
+		if len(workflow.RunsOn) > 0 {
+			return true
+		}
+		for _, task := range workflow.Tasks {
+			if len(task.RunsOn) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveWorkers starts the API workers claim tasks from on workerListen. The
+// returned function stops it, failing the attempts still on workers.
+// Without a token it refuses to start unless --worker-insecure is set.
+func serveWorkers(dispatcher *worker.Dispatcher) (func(), error) {
+	token := workerTokenOrEnv()
+	if token == "" && !workerInsecure {
+		return nil, fmt.Errorf("the worker API needs a token, or anyone who reaches %s can run commands; set --worker-token or %s, or --worker-insecure", workerListen, worker.TokenEnv)
+	}
+	listener, err := net.Listen("tcp", workerListen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for workers: %w", err)
+	}
+	dispatcher.SetToken(token)
+
+	log := logger.GetGlobalLogger()
+	server := &http.Server{Handler: dispatcher.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Worker API stopped: %v", err)
+		}
+	}()
+	if token == "" {
+		log.Warnf("Worker API listening on %s without a token; anyone who reaches it can run commands", listener.Addr())
+	} else {
+		log.Infof("Worker API listening on %s", listener.Addr())
+	}
+
+	return func() {
+		dispatcher.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// runWorker claims and runs tasks until it receives SIGINT or SIGTERM, then
+// finishes the tasks in progress
+func runWorker(cmd *cobra.Command, args []string) error {
+	if verbose {
+		logger.SetGlobalLevel(zerolog.DebugLevel)
+	} else {
+		logger.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	w, err := worker.New(worker.Config{
+		Server:      workerServer,
+		ID:          workerID,
+		Labels:      workerLabels,
+		Concurrency: workerConcurrency,
+		Token:       workerTokenOrEnv(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		logger.GetGlobalLogger().Info("Received shutdown signal, finishing the tasks in progress...")
+	}()
+	return w.Run(ctx)
+}
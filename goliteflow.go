@@ -3,13 +3,20 @@ package goliteflow
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/executor"
 	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/notify"
 	"github.com/sintakaridina/goliteflow/internal/parser"
 	"github.com/sintakaridina/goliteflow/internal/reporter"
 	"github.com/sintakaridina/goliteflow/internal/scheduler"
+	"github.com/sintakaridina/goliteflow/internal/store"
 )
 
 // GoliteFlow is the main library interface
@@ -17,13 +24,105 @@ type GoliteFlow struct {
 	scheduler *scheduler.Scheduler
 	config    *parser.WorkflowConfig
 	logger    *logger.Logger
+	funcs     map[string]TaskFunc
+	events    *events.Bus
+	stateDir  string        // optional, where runs and pauses are persisted
+	elector   LeaderElector // optional, elects the instance that fires cron runs
+	queue     WorkQueue     // optional, runs task attempts away from this process
+	mu        sync.Mutex    // guards config changes while the scheduler runs
 }
 
+// TaskFunc is a Go function run by tasks with func: name. The returned
+// outputs are available to later tasks as {{ .Outputs.task.name }}.
+type TaskFunc = executor.TaskFunc
+
+// TaskContext describes the task a TaskFunc runs for: its workflow, params,
+// the outputs of earlier tasks and a writer captured as its stdout
+type TaskContext = executor.TaskContext
+
+// Outputs are the named values a TaskFunc returns
+type Outputs = map[string]string
+
 // New creates a new GoliteFlow instance
 func New() *GoliteFlow {
 	return &GoliteFlow{
 		logger: logger.NewLogger(),
+		funcs:  make(map[string]TaskFunc),
+		events: events.NewBus(),
+	}
+}
+
+// Subscribe calls handler for every event of this instance's workflows, runs
+// and tasks, including one-off runs. Events of a run arrive in order. By
+// default none are lost: up to DefaultEventQueueSize events are queued, and
+// runs wait while the queue is full; see SyncDelivery and BestEffortDelivery.
+// The returned function unsubscribes once the queued events are handled.
+// Handlers must not call it, nor Run, RunWithContext, RetryExecution, Start,
+// Stop or AddWorkflow, which publish events or wait for handlers and so may
+// wait for the handler itself; start a goroutine to call them instead.
+func (gf *GoliteFlow) Subscribe(handler EventHandler, opts ...SubscribeOption) (unsubscribe func()) {
+	return gf.events.Subscribe(handler, opts...)
+}
+
+// RegisterTaskFunc makes fn available to tasks with func: name. Func tasks
+// are scheduled, ordered, retried, timed out and reported like commands, and
+// a panic in fn fails the task. Register functions before Start or Run.
+func (gf *GoliteFlow) RegisterTaskFunc(name string, fn TaskFunc) {
+	gf.funcs[name] = fn
+	if gf.scheduler != nil {
+		gf.scheduler.RegisterTaskFunc(name, fn)
+	}
+}
+
+// SetStateDir records every run and the paused workflows in dir, like the
+// CLI's --state-dir, so that pauses outlast restarts. Call it before Start or Run.
+func (gf *GoliteFlow) SetStateDir(dir string) {
+	gf.stateDir = dir
+}
+
+// SetLeaderElector lets only the instance that elector elects fire cron
+// runs, for instances on several hosts running the same workflows. The
+// others stand by and take over when the leader stops renewing its lease.
+// Call it before Start.
+func (gf *GoliteFlow) SetLeaderElector(elector LeaderElector) {
+	gf.elector = elector
+}
+
+// SetWorkQueue hands the attempts of command, container and HTTP tasks to
+// queue, such as a WorkerDispatcher serving workers on other hosts. Func and
+// sub-workflow tasks always run in this process. Call it before Start or Run.
+func (gf *GoliteFlow) SetWorkQueue(queue WorkQueue) {
+	gf.queue = queue
+	if gf.scheduler != nil {
+		gf.scheduler.SetWorkQueue(queue)
+	}
+}
+
+// newScheduler creates a scheduler with the registered task functions, the
+// notifications of the configuration, and the state directory, leader
+// elector and work queue, if any
+func (gf *GoliteFlow) newScheduler() *scheduler.Scheduler {
+	s := scheduler.NewScheduler()
+	if gf.stateDir != "" {
+		s.SetStore(store.NewFileStore(gf.stateDir))
+	}
+	if gf.elector != nil {
+		s.SetElector(gf.elector)
 	}
+	if gf.queue != nil {
+		s.SetWorkQueue(gf.queue)
+	}
+	s.SetEventBus(gf.events)
+	var defaults *parser.Notifications
+	if gf.config != nil {
+		defaults = gf.config.Notifications
+		s.SetLimits(gf.config.MaxConcurrentTasks, gf.config.Pools)
+	}
+	s.SetNotifier(notify.NewNotifier(defaults, s))
+	for name, fn := range gf.funcs {
+		s.RegisterTaskFunc(name, fn)
+	}
+	return s
 }
 
 // LoadConfig loads workflow configuration from a YAML file
@@ -34,26 +133,151 @@ func (gf *GoliteFlow) LoadConfig(filename string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	gf.config = config
+	gf.setConfig(config)
 	gf.logger.Infof("Loaded %d workflows from %s", len(config.Workflows), filename)
 	return nil
 }
 
-// Start starts the workflow scheduler
-func (gf *GoliteFlow) Start() error {
-	if gf.config == nil {
-		return fmt.Errorf("configuration not loaded, call LoadConfig first")
+// LoadConfigFromReader loads workflow configuration from YAML read from r.
+// Included files are resolved relative to the working directory.
+func (gf *GoliteFlow) LoadConfigFromReader(r io.Reader) error {
+	config, err := parser.NewYAMLParser().ParseReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	gf.scheduler = scheduler.NewScheduler()
+	gf.setConfig(config)
+	gf.logger.Infof("Loaded %d workflows", len(config.Workflows))
+	return nil
+}
 
-	// Add workflows to scheduler
-	if err := gf.scheduler.AddWorkflows(gf.config.Workflows); err != nil {
-		return fmt.Errorf("failed to add workflows to scheduler: %w", err)
+// LoadConfigBytes loads workflow configuration from YAML data. Included
+// files are resolved relative to the working directory.
+func (gf *GoliteFlow) LoadConfigBytes(data []byte) error {
+	config, err := parser.NewYAMLParser().ParseBytes(data)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	gf.setConfig(config)
+	gf.logger.Infof("Loaded %d workflows", len(config.Workflows))
+	return nil
+}
+
+func (gf *GoliteFlow) setConfig(config *parser.WorkflowConfig) {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	gf.config = config
+}
+
+// AddWorkflow validates workflows together with those already loaded and adds
+// them. Workflows that reference each other, such as a sub-workflow and its
+// caller, must be added in one call. On a started instance they are scheduled
+// immediately. No configuration needs to be loaded first.
+func (gf *GoliteFlow) AddWorkflow(workflows ...Workflow) error {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+
+	config := &parser.WorkflowConfig{Version: "1.0", Workflows: append([]Workflow(nil), gf.configWorkflows()...)}
+	if gf.config != nil {
+		config.Version = gf.config.Version
+		config.TaskTemplates = gf.config.TaskTemplates
+		config.Pools = gf.config.Pools
+		config.MaxConcurrentTasks = gf.config.MaxConcurrentTasks
+	}
+
+	yamlParser := parser.NewYAMLParser()
+	added := &parser.WorkflowConfig{TaskTemplates: config.TaskTemplates, Workflows: append([]Workflow(nil), workflows...)}
+	if err := yamlParser.ResolveTemplates(added); err != nil {
+		return fmt.Errorf("failed to add workflows: %w", err)
+	}
+	config.Workflows = append(config.Workflows, added.Workflows...)
+	if err := yamlParser.ValidateConfig(config); err != nil {
+		return fmt.Errorf("failed to add workflows: %w", err)
+	}
+
+	if gf.scheduler != nil {
+		if err := gf.scheduler.AddWorkflows(added.Workflows); err != nil {
+			return fmt.Errorf("failed to add workflows to scheduler: %w", err)
+		}
+	}
+
+	gf.config = config
+	for _, workflow := range added.Workflows {
+		gf.logger.Infof("Added workflow %s", workflow.Name)
+	}
+	return nil
+}
+
+// RemoveWorkflow removes workflows and, on a started instance, unschedules
+// them. Runs in progress finish. The remaining workflows must still be valid:
+// a workflow referenced by triggers, sub-workflow tasks or wait_for is removed
+// together with the workflows that reference it.
+func (gf *GoliteFlow) RemoveWorkflow(names ...string) error {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+
+	removed := make(map[string]bool, len(names))
+	for _, name := range names {
+		removed[name] = true
+	}
+
+	remaining := &parser.WorkflowConfig{}
+	if gf.config != nil {
+		*remaining = *gf.config
+	}
+	remaining.Workflows = nil
+	found := make(map[string]bool)
+	for _, workflow := range gf.configWorkflows() {
+		if removed[workflow.Name] {
+			found[workflow.Name] = true
+			continue
+		}
+		remaining.Workflows = append(remaining.Workflows, workflow)
+	}
+	for _, name := range names {
+		if !found[name] {
+			return fmt.Errorf("workflow '%s' not found", name)
+		}
+	}
+	if len(remaining.Workflows) > 0 {
+		if err := parser.NewYAMLParser().ValidateConfig(remaining); err != nil {
+			return fmt.Errorf("cannot remove %s: %w", strings.Join(names, ", "), err)
+		}
+	}
+
+	if gf.scheduler != nil {
+		for _, name := range names {
+			if err := gf.scheduler.RemoveWorkflow(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	gf.config = remaining
+	for _, name := range names {
+		gf.logger.Infof("Removed workflow %s", name)
+	}
+	return nil
+}
+
+// configWorkflows returns the loaded workflows, if any
+func (gf *GoliteFlow) configWorkflows() []Workflow {
+	if gf.config == nil {
+		return nil
+	}
+	return gf.config.Workflows
+}
+
+// Start starts the workflow scheduler
+func (gf *GoliteFlow) Start() error {
+	sched, err := gf.prepareScheduler()
+	if err != nil {
+		return err
 	}
 
 	// Start scheduler
-	if err := gf.scheduler.Start(); err != nil {
+	if err := sched.Start(); err != nil {
 		return fmt.Errorf("failed to start scheduler: %w", err)
 	}
 
@@ -61,10 +285,32 @@ func (gf *GoliteFlow) Start() error {
 	return nil
 }
 
+// prepareScheduler creates the scheduler with the loaded workflows. The lock
+// keeps AddWorkflow and RemoveWorkflow from changing the configuration until
+// the scheduler is in place to receive their changes.
+func (gf *GoliteFlow) prepareScheduler() (*scheduler.Scheduler, error) {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+
+	if gf.config == nil {
+		return nil, fmt.Errorf("configuration not loaded, call LoadConfig first")
+	}
+
+	sched := gf.newScheduler()
+
+	// Add workflows to scheduler
+	if err := sched.AddWorkflows(gf.config.Workflows); err != nil {
+		return nil, fmt.Errorf("failed to add workflows to scheduler: %w", err)
+	}
+	gf.scheduler = sched
+	return sched, nil
+}
+
 // Stop stops the workflow scheduler
 func (gf *GoliteFlow) Stop() {
 	if gf.scheduler != nil {
 		gf.scheduler.Stop()
+		gf.events.Flush()
 		gf.logger.Info("GoliteFlow scheduler stopped")
 	}
 }
@@ -77,26 +323,8 @@ func (gf *GoliteFlow) Run() error {
 
 	gf.logger.Info("Running workflows once...")
 
-	for _, workflow := range gf.config.Workflows {
-		gf.logger.Infof("Executing workflow: %s", workflow.Name)
-
-		// Create a temporary scheduler for one-time execution
-		tempScheduler := scheduler.NewScheduler()
-		if err := tempScheduler.AddWorkflows([]parser.Workflow{workflow}); err != nil {
-			gf.logger.Errorf("Failed to add workflow '%s' to scheduler: %v", workflow.Name, err)
-			continue
-		}
-
-		execution, err := tempScheduler.ExecuteWorkflowNow(workflow.Name)
-		if err != nil {
-			gf.logger.Errorf("Failed to execute workflow '%s': %v", workflow.Name, err)
-			continue
-		}
-
-		gf.logger.Infof("Workflow '%s' completed with status: %s", workflow.Name, execution.Status)
-	}
-
-	return nil
+	defer gf.events.Flush()
+	return gf.runOnce(context.Background())
 }
 
 // RunWithContext executes workflows with a context for cancellation
@@ -107,7 +335,31 @@ func (gf *GoliteFlow) RunWithContext(ctx context.Context) error {
 
 	gf.logger.Info("Running workflows with context...")
 
-	for _, workflow := range gf.config.Workflows {
+	defer gf.events.Flush()
+	return gf.runOnce(ctx)
+}
+
+// runOnce executes every workflow once, upstream workflows first. Triggered
+// workflows and sub-workflows run as part of the run that starts them.
+func (gf *GoliteFlow) runOnce(ctx context.Context) error {
+	ordered, err := parser.NewYAMLParser().SortWorkflows(gf.config)
+	if err != nil {
+		return fmt.Errorf("failed to order workflows: %w", err)
+	}
+
+	// Create a temporary scheduler for one-time execution
+	tempScheduler := gf.newScheduler()
+	defer tempScheduler.Stop()
+	added := make(map[string]bool)
+	for _, workflow := range ordered {
+		if err := tempScheduler.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+			gf.logger.Errorf("Failed to add workflow '%s' to scheduler: %v", workflow.Name, err)
+			continue
+		}
+		added[workflow.Name] = true
+	}
+
+	for _, workflow := range ordered {
 		select {
 		case <-ctx.Done():
 			gf.logger.Info("Context cancelled, stopping workflow execution")
@@ -115,15 +367,12 @@ func (gf *GoliteFlow) RunWithContext(ctx context.Context) error {
 		default:
 		}
 
-		gf.logger.Infof("Executing workflow: %s", workflow.Name)
-
-		// Create a temporary scheduler for one-time execution
-		tempScheduler := scheduler.NewScheduler()
-		if err := tempScheduler.AddWorkflows([]parser.Workflow{workflow}); err != nil {
-			gf.logger.Errorf("Failed to add workflow '%s' to scheduler: %v", workflow.Name, err)
+		if !added[workflow.Name] || !workflow.IsEntryPoint() {
 			continue
 		}
 
+		gf.logger.Infof("Executing workflow: %s", workflow.Name)
+
 		execution, err := tempScheduler.ExecuteWorkflowNow(workflow.Name)
 		if err != nil {
 			gf.logger.Errorf("Failed to execute workflow '%s': %v", workflow.Name, err)
@@ -147,6 +396,7 @@ func (gf *GoliteFlow) GenerateReport(outputFile string) error {
 		return fmt.Errorf("failed to create HTML reporter: %w", err)
 	}
 
+	htmlReporter.SetWorkflows(gf.scheduler.GetWorkflows())
 	executions := gf.scheduler.GetAllExecutions()
 	if err := htmlReporter.GenerateReport(executions, outputFile); err != nil {
 		return fmt.Errorf("failed to generate HTML report: %w", err)
@@ -157,7 +407,7 @@ func (gf *GoliteFlow) GenerateReport(outputFile string) error {
 }
 
 // GetStats returns scheduler statistics
-func (gf *GoliteFlow) GetStats() *scheduler.SchedulerStats {
+func (gf *GoliteFlow) GetStats() *SchedulerStats {
 	if gf.scheduler == nil {
 		return nil
 	}
@@ -167,7 +417,7 @@ func (gf *GoliteFlow) GetStats() *scheduler.SchedulerStats {
 }
 
 // GetExecutions returns execution history for a specific workflow
-func (gf *GoliteFlow) GetExecutions(workflowName string) []parser.WorkflowExecution {
+func (gf *GoliteFlow) GetExecutions(workflowName string) []WorkflowExecution {
 	if gf.scheduler == nil {
 		return nil
 	}
@@ -175,6 +425,66 @@ func (gf *GoliteFlow) GetExecutions(workflowName string) []parser.WorkflowExecut
 	return gf.scheduler.GetExecutions(workflowName)
 }
 
+// GetRunningExecutions returns the runs in progress with their current task,
+// oldest first
+func (gf *GoliteFlow) GetRunningExecutions() []RunningExecution {
+	if gf.scheduler == nil {
+		return nil
+	}
+
+	return gf.scheduler.GetRunningExecutions()
+}
+
+// CancelExecution cancels a run in progress, identified by its run ID: its
+// current task is killed and the run finishes as cancelled
+func (gf *GoliteFlow) CancelExecution(runID string) error {
+	if gf.scheduler == nil {
+		return fmt.Errorf("scheduler not started, call Start first")
+	}
+
+	return gf.scheduler.CancelExecution(runID)
+}
+
+// PauseWorkflow skips the scheduled and triggered runs of a workflow until
+// ResumeWorkflow. Pauses outlast restarts when a state directory is set.
+func (gf *GoliteFlow) PauseWorkflow(name string) error {
+	if gf.scheduler == nil {
+		return fmt.Errorf("scheduler not started, call Start first")
+	}
+
+	return gf.scheduler.PauseWorkflow(name)
+}
+
+// ResumeWorkflow lets a paused workflow run again
+func (gf *GoliteFlow) ResumeWorkflow(name string) error {
+	if gf.scheduler == nil {
+		return fmt.Errorf("scheduler not started, call Start first")
+	}
+
+	return gf.scheduler.ResumeWorkflow(name)
+}
+
+// RetryExecution runs a finished run again from a task, or from the task that
+// failed when from is empty. Tasks that succeeded and are not downstream of it
+// are reused with their outputs. Runs of earlier instances are found when a
+// state directory is set.
+func (gf *GoliteFlow) RetryExecution(runID, from string) (*WorkflowExecution, error) {
+	if gf.scheduler == nil {
+		return nil, fmt.Errorf("scheduler not started, call Start first")
+	}
+
+	return gf.scheduler.RetryExecution(runID, from)
+}
+
+// IsLeader reports whether this instance fires cron runs: always without a
+// leader elector, and while elected with one
+func (gf *GoliteFlow) IsLeader() bool {
+	if gf.scheduler == nil {
+		return false
+	}
+	return gf.scheduler.IsLeader()
+}
+
 // GetNextRunTimes returns the next scheduled run times for all workflows
 func (gf *GoliteFlow) GetNextRunTimes() map[string]time.Time {
 	if gf.scheduler == nil {
@@ -217,13 +527,17 @@ func RunWithReport(configFile, reportFile string) error {
 	}
 
 	// Create a temporary scheduler to capture execution data
-	tempScheduler := scheduler.NewScheduler()
+	tempScheduler := gf.newScheduler()
+	defer tempScheduler.Stop()
 	if err := tempScheduler.AddWorkflows(gf.config.Workflows); err != nil {
 		return fmt.Errorf("failed to add workflows to scheduler: %w", err)
 	}
 
-	// Execute workflows and capture results
+	// Execute workflows and capture results, triggered workflows run with their upstream
 	for _, workflow := range gf.config.Workflows {
+		if !workflow.IsEntryPoint() {
+			continue
+		}
 		_, err := tempScheduler.ExecuteWorkflowNow(workflow.Name)
 		if err != nil {
 			gf.logger.Errorf("Failed to execute workflow '%s': %v", workflow.Name, err)
@@ -236,6 +550,7 @@ func RunWithReport(configFile, reportFile string) error {
 		return fmt.Errorf("failed to create HTML reporter: %w", err)
 	}
 
+	htmlReporter.SetWorkflows(tempScheduler.GetWorkflows())
 	executions := tempScheduler.GetAllExecutions()
 	if err := htmlReporter.GenerateReport(executions, reportFile); err != nil {
 		return fmt.Errorf("failed to generate HTML report: %w", err)
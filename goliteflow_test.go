@@ -2,7 +2,14 @@ package goliteflow
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -230,3 +237,270 @@ func TestValidateConfig_ConvenienceFunction(t *testing.T) {
 		t.Error("Expected error for invalid config")
 	}
 }
+
+func TestGoliteFlow_RegisterTaskFunc(t *testing.T) {
+	gf := New()
+	if err := gf.LoadConfig("testdata/func-workflow.yml"); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	// Unregistered functions fail at start
+	if err := gf.Start(); err == nil || !strings.Contains(err.Error(), "function 'compute' is not registered") {
+		t.Fatalf("Expected an unregistered function error, got %v", err)
+	}
+
+	gf.RegisterTaskFunc("compute", func(ctx context.Context, tc TaskContext) (Outputs, error) {
+		fmt.Fprintf(tc.Stdout, "%s from %s", tc.Params["greeting"], tc.TaskID)
+		return Outputs{"answer": "42"}, nil
+	})
+	gf.RegisterTaskFunc("explode", func(ctx context.Context, tc TaskContext) (Outputs, error) {
+		panic("boom")
+	})
+	if err := gf.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer gf.Stop()
+
+	execution, err := gf.scheduler.ExecuteWorkflowNow("in_process")
+	if err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	results := execution.TaskResults
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 task results, got %d", len(results))
+	}
+	if results[0].Stdout != "hello from compute" || results[0].Outputs["answer"] != "42" {
+		t.Errorf("Unexpected function result: %+v", results[0])
+	}
+	if strings.TrimSpace(results[1].Stdout) != "42" {
+		t.Errorf("Expected the output in the command, got %q", results[1].Stdout)
+	}
+	if results[2].Success || !strings.Contains(results[2].Error, "panic: boom") || execution.Status != "failed" {
+		t.Errorf("Expected the panic to fail the task, got %+v", results[2])
+	}
+}
+
+func TestGoliteFlow_Subscribe(t *testing.T) {
+	gf := New()
+	if err := gf.LoadConfig("testdata/simple-workflow.yml"); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	var got []EventType
+	unsubscribe := gf.Subscribe(func(e Event) {
+		got = append(got, e.Type)
+	})
+	defer unsubscribe()
+
+	if err := gf.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Run waits for the handler to receive every event
+	expected := []EventType{WorkflowScheduled, WorkflowStarted, TaskStarted, TaskFinished, TaskStarted, TaskFinished, WorkflowFinished}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestGoliteFlow_Notifications(t *testing.T) {
+	var mu sync.Mutex
+	var subjects []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Subject string }
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		subjects = append(subjects, body.Subject)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	gf := New()
+	err := gf.LoadConfigBytes([]byte(`version: "1.0"
+notifications:
+  on_failure:
+    - webhook: {url: "` + server.URL + `"}
+workflows:
+  - name: broken
+    schedule: "@daily"
+    tasks:
+      - id: fail
+        command: "false"
+`))
+	if err != nil {
+		t.Fatalf("LoadConfigBytes() error = %v", err)
+	}
+	if err := gf.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Run returns once pending notifications are sent
+	mu.Lock()
+	defer mu.Unlock()
+	if len(subjects) != 1 || subjects[0] != "[goliteflow] broken failed" {
+		t.Errorf("Expected one failure notification, got %v", subjects)
+	}
+}
+
+func TestGoliteFlow_PauseWorkflow(t *testing.T) {
+	stateDir := t.TempDir()
+
+	gf := New()
+	gf.SetStateDir(stateDir)
+	if err := gf.PauseWorkflow("simple_test"); err == nil {
+		t.Error("Expected an error pausing before Start")
+	}
+	if err := gf.LoadConfig("testdata/simple-workflow.yml"); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := gf.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := gf.PauseWorkflow("simple_test"); err != nil {
+		t.Fatalf("PauseWorkflow() error = %v", err)
+	}
+	gf.Stop()
+
+	// A new instance on the same state directory keeps the pause
+	restarted := New()
+	restarted.SetStateDir(stateDir)
+	if err := restarted.LoadConfig("testdata/simple-workflow.yml"); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer restarted.Stop()
+	if !restarted.scheduler.IsPaused("simple_test") {
+		t.Error("Expected simple_test to stay paused after a restart")
+	}
+	if err := restarted.ResumeWorkflow("simple_test"); err != nil {
+		t.Fatalf("ResumeWorkflow() error = %v", err)
+	}
+	if err := restarted.CancelExecution("01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil {
+		t.Error("Expected an error cancelling a run that is not in progress")
+	}
+}
+
+func TestGoliteFlow_SetLeaderElector(t *testing.T) {
+	lease := filepath.Join(t.TempDir(), "leader.lease")
+
+	start := func() *GoliteFlow {
+		gf := New()
+		gf.SetLeaderElector(NewFileLease(lease, 300*time.Millisecond))
+		if err := gf.LoadConfig("testdata/simple-workflow.yml"); err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if err := gf.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		return gf
+	}
+	waitForLeader := func(gf *GoliteFlow) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !gf.IsLeader() {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the instance to be elected")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	first := start()
+	waitForLeader(first)
+	second := start()
+	defer second.Stop()
+	time.Sleep(150 * time.Millisecond)
+	if second.IsLeader() {
+		t.Fatal("Expected only one instance to lead")
+	}
+
+	first.Stop()
+	waitForLeader(second)
+}
+
+// labelQueue records the labels of the attempts it runs in this process
+type labelQueue struct {
+	mu     sync.Mutex
+	labels [][]string
+}
+
+func (q *labelQueue) Execute(ctx context.Context, work Work) CommandResult {
+	q.mu.Lock()
+	q.labels = append(q.labels, work.Task.RunsOn)
+	q.mu.Unlock()
+	return CommandResult{Stdout: work.Command}
+}
+
+func TestGoliteFlow_SetWorkQueue(t *testing.T) {
+	queue := &labelQueue{}
+	gf := New()
+	gf.SetWorkQueue(queue)
+	err := gf.LoadConfigBytes([]byte(`version: "1.0"
+workflows:
+  - name: etl
+    schedule: "@daily"
+    runs_on: [etl]
+    tasks:
+      - id: extract
+        command: ./extract.sh
+      - id: train
+        command: ./train.sh
+        runs_on: [gpu-free]
+        depends_on: [extract]
+`))
+	if err != nil {
+		t.Fatalf("LoadConfigBytes() error = %v", err)
+	}
+	if err := gf.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.labels) != 2 || queue.labels[0][0] != "etl" || queue.labels[1][0] != "gpu-free" {
+		t.Errorf("Expected both tasks in the queue with their labels, got %v", queue.labels)
+	}
+}
+
+func TestGoliteFlow_Pools(t *testing.T) {
+	gf := New()
+	err := gf.LoadConfigBytes([]byte(`version: "1.0"
+max_concurrent_tasks: 4
+pools:
+  db:
+    slots: 2
+workflows:
+  - name: load
+    schedule: "@daily"
+    tasks:
+      - id: load
+        command: "true"
+        pool: db
+`))
+	if err != nil {
+		t.Fatalf("LoadConfigBytes() error = %v", err)
+	}
+
+	// Workflows added later take slots of the configured pools
+	vacuum := NewWorkflow("vacuum").Schedule("@daily").Task(NewTask("vacuum").Command("true").Pool("db", 2)).MustBuild()
+	if err := gf.AddWorkflow(vacuum); err != nil {
+		t.Fatalf("AddWorkflow() error = %v", err)
+	}
+
+	if err := gf.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer gf.Stop()
+
+	queue := gf.GetStats().Queue
+	if queue.MaxConcurrentTasks != 4 || queue.Pools["db"] != (PoolStats{Slots: 2}) {
+		t.Errorf("Unexpected queue stats: %+v", queue)
+	}
+}
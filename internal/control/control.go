@@ -0,0 +1,252 @@
+// Package control lets CLI commands talk to a running daemon over a unix
+// socket in the state directory, using JSON-RPC. The socket is only writable
+// by the daemon's user, so file permissions decide who may control it.
+package control
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/scheduler"
+)
+
+// SocketName is the file name of the control socket in the state directory
+const SocketName = "goliteflow.sock"
+
+// serviceName prefixes the RPC methods
+const serviceName = "Control"
+
+// dialTimeout bounds how long a client waits for the daemon to accept
+const dialTimeout = 2 * time.Second
+
+// SocketPath returns the path of the control socket of a state directory
+func SocketPath(stateDir string) string {
+	return filepath.Join(stateDir, SocketName)
+}
+
+// Status is what the daemon reports about itself
+type Status struct {
+	PID       int                          `json:"pid"`
+	StartedAt time.Time                    `json:"started_at"`
+	Workflows int                          `json:"workflows"`
+	Running   []scheduler.RunningExecution `json:"running"`
+	NextRuns  map[string]time.Time         `json:"next_runs"`
+	Paused    []string                     `json:"paused,omitempty"`
+	Role      string                       `json:"role,omitempty"`  // leader or follower, with leader election
+	Queue     *executor.QueueStats         `json:"queue,omitempty"` // tasks running and waiting for slots
+}
+
+// Empty is the argument or reply of methods that take or return none
+type Empty struct{}
+
+// RunArgs identifies a run
+type RunArgs struct {
+	RunID string `json:"run_id"`
+}
+
+// WorkflowArgs identifies a workflow
+type WorkflowArgs struct {
+	Name string `json:"name"`
+}
+
+// RetryArgs identifies a run to retry and the task to retry it from
+type RetryArgs struct {
+	RunID string `json:"run_id"`
+	From  string `json:"from,omitempty"`
+}
+
+// Service holds the methods exposed on the control socket
+type Service struct {
+	sched     *scheduler.Scheduler
+	startedAt time.Time
+}
+
+// Status reports the runs in progress and the next scheduled runs
+func (s *Service) Status(_ Empty, reply *Status) error {
+	queue := s.sched.QueueStats()
+	*reply = Status{
+		PID:       os.Getpid(),
+		StartedAt: s.startedAt,
+		Workflows: len(s.sched.GetWorkflows()),
+		Running:   s.sched.GetRunningExecutions(),
+		NextRuns:  s.sched.GetNextRunTimes(),
+		Paused:    s.sched.GetPausedWorkflows(),
+		Role:      s.sched.Role(),
+		Queue:     &queue,
+	}
+	return nil
+}
+
+// Cancel cancels a run in progress
+func (s *Service) Cancel(args RunArgs, _ *Empty) error {
+	return s.sched.CancelExecution(args.RunID)
+}
+
+// Pause pauses a workflow
+func (s *Service) Pause(args WorkflowArgs, _ *Empty) error {
+	return s.sched.PauseWorkflow(args.Name)
+}
+
+// Resume resumes a paused workflow
+func (s *Service) Resume(args WorkflowArgs, _ *Empty) error {
+	return s.sched.ResumeWorkflow(args.Name)
+}
+
+// Workflows returns the workflows the daemon runs
+func (s *Service) Workflows(_ Empty, reply *[]parser.Workflow) error {
+	*reply = s.sched.GetWorkflows()
+	return nil
+}
+
+// Retry retries a finished run and replies once the new run finished
+func (s *Service) Retry(args RetryArgs, reply *parser.WorkflowExecution) error {
+	execution, err := s.sched.RetryExecution(args.RunID, args.From)
+	if err != nil {
+		return err
+	}
+	*reply = *execution
+	return nil
+}
+
+// Server serves the control socket of a daemon
+type Server struct {
+	listener net.Listener
+	path     string
+	wg       sync.WaitGroup
+}
+
+// Listen creates the control socket at path and serves sched on it until
+// Close. A socket left behind by a daemon that is gone is replaced.
+func Listen(path string, sched *scheduler.Scheduler) (*Server, error) {
+	// The directory is private when Listen creates it
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	// Only the daemon's user may connect
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(serviceName, &Service{sched: sched, startedAt: time.Now()}); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to register control service: %w", err)
+	}
+
+	s := &Server{listener: listener, path: path}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return s, nil
+}
+
+// Path returns the path of the socket
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Close stops accepting connections and removes the socket
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// ErrNoDaemon is returned by Dial when no daemon listens on the socket
+var ErrNoDaemon = errors.New("no daemon is running")
+
+// Client calls a daemon over its control socket
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the daemon listening on the control socket at path
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if errors.Is(err, os.ErrPermission) {
+		return nil, fmt.Errorf("not allowed to control the daemon listening on %s: %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w (no control socket at %s)", ErrNoDaemon, path)
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// Status returns the status of the daemon
+func (c *Client) Status() (*Status, error) {
+	var status Status
+	if err := c.rpc.Call(serviceName+".Status", Empty{}, &status); err != nil {
+		return nil, fmt.Errorf("status request failed: %w", err)
+	}
+	return &status, nil
+}
+
+// Cancel asks the daemon to cancel a run in progress
+func (c *Client) Cancel(runID string) error {
+	return c.rpc.Call(serviceName+".Cancel", RunArgs{RunID: runID}, &Empty{})
+}
+
+// Pause asks the daemon to pause a workflow
+func (c *Client) Pause(name string) error {
+	return c.rpc.Call(serviceName+".Pause", WorkflowArgs{Name: name}, &Empty{})
+}
+
+// Resume asks the daemon to resume a paused workflow
+func (c *Client) Resume(name string) error {
+	return c.rpc.Call(serviceName+".Resume", WorkflowArgs{Name: name}, &Empty{})
+}
+
+// Workflows returns the workflows the daemon runs
+func (c *Client) Workflows() ([]parser.Workflow, error) {
+	var workflows []parser.Workflow
+	if err := c.rpc.Call(serviceName+".Workflows", Empty{}, &workflows); err != nil {
+		return nil, fmt.Errorf("workflows request failed: %w", err)
+	}
+	return workflows, nil
+}
+
+// Retry asks the daemon to retry a finished run and returns the new run
+func (c *Client) Retry(runID, from string) (*parser.WorkflowExecution, error) {
+	var execution parser.WorkflowExecution
+	if err := c.rpc.Call(serviceName+".Retry", RetryArgs{RunID: runID, From: from}, &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
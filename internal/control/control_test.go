@@ -0,0 +1,97 @@
+package control
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/scheduler"
+)
+
+func TestServer_Status(t *testing.T) {
+	sched := scheduler.NewScheduler()
+	workflows := []parser.Workflow{
+		{Name: "nightly", Schedule: "0 2 * * *", Tasks: []parser.Task{{ID: "task1", Command: "true"}}},
+	}
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	path := SocketPath(t.TempDir())
+	// A socket file left behind by a daemon that is gone
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := Listen(path, sched)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if _, err := Listen(path, sched); err == nil {
+		t.Error("Expected a second daemon on the same socket to be refused")
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a socket only its owner may use, got %v, %v", info.Mode(), err)
+	}
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	if workflows, err := client.Workflows(); err != nil || len(workflows) != 1 || workflows[0].Schedule != "0 2 * * *" {
+		t.Errorf("Workflows() = %+v, %v; want nightly", workflows, err)
+	}
+	if _, err := client.Retry("01ARZ3NDEKTSV4RRFFQ69G5FAV", ""); err == nil || err.Error() != "run '01ARZ3NDEKTSV4RRFFQ69G5FAV' not found" {
+		t.Errorf("Expected the daemon's retry error, got %v", err)
+	}
+	if err := client.Pause("nightly"); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := client.Pause("missing"); err == nil || err.Error() != "workflow 'missing' not found" {
+		t.Errorf("Expected the daemon's error, got %v", err)
+	}
+	if err := client.Cancel("01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil {
+		t.Error("Expected an error cancelling a run that is not in progress")
+	}
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status.Paused) != 1 || status.Paused[0] != "nightly" {
+		t.Errorf("Expected nightly to be paused, got %v", status.Paused)
+	}
+	if err := client.Resume("nightly"); err != nil || sched.IsPaused("nightly") {
+		t.Errorf("Expected Resume() to resume nightly, got %v", err)
+	}
+	client.Close()
+
+	if status.PID != os.Getpid() || status.Workflows != 1 || len(status.Running) != 0 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if status.NextRuns["nightly"].IsZero() {
+		t.Errorf("Expected the next run of nightly, got %v", status.NextRuns)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := Dial(path); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("Expected ErrNoDaemon once the server closed, got %v", err)
+	}
+}
+
+func TestListen_CreatesPrivateDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	server, err := Listen(SocketPath(dir), scheduler.NewScheduler())
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer server.Close()
+
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a directory only its owner may list, got %v, %v", info.Mode(), err)
+	}
+}
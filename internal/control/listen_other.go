@@ -0,0 +1,11 @@
+//go:build !unix
+
+package control
+
+import "net"
+
+// listenPrivate creates the socket at path; there is no umask to narrow, so
+// access is restricted afterwards
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
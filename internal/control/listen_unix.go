@@ -0,0 +1,24 @@
+//go:build unix
+
+package control
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serialises the umask changes of concurrent Listen calls
+var umaskMu sync.Mutex
+
+// listenPrivate creates the socket at path accessible to its owner only. The
+// umask is narrowed while the socket is bound, so there is no moment in which
+// other users could connect to it.
+func listenPrivate(path string) (net.Listener, error) {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
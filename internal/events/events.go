@@ -0,0 +1,257 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// Type identifies what happened
+type Type string
+
+// Event types, in the order they occur for a run
+const (
+	WorkflowScheduled Type = "workflow_scheduled" // added to the scheduler; Next is the first run
+	WorkflowStarted   Type = "workflow_started"
+	TaskQueued        Type = "task_queued" // the task waits for slots; Reason says which
+	TaskStarted       Type = "task_started"
+	TaskRetrying      Type = "task_retrying" // an attempt failed; Attempt is the next one, after Delay
+	TaskFinished      Type = "task_finished" // Result holds the outcome
+	WorkflowFinished  Type = "workflow_finished"
+	WorkflowSkipped   Type = "workflow_skipped" // a run did not start; Reason says why
+	SLAMissed         Type = "sla_missed"       // a run or task breached its SLA; Violation says how
+)
+
+// Event is something that happened to a workflow or task. Events of a run
+// share its RunID and reach every subscriber in the order they occurred.
+type Event struct {
+	Type        Type      `json:"type"`
+	Time        time.Time `json:"time"`
+	Workflow    string    `json:"workflow"`
+	RunID       string    `json:"run_id,omitempty"`
+	ParentRunID string    `json:"parent_run_id,omitempty"` // run that invoked this one as a sub-workflow
+	Task        string    `json:"task,omitempty"`
+	Trigger     string    `json:"trigger,omitempty"` // what started the run; set on WorkflowStarted
+
+	Attempt int           `json:"attempt,omitempty"` // 1-based
+	Delay   time.Duration `json:"delay,omitempty"`   // backoff before the next attempt
+	Error   string        `json:"error,omitempty"`
+	Reason  string        `json:"reason,omitempty"`
+	Next    time.Time     `json:"next,omitempty"`
+
+	Result    *parser.ExecutionResult   `json:"result,omitempty"`
+	Execution *parser.WorkflowExecution `json:"execution,omitempty"`
+	Violation *parser.SLAViolation      `json:"violation,omitempty"`
+}
+
+// Handler receives events
+type Handler func(Event)
+
+// Option configures how a subscriber receives events
+type Option func(*subscriber)
+
+// Sync calls the handler in the goroutine that publishes the event, so a run
+// does not continue until the handler returns. Handlers may then be called
+// concurrently by different runs.
+func Sync() Option {
+	return func(s *subscriber) {
+		s.sync = true
+	}
+}
+
+// BestEffort queues up to size events for the handler and drops events
+// while the queue is full, so a slow handler never holds up runs
+func BestEffort(size int) Option {
+	return func(s *subscriber) {
+		s.limit = size
+		s.drop = true
+	}
+}
+
+// DefaultQueueSize is how many events a subscriber without options queues
+// before publishing waits for its handler to catch up
+const DefaultQueueSize = 1024
+
+// Bus delivers events to subscribers. By default each subscriber has its own
+// goroutine and a queue of DefaultQueueSize events, so no event is lost and a
+// slow handler only delays itself until its queue is full. A nil *Bus
+// discards events.
+//
+// Handlers must not unsubscribe, flush or publish to the bus they are called
+// by: each may wait for the handler itself to return. They can do so from a
+// goroutine of their own.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[uint64]*subscriber
+	nextID      uint64
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[uint64]*subscriber)}
+}
+
+// Subscribe registers a handler for every event published from now on. The
+// returned function unsubscribes it once the events already queued for it
+// have been delivered.
+func (b *Bus) Subscribe(handler Handler, opts ...Option) func() {
+	s := &subscriber{handler: handler, idle: true, limit: DefaultQueueSize}
+	s.cond = sync.NewCond(&s.mu)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !s.sync {
+		go s.run()
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = s
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			s.close()
+		})
+	}
+}
+
+// Publish delivers an event to every subscriber, stamping its time if unset
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, s := range b.snapshot() {
+		s.deliver(event)
+	}
+}
+
+// Flush waits until every event published so far has been handled, except
+// those dropped by best-effort subscribers
+func (b *Bus) Flush() {
+	if b == nil {
+		return
+	}
+	for _, s := range b.snapshot() {
+		s.flush()
+	}
+}
+
+// snapshot returns the subscribers in the order they subscribed
+func (b *Bus) snapshot() []*subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ids := make([]uint64, 0, len(b.subscribers))
+	for id := range b.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	subscribers := make([]*subscriber, len(ids))
+	for i, id := range ids {
+		subscribers[i] = b.subscribers[id]
+	}
+	return subscribers
+}
+
+// subscriber holds the queue of one handler
+type subscriber struct {
+	handler Handler
+	sync    bool
+	limit   int  // 0 means unbounded
+	drop    bool // drop events while the queue is full instead of waiting
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []Event
+	idle   bool // not running the handler
+	closed bool
+}
+
+func (s *subscriber) deliver(event Event) {
+	if s.sync {
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if !closed {
+			s.handle(event)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.closed && !s.drop && s.limit > 0 && len(s.queue) >= s.limit {
+		s.cond.Wait()
+	}
+	if s.closed || (s.drop && s.limit > 0 && len(s.queue) >= s.limit) {
+		return
+	}
+	s.queue = append(s.queue, event)
+	s.cond.Broadcast()
+}
+
+// run delivers queued events until the subscriber is closed and drained
+func (s *subscriber) run() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 {
+			return
+		}
+
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.idle = false
+		s.cond.Broadcast() // publishers may be waiting for room
+		s.mu.Unlock()
+		s.handle(event)
+		s.mu.Lock()
+		s.idle = true
+		s.cond.Broadcast()
+	}
+}
+
+// handle calls the handler, keeping a panicking handler from stopping delivery
+func (s *subscriber) handle(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Event handler panicked on %s event of workflow '%s': %v", event.Type, event.Workflow, r)
+		}
+	}()
+	s.handler(event)
+}
+
+func (s *subscriber) flush() {
+	if s.sync {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) > 0 || !s.idle {
+		s.cond.Wait()
+	}
+}
+
+func (s *subscriber) close() {
+	s.flush()
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
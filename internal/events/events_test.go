@@ -0,0 +1,171 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBus_DeliversInOrder(t *testing.T) {
+	bus := NewBus()
+	var mu sync.Mutex
+	var got []string
+	unsubscribe := bus.Subscribe(func(e Event) {
+		time.Sleep(time.Millisecond) // a slow handler must not reorder or lose events
+		mu.Lock()
+		got = append(got, e.Task)
+		mu.Unlock()
+	})
+
+	tasks := []string{"a", "b", "c", "d", "e"}
+	for _, task := range tasks {
+		bus.Publish(Event{Type: TaskStarted, RunID: "run", Task: task})
+	}
+	unsubscribe() // waits for the queued events
+
+	if len(got) != len(tasks) {
+		t.Fatalf("Expected %d events, got %v", len(tasks), got)
+	}
+	for i, task := range tasks {
+		if got[i] != task {
+			t.Errorf("Event %d: expected %s, got %s", i, task, got[i])
+		}
+	}
+
+	bus.Publish(Event{Type: TaskStarted, Task: "late"})
+	if len(got) != len(tasks) {
+		t.Errorf("Expected no events after unsubscribing, got %v", got)
+	}
+}
+
+func TestBus_Sync(t *testing.T) {
+	bus := NewBus()
+	var got []Event
+	bus.Subscribe(func(e Event) { got = append(got, e) }, Sync())
+
+	bus.Publish(Event{Type: WorkflowStarted, Workflow: "wf"})
+	if len(got) != 1 || got[0].Time.IsZero() {
+		t.Fatalf("Expected the event to be handled before Publish returns, got %v", got)
+	}
+}
+
+func TestBus_BestEffortDropsWhenFull(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	count := 0
+	bus.Subscribe(func(e Event) {
+		<-release
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}, BestEffort(2))
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(Event{Type: TaskStarted})
+	}
+	close(release)
+	bus.Flush()
+
+	// One event in the handler and two queued; the rest were dropped
+	if count < 2 || count > 3 {
+		t.Errorf("Expected 2 or 3 delivered events, got %d", count)
+	}
+}
+
+func TestBus_HandlerPanic(t *testing.T) {
+	bus := NewBus()
+	var got []Type
+	var mu sync.Mutex
+	bus.Subscribe(func(e Event) {
+		if e.Type == TaskStarted {
+			panic("handler bug")
+		}
+		mu.Lock()
+		got = append(got, e.Type)
+		mu.Unlock()
+	})
+
+	bus.Publish(Event{Type: TaskStarted})
+	bus.Publish(Event{Type: TaskFinished})
+	bus.Flush()
+
+	if len(got) != 1 || got[0] != TaskFinished {
+		t.Errorf("Expected delivery to continue after a panic, got %v", got)
+	}
+}
+
+func TestBus_Nil(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: TaskStarted})
+	bus.Flush()
+}
+
+func TestBus_HandlerUnsubscribesFromGoroutine(t *testing.T) {
+	bus := NewBus()
+	done := make(chan struct{})
+	var mu sync.Mutex
+	count := 0
+	var unsubscribe func()
+	unsubscribe = bus.Subscribe(func(e Event) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+		if e.Task == "stop" {
+			go func() {
+				unsubscribe()
+				close(done)
+			}()
+		}
+	})
+
+	bus.Publish(Event{Type: TaskStarted, Task: "stop"})
+	bus.Publish(Event{Type: TaskStarted, Task: "queued"})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Unsubscribing from the handler's goroutine did not return")
+	}
+
+	bus.Publish(Event{Type: TaskStarted, Task: "late"})
+	bus.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 2 {
+		t.Errorf("Expected the queued event delivered and none after unsubscribing, got %d", count)
+	}
+}
+
+func TestBus_DefaultQueueIsBounded(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	count := 0
+	bus.Subscribe(func(e Event) {
+		<-release
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < DefaultQueueSize+2; i++ {
+			bus.Publish(Event{Type: TaskStarted})
+		}
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("Expected publishing to wait while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-published
+	bus.Flush()
+
+	if count != DefaultQueueSize+2 {
+		t.Errorf("Expected all %d events delivered, got %d", DefaultQueueSize+2, count)
+	}
+}
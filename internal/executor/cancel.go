@@ -0,0 +1,37 @@
+package executor
+
+import "context"
+
+// withCancel gives a run its own cancellable context, registered under its
+// run ID until the returned function is called
+func (tr *TaskRunner) withCancel(ctx context.Context, runID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	tr.cancelsMu.Lock()
+	if tr.cancels == nil {
+		tr.cancels = make(map[string]context.CancelFunc)
+	}
+	tr.cancels[runID] = cancel
+	tr.cancelsMu.Unlock()
+
+	return ctx, func() {
+		tr.cancelsMu.Lock()
+		delete(tr.cancels, runID)
+		tr.cancelsMu.Unlock()
+		cancel()
+	}
+}
+
+// Cancel cancels the run in progress with the given ID, killing its current
+// task and skipping the rest, along with its sub-workflow runs. It reports
+// whether such a run was in progress.
+func (tr *TaskRunner) Cancel(runID string) bool {
+	tr.cancelsMu.Lock()
+	cancel, ok := tr.cancels[runID]
+	tr.cancelsMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
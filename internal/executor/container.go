@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// defaultContainerRuntime is used when neither the task nor the runner names one
+const defaultContainerRuntime = "docker"
+
+// containerRuntimeEnv overrides the default container runtime of a runner
+const containerRuntimeEnv = "GOLITEFLOW_CONTAINER_RUNTIME"
+
+// containerStopTimeout bounds how long stopping a cancelled container may take
+const containerStopTimeout = 30 * time.Second
+
+// SetContainerRuntime sets the container CLI used by tasks with an image that
+// do not name one, e.g. "docker" or "podman"
+func (tr *TaskRunner) SetContainerRuntime(runtime string) {
+	tr.containerRuntime = runtime
+}
+
+// runtimeFor returns the container CLI of a task
+func (tr *TaskRunner) runtimeFor(task parser.Task) string {
+	if task.Container != nil && task.Container.Runtime != "" {
+		return task.Container.Runtime
+	}
+	if tr.containerRuntime != "" {
+		return tr.containerRuntime
+	}
+	if runtime := os.Getenv(containerRuntimeEnv); runtime != "" {
+		return runtime
+	}
+	return defaultContainerRuntime
+}
+
+// invalidNameChars are replaced in container names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// containerName returns a unique name for a task's container, so it can be
+// stopped when the task is cancelled
+func containerName(workflowID, taskID string) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := invalidNameChars.ReplaceAllString(fmt.Sprintf("goliteflow-%s-%s", workflowID, taskID), "_")
+	return name + "-" + hex.EncodeToString(suffix)
+}
+
+// containerArgs maps a task to the arguments of "<runtime> run"
+func containerArgs(task parser.Task, name, command string) ([]string, error) {
+	args := []string{"run", "--rm", "--name", name}
+
+	if task.Container != nil {
+		for _, mount := range task.Container.Mounts {
+			host, target, options, err := parser.SplitMount(mount)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mount '%s': %w", mount, err)
+			}
+			// Relative host paths are bind mounts from the working directory
+			if strings.HasPrefix(host, ".") {
+				if host, err = filepath.Abs(host); err != nil {
+					return nil, err
+				}
+			}
+			volume := host + ":" + target
+			if options != "" {
+				volume += ":" + options
+			}
+			args = append(args, "--volume", volume)
+		}
+
+		keys := make([]string, 0, len(task.Container.Env))
+		for key := range task.Container.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			args = append(args, "--env", key+"="+task.Container.Env[key])
+		}
+
+		if task.Container.Workdir != "" {
+			args = append(args, "--workdir", task.Container.Workdir)
+		}
+	}
+
+	if task.RunAs != nil {
+		user := task.RunAs.User
+		if task.RunAs.Group != "" {
+			user += ":" + task.RunAs.Group
+		}
+		args = append(args, "--user", user)
+	}
+
+	limits, err := limitsFrom(task.Resources)
+	if err != nil {
+		return nil, err
+	}
+	if limits.memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(limits.memory, 10))
+	}
+	if limits.maxProcesses > 0 {
+		args = append(args, "--pids-limit", strconv.FormatUint(limits.maxProcesses, 10))
+	}
+	if limits.nofile > 0 {
+		args = append(args, "--ulimit", fmt.Sprintf("nofile=%d:%d", limits.nofile, limits.nofile))
+	}
+	if limits.cpuSeconds > 0 {
+		args = append(args, "--ulimit", fmt.Sprintf("cpu=%d:%d", limits.cpuSeconds, limits.cpuSeconds))
+	}
+
+	args = append(args, task.Image)
+	return append(args, strings.Fields(command)...), nil
+}
+
+// executeContainer runs a task's command in a container of its image. When
+// the context ends, the container is removed rather than left running.
+func (tr *TaskRunner) executeContainer(ctx context.Context, task parser.Task, workflowID, command string) CommandResult {
+	result := CommandResult{}
+
+	runtime := tr.runtimeFor(task)
+	name := containerName(workflowID, task.ID)
+	args, err := containerArgs(task, name, command)
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = 1
+		return result
+	}
+
+	cmd := exec.Command(runtime, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		result.Error = fmt.Sprintf("failed to start container runtime '%s': %v", runtime, err)
+		result.ExitCode = 1
+		return result
+	}
+
+	// Killing the runtime CLI does not stop the container, so remove it first
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), containerStopTimeout)
+			defer cancel()
+			exec.CommandContext(stopCtx, runtime, "rm", "--force", name).Run()
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	err = cmd.Wait()
+	close(done)
+	<-stopped
+
+	if ctx.Err() != nil {
+		result.ExitCode = -1
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		result.Error = fmt.Sprintf("container %s stopped: %v", name, ctx.Err())
+		return result
+	}
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitError.ExitCode()
+			result.Stderr = stderr.String()
+		} else {
+			result.ExitCode = 1
+			result.Error = err.Error()
+		}
+	} else {
+		result.ExitCode = 0
+		result.Stdout = stdout.String()
+	}
+
+	return result
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// fakeRuntime writes a container CLI stub that logs every invocation. "run"
+// prints its arguments, or sleeps for the image "slow"; "rm" only logs.
+func fakeRuntime(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake runtime is a shell script")
+	}
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+echo "$*" >> "` + logFile + `"
+if [ "$1" = "run" ]; then
+  for arg in "$@"; do
+    if [ "$arg" = "slow" ]; then exec sleep 30; fi
+    if [ "$arg" = "broken" ]; then echo "pull failed" >&2; exit 125; fi
+  done
+  echo "$*"
+fi
+`
+	path := filepath.Join(dir, "fake-docker")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake runtime: %v", err)
+	}
+	return path, logFile
+}
+
+func TestTaskRunner_ExecuteTask_Container(t *testing.T) {
+	runtimePath, _ := fakeRuntime(t)
+	runner := NewTaskRunner()
+	runner.SetContainerRuntime(runtimePath)
+
+	task := parser.Task{
+		ID:      "build",
+		Command: "make all",
+		Image:   "golang:1.22",
+		RunAs:   &parser.RunAs{User: "1000", Group: "1000"},
+		Container: &parser.Container{
+			Mounts:  []string{"./src:/src:ro", "/cache:/root/.cache"},
+			Env:     map[string]string{"GOFLAGS": "-mod=mod", "CGO_ENABLED": "0"},
+			Workdir: "/src",
+		},
+		Resources: &parser.Resources{MemoryLimit: "512Mi", MaxProcesses: 100, Nofile: 1024, CPUTime: "1m"},
+	}
+
+	result := runner.ExecuteTask(context.Background(), task, "ci pipeline")
+	if !result.Success {
+		t.Fatalf("Expected success, got %s %s", result.Error, result.Stderr)
+	}
+
+	src, _ := filepath.Abs("./src")
+	args := strings.Fields(result.Stdout)
+	expected := []string{
+		"run", "--rm", "--name", args[3],
+		"--volume", src + ":/src:ro",
+		"--volume", "/cache:/root/.cache",
+		"--env", "CGO_ENABLED=0",
+		"--env", "GOFLAGS=-mod=mod",
+		"--workdir", "/src",
+		"--user", "1000:1000",
+		"--memory", "536870912",
+		"--pids-limit", "100",
+		"--ulimit", "nofile=1024:1024",
+		"--ulimit", "cpu=60:60",
+		"golang:1.22", "make", "all",
+	}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected arguments\n%v\ngot\n%v", expected, args)
+	}
+	if !strings.HasPrefix(args[3], "goliteflow-ci_pipeline-build-") {
+		t.Errorf("Expected a container name derived from the workflow and task, got %s", args[3])
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_ContainerIgnoresWorkflowIsolation(t *testing.T) {
+	runtimePath, _ := fakeRuntime(t)
+	runner := NewTaskRunner()
+	runner.SetContainerRuntime(runtimePath)
+
+	workflow := parser.Workflow{
+		Name:    "isolated",
+		RunAs:   &parser.RunAs{User: "no-such-user-goliteflow"},
+		Sandbox: &parser.Sandbox{NoNetwork: true, PrivateTmp: true},
+		Tasks: []parser.Task{
+			{ID: "inherits", Command: "true", Image: "alpine"},
+			{ID: "own-user", Command: "true", Image: "alpine", RunAs: &parser.RunAs{User: "nobody"}},
+		},
+	}
+
+	execution := runner.ExecuteWorkflow(context.Background(), &workflow)
+	if execution.Status != "completed" {
+		t.Fatalf("Expected success, got %+v", execution.TaskResults)
+	}
+	if stdout := execution.TaskResults[0].Stdout; strings.Contains(stdout, "--user") {
+		t.Errorf("Expected no host user in the container, got %s", stdout)
+	}
+	if stdout := execution.TaskResults[1].Stdout; !strings.Contains(stdout, "--user nobody") {
+		t.Errorf("Expected the task's own user, got %s", stdout)
+	}
+}
+
+func TestTaskRunner_ExecuteTask_ContainerRuntimeSelection(t *testing.T) {
+	runtimePath, logFile := fakeRuntime(t)
+	runner := NewTaskRunner()
+	runner.SetContainerRuntime("no-such-runtime-goliteflow")
+
+	// The task's runtime wins over the runner default
+	task := parser.Task{ID: "default-cmd", Image: "alpine", Container: &parser.Container{Runtime: runtimePath}}
+	result := runner.ExecuteTask(context.Background(), task, "wf")
+	if !result.Success {
+		t.Fatalf("Expected success, got %s", result.Error)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(result.Stdout), " alpine") {
+		t.Errorf("Expected the image's default command, got %q", result.Stdout)
+	}
+	if data, _ := os.ReadFile(logFile); !strings.HasPrefix(string(data), "run --rm") {
+		t.Errorf("Expected the task runtime to be called, log: %s", data)
+	}
+
+	result = runner.ExecuteTask(context.Background(), parser.Task{ID: "missing", Image: "alpine"}, "wf")
+	if result.Success || !strings.Contains(result.Error, "no-such-runtime-goliteflow") {
+		t.Errorf("Expected the runner default runtime to be used, got %+v", result)
+	}
+}
+
+func TestTaskRunner_ExecuteTask_ContainerFailure(t *testing.T) {
+	runtimePath, _ := fakeRuntime(t)
+	runner := NewTaskRunner()
+	runner.SetContainerRuntime(runtimePath)
+
+	result := runner.ExecuteTask(context.Background(), parser.Task{ID: "pull", Image: "broken"}, "wf")
+	if result.Success || result.ExitCode != 125 || !strings.Contains(result.Stderr, "pull failed") {
+		t.Errorf("Expected the runtime error to be reported, got %+v", result)
+	}
+}
+
+func TestTaskRunner_ExecuteTask_ContainerTimeout(t *testing.T) {
+	runtimePath, logFile := fakeRuntime(t)
+	runner := NewTaskRunner()
+	runner.SetContainerRuntime(runtimePath)
+
+	start := time.Now()
+	result := runner.ExecuteTask(context.Background(), parser.Task{ID: "hang", Image: "slow", Timeout: "200ms"}, "wf")
+	if result.Success {
+		t.Fatal("Expected the task to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the task to stop promptly, took %s", elapsed)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read runtime log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected run and rm calls, got:\n%s", data)
+	}
+	name := strings.Fields(lines[0])[3]
+	if lines[1] != "rm --force "+name {
+		t.Errorf("Expected the container %s to be removed, got %q", name, lines[1])
+	}
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// TaskFunc is a Go function run by tasks with func: name. The returned
+// outputs are available to later tasks as {{ .Outputs.task.name }}.
+type TaskFunc func(ctx context.Context, tc TaskContext) (map[string]string, error)
+
+// TaskContext describes the task a TaskFunc runs for
+type TaskContext struct {
+	Workflow string
+	TaskID   string
+	Params   map[string]string            // params of the workflow run
+	Outputs  map[string]map[string]string // outputs of the finished tasks, by task ID
+	Stdout   io.Writer                    // captured as the task's stdout
+}
+
+// RegisterFunc makes a function available to tasks with func: name
+func (tr *TaskRunner) RegisterFunc(name string, fn TaskFunc) {
+	tr.funcsMu.Lock()
+	defer tr.funcsMu.Unlock()
+	if tr.funcs == nil {
+		tr.funcs = make(map[string]TaskFunc)
+	}
+	tr.funcs[name] = fn
+}
+
+// lookupFunc returns a registered function
+func (tr *TaskRunner) lookupFunc(name string) (TaskFunc, bool) {
+	tr.funcsMu.RLock()
+	defer tr.funcsMu.RUnlock()
+	fn, ok := tr.funcs[name]
+	return fn, ok
+}
+
+// CheckFuncs verifies that every func task of a workflow names a registered
+// function, so a missing registration fails at start rather than on the first run
+func (tr *TaskRunner) CheckFuncs(workflow parser.Workflow) error {
+	var missing []string
+	for _, task := range workflow.Tasks {
+		if task.Func == "" {
+			continue
+		}
+		if _, ok := tr.lookupFunc(task.Func); !ok {
+			missing = append(missing, fmt.Sprintf("task '%s': function '%s' is not registered", task.ID, task.Func))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("workflow '%s': %s", workflow.Name, strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// lockedBuffer is a buffer a function may keep writing to after its task timed out
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// executeFunc calls the registered function of a task. A panic fails the
+// task with its stack as stderr. Functions that ignore their context are
+// abandoned when the task times out.
+func (tr *TaskRunner) executeFunc(ctx context.Context, task parser.Task, workflowID string) CommandResult {
+	result := CommandResult{}
+
+	fn, ok := tr.lookupFunc(task.Func)
+	if !ok {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("function '%s' is not registered", task.Func)
+		return result
+	}
+
+	// The run adds to its outputs map while an abandoned function may still read it
+	state := runStateFrom(ctx)
+	outputs := make(map[string]map[string]string, len(state.outputs))
+	for id, values := range state.outputs {
+		outputs[id] = values
+	}
+	stdout := &lockedBuffer{}
+	tc := TaskContext{
+		Workflow: workflowID,
+		TaskID:   task.ID,
+		Params:   state.params,
+		Outputs:  outputs,
+		Stdout:   stdout,
+	}
+
+	type funcResult struct {
+		outputs map[string]string
+		err     error
+		stack   string
+	}
+	done := make(chan funcResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- funcResult{err: fmt.Errorf("panic: %v", r), stack: string(debug.Stack())}
+			}
+		}()
+		outputs, err := fn(ctx, tc)
+		done <- funcResult{outputs: outputs, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		result.Stdout = stdout.String()
+		if res.err != nil {
+			result.ExitCode = 1
+			result.Error = res.err.Error()
+			result.Stderr = res.stack
+			return result
+		}
+		result.Outputs = res.outputs
+	case <-ctx.Done():
+		result.ExitCode = -1
+		result.Stdout = stdout.String()
+		result.Error = fmt.Sprintf("function '%s' stopped: %v", task.Func, ctx.Err())
+	}
+	return result
+}
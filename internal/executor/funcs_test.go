@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func TestTaskRunner_ExecuteTask_Func(t *testing.T) {
+	runner := NewTaskRunner()
+	var calls int32
+	runner.RegisterFunc("flaky", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, errors.New("not yet")
+		}
+		return map[string]string{"attempts": "2"}, nil
+	})
+	runner.RegisterFunc("panics", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+		var m map[string]string
+		m["x"] = "y"
+		return nil, nil
+	})
+	runner.RegisterFunc("hangs", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+		select {} // ignores its context
+	})
+
+	tests := []struct {
+		name        string
+		task        parser.Task
+		wantSuccess bool
+		wantError   string
+	}{
+		{name: "retried", task: parser.Task{ID: "a", Func: "flaky", Retry: 2}, wantSuccess: true},
+		{name: "panic", task: parser.Task{ID: "b", Func: "panics"}, wantError: "panic: assignment to entry in nil map"},
+		{name: "timeout", task: parser.Task{ID: "c", Func: "hangs", Timeout: "100ms"}, wantError: "function 'hangs' stopped"},
+		{name: "unregistered", task: parser.Task{ID: "d", Func: "missing"}, wantError: "function 'missing' is not registered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			result := runner.ExecuteTask(context.Background(), tt.task, "funcs")
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Expected success %v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, result.Error)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("Task took %s", time.Since(start))
+			}
+		})
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 calls of the flaky function, got %d", calls)
+	}
+}
+
+func TestTaskRunner_CheckFuncs(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.RegisterFunc("known", func(ctx context.Context, tc TaskContext) (map[string]string, error) { return nil, nil })
+
+	workflow := parser.Workflow{Name: "wf", Tasks: []parser.Task{{ID: "a", Func: "known"}, {ID: "b", Func: "unknown"}}}
+	err := runner.CheckFuncs(workflow)
+	if err == nil || err.Error() != "workflow 'wf': task 'b': function 'unknown' is not registered" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
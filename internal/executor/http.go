@@ -0,0 +1,257 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// maxHTTPBody caps how much of a response body http tasks read
+const maxHTTPBody = 10 << 20
+
+// httpClient sends the requests of http tasks; timeouts come from the task context
+var httpClient = &http.Client{}
+
+// executeHTTP sends the request of an http task and checks the response
+// against its expectations. The body becomes the task's stdout and, on
+// success, is saved to save_to and stored in the task outputs.
+func (tr *TaskRunner) executeHTTP(ctx context.Context, task parser.Task, workflowID string) CommandResult {
+	result := CommandResult{}
+	request := task.HTTP
+	state := runStateFrom(ctx)
+	data := templateData{Workflow: workflowID, Params: state.params, Outputs: state.outputs}
+
+	req, err := buildRequest(ctx, request, data)
+	if err != nil {
+		result.ExitCode = 1
+		result.Error = err.Error()
+		return result
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("request failed: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody+1))
+	if err != nil {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("failed to read response: %v", err)
+		return result
+	}
+	if len(body) > maxHTTPBody {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("response body exceeds %d bytes", maxHTTPBody)
+		return result
+	}
+	result.Stdout = string(body)
+
+	outputs, err := checkResponse(request, resp.StatusCode, body)
+	if err != nil {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("%s %s: %v", req.Method, req.URL.Redacted(), err)
+		return result
+	}
+
+	if request.SaveTo != "" {
+		saveTo, err := renderTemplate(request.SaveTo, data)
+		if err == nil {
+			err = writeBody(saveTo, body)
+		}
+		if err != nil {
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("failed to save response: %v", err)
+			return result
+		}
+	}
+	if request.Output != "" {
+		outputs[request.Output] = string(body)
+	}
+	if len(outputs) > 0 {
+		result.Outputs = outputs
+	}
+
+	result.ExitCode = 0
+	return result
+}
+
+// buildRequest renders the URL, headers and body of a request and applies its auth
+func buildRequest(ctx context.Context, request *parser.HTTPRequest, data templateData) (*http.Request, error) {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	url, err := renderTemplate(request.URL, data)
+	if err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+	body, err := renderTemplate(request.Body, data)
+	if err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("User-Agent", "goliteflow")
+
+	for name, value := range request.Headers {
+		rendered, err := renderTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("header '%s': %w", name, err)
+		}
+		req.Header.Set(name, rendered)
+	}
+
+	if auth := request.Auth; auth != nil {
+		if auth.Bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(auth.Bearer))
+		} else {
+			req.SetBasicAuth(os.ExpandEnv(auth.Username), os.ExpandEnv(auth.Password))
+		}
+	}
+	return req, nil
+}
+
+// checkResponse verifies the status and JSON assertions of a response and
+// returns the outputs extracted from its JSON body
+func checkResponse(request *parser.HTTPRequest, status int, body []byte) (map[string]string, error) {
+	var expect parser.HTTPExpect
+	if request.Expect != nil {
+		expect = *request.Expect
+	}
+
+	if !statusAccepted(status, expect.Status) {
+		return nil, fmt.Errorf("unexpected status %d", status)
+	}
+
+	outputs := make(map[string]string)
+	if len(expect.JSON) == 0 && len(request.Outputs) == 0 {
+		return outputs, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep numbers as written for comparisons and outputs
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	for _, assertion := range expect.JSON {
+		value, found, err := lookupJSON(document, assertion.Path)
+		if err != nil {
+			return nil, err
+		}
+		if assertion.Exists != nil && found != *assertion.Exists {
+			if found {
+				return nil, fmt.Errorf("expected %s not to exist", assertion.Path)
+			}
+			return nil, fmt.Errorf("expected %s to exist", assertion.Path)
+		}
+		if assertion.Equals != nil {
+			if !found {
+				return nil, fmt.Errorf("expected %s to equal %q, but it does not exist", assertion.Path, *assertion.Equals)
+			}
+			if actual := jsonString(value); actual != *assertion.Equals {
+				return nil, fmt.Errorf("expected %s to equal %q, got %q", assertion.Path, *assertion.Equals, actual)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(request.Outputs))
+	for name := range request.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, found, err := lookupJSON(document, request.Outputs[name])
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("output '%s': %s does not exist in the response", name, request.Outputs[name])
+		}
+		outputs[name] = jsonString(value)
+	}
+	return outputs, nil
+}
+
+// statusAccepted reports whether a status is one of the expected ones, or any
+// 2xx status when none are listed
+func statusAccepted(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, code := range expected {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupJSON finds the value at a path such as "data.items[0].id"
+func lookupJSON(document interface{}, path string) (interface{}, bool, error) {
+	segments, err := parser.ParseJSONPath(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid JSON path '%s': %w", path, err)
+	}
+
+	value := document
+	for _, segment := range segments {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false, nil
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false, nil
+			}
+			value = node[index]
+		default:
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// jsonString returns strings as-is and other JSON values in compact form
+func jsonString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// writeBody saves a response body, creating the parent directories
+func writeBody(path string, body []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, body, 0644)
+}
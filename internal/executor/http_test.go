@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func stringPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestTaskRunner_ExecuteTask_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"data": {"count": 2, "items": [{"id": "a1"}, {"id": "b2"}]}}`)
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, `{"method": %q, "body": %q}`, r.Method, body)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("TEST_API_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		request     parser.HTTPRequest
+		wantSuccess bool
+		wantOutputs map[string]string
+		wantError   string
+	}{
+		{
+			name: "json assertions and outputs",
+			request: parser.HTTPRequest{
+				URL:  server.URL + "/items",
+				Auth: &parser.HTTPAuth{Bearer: "$TEST_API_TOKEN"},
+				Expect: &parser.HTTPExpect{JSON: []parser.JSONAssertion{
+					{Path: "data.count", Equals: stringPtr("2")},
+					{Path: "data.items[1].id", Equals: stringPtr("b2")},
+					{Path: "data.next", Exists: boolPtr(false)},
+				}},
+				Outputs: map[string]string{"first": "data.items[0].id", "items": "data.items"},
+			},
+			wantSuccess: true,
+			wantOutputs: map[string]string{"first": "a1", "items": `[{"id":"a1"},{"id":"b2"}]`},
+		},
+		{
+			name:      "missing auth",
+			request:   parser.HTTPRequest{URL: server.URL + "/items"},
+			wantError: "unexpected status 401",
+		},
+		{
+			name: "failed assertion",
+			request: parser.HTTPRequest{
+				URL:    server.URL + "/items",
+				Auth:   &parser.HTTPAuth{Bearer: "$TEST_API_TOKEN"},
+				Expect: &parser.HTTPExpect{JSON: []parser.JSONAssertion{{Path: "data.count", Equals: stringPtr("3")}}},
+			},
+			wantError: `expected data.count to equal "3", got "2"`,
+		},
+		{
+			name:        "expected status",
+			request:     parser.HTTPRequest{URL: server.URL + "/missing", Expect: &parser.HTTPExpect{Status: []int{404}}},
+			wantSuccess: true,
+		},
+		{
+			name: "method, body and output",
+			request: parser.HTTPRequest{
+				Method: "post",
+				URL:    server.URL + "/echo",
+				Body:   "hello",
+				Output: "response",
+			},
+			wantSuccess: true,
+			wantOutputs: map[string]string{"response": `{"method": "POST", "body": "hello"}`},
+		},
+	}
+
+	runner := NewTaskRunner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			result := runner.ExecuteTask(context.Background(), parser.Task{ID: "call", HTTP: &request}, "api")
+
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Expected success %v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if tt.wantError != "" && !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, result.Error)
+			}
+			for name, want := range tt.wantOutputs {
+				if result.Outputs[name] != want {
+					t.Errorf("Output %s: expected %q, got %q", name, want, result.Outputs[name])
+				}
+			}
+		})
+	}
+}
+
+func TestTaskRunner_ExecuteTask_HTTPSaveTo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "report")
+	}))
+	defer server.Close()
+
+	saveTo := filepath.Join(t.TempDir(), "out", "{{ .Params.name }}.txt")
+	ctx := withRunState(context.Background(), runState{params: map[string]string{"name": "daily"}})
+	task := parser.Task{ID: "download", HTTP: &parser.HTTPRequest{URL: server.URL, SaveTo: saveTo}}
+
+	result := NewTaskRunner().ExecuteTask(ctx, task, "api")
+	if !result.Success {
+		t.Fatalf("Expected success, got %s", result.Error)
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(saveTo), "daily.txt"))
+	if err != nil || string(data) != "report" {
+		t.Errorf("Expected the body to be saved, got %q (%v)", data, err)
+	}
+}
+
+func TestTaskRunner_ExecuteTask_HTTPRetryAndTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	runner := NewTaskRunner()
+	result := runner.ExecuteTask(context.Background(), parser.Task{ID: "flaky", Retry: 2, HTTP: &parser.HTTPRequest{URL: server.URL}}, "api")
+	if !result.Success || result.RetryCount != 1 || result.Stdout != "ok" {
+		t.Errorf("Expected success on the second attempt, got %+v", result)
+	}
+
+	start := time.Now()
+	result = runner.ExecuteTask(context.Background(), parser.Task{ID: "slow", Timeout: "200ms", HTTP: &parser.HTTPRequest{URL: server.URL + "/slow"}}, "api")
+	if result.Success || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the request to time out, got %+v", result)
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_HTTPOutputs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token": "t-123"}`)
+			return
+		}
+		if r.Header.Get("X-Token") != "t-123" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	workflow := &parser.Workflow{
+		Name: "api",
+		Tasks: []parser.Task{
+			{ID: "login", HTTP: &parser.HTTPRequest{URL: server.URL + "/token", Outputs: map[string]string{"token": "token"}}},
+			{ID: "fetch", DependsOn: []string{"login"}, HTTP: &parser.HTTPRequest{
+				URL:     server.URL + "/data",
+				Headers: map[string]string{"X-Token": "{{ .Outputs.login.token }}"},
+			}},
+			{ID: "print", DependsOn: []string{"login"}, Command: "echo {{ .Outputs.login.token }}"},
+		},
+	}
+
+	execution := NewTaskRunner().ExecuteWorkflow(context.Background(), workflow)
+	if execution.Status != "completed" {
+		t.Fatalf("Expected completed, got %s: %s", execution.Status, execution.ErrorMessage)
+	}
+	if got := strings.TrimSpace(execution.TaskResults[2].Stdout); got != "t-123" {
+		t.Errorf("Expected the output in the command, got %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// CheckPrivileges verifies that this process can apply the run_as and
+// sandbox settings of every task of a workflow, so a daemon without the
+// required privileges fails at start rather than on the first run
+func CheckPrivileges(workflow parser.Workflow) error {
+	var problems []string
+	for _, task := range workflow.Tasks {
+		if err := checkTaskPrivileges(task, workflow); err != nil {
+			problems = append(problems, fmt.Sprintf("task '%s': %v", task.ID, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("workflow '%s': %s", workflow.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkTaskPrivileges checks the run_as and sandbox a task runs with on this
+// host, if any
+func checkTaskPrivileges(task parser.Task, workflow parser.Workflow) error {
+	if task.Image != "" {
+		return nil // run_as applies inside the container
+	}
+	if task.Func != "" {
+		return nil // runs in-process
+	}
+	if len(effectiveRunsOn(task, workflow)) > 0 {
+		return nil // runs on a worker
+	}
+	runAs, sandbox := effectiveIsolation(task, workflow)
+	if runAs == nil && sandbox == nil {
+		return nil
+	}
+	return checkIsolation(runAs, sandbox)
+}
+
+// effectiveIsolation returns the run_as and sandbox of a task, falling back
+// to the workflow defaults. Task-level settings replace the defaults whole.
+// Image tasks take neither default: the container is already isolated, and
+// a host user may not exist inside the image.
+func effectiveIsolation(task parser.Task, workflow parser.Workflow) (*parser.RunAs, *parser.Sandbox) {
+	runAs, sandbox := task.RunAs, task.Sandbox
+	if task.Image != "" {
+		return runAs, sandbox
+	}
+	if runAs == nil {
+		runAs = workflow.RunAs
+	}
+	if sandbox == nil {
+		sandbox = workflow.Sandbox
+	}
+	return runAs, sandbox
+}
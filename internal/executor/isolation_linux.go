@@ -0,0 +1,243 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the sandbox and resource limits of a task to the
+// re-executed binary, which applies them before executing the task command
+const sandboxEnv = "GOLITEFLOW_SANDBOX"
+
+// sandboxArg0 is the argv[0] of the re-executed binary. The hook needs it as
+// well as sandboxEnv, so a program embedding GoliteFlow that inherits the
+// variable from its environment is not taken over by the hook.
+const sandboxArg0 = "goliteflow-sandbox"
+
+// geteuid is replaced in tests to check privilege validation
+var geteuid = os.Geteuid
+
+// credential is a resolved run_as
+type credential struct {
+	Name string `json:"name"`
+	Home string `json:"home"`
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+}
+
+// sandboxSpec is what the re-executed binary needs to enter the sandbox
+type sandboxSpec struct {
+	Cgroup     string         `json:"cgroup,omitempty"`
+	Rlimits    map[int]uint64 `json:"rlimits,omitempty"`
+	PrivateTmp bool           `json:"private_tmp,omitempty"`
+	ReadOnly   []string       `json:"read_only,omitempty"`
+	Umask      *int           `json:"umask,omitempty"`
+	Credential *credential    `json:"credential,omitempty"`
+}
+
+// init is the hook of the re-executed binary. It runs before the main
+// package of any program importing GoliteFlow, which is why it checks both
+// argv[0] and the environment.
+func init() {
+	encoded, ok := os.LookupEnv(sandboxEnv)
+	if !ok || len(os.Args) == 0 || os.Args[0] != sandboxArg0 {
+		return
+	}
+	os.Unsetenv(sandboxEnv)
+	err := enterSandbox(encoded, os.Args[1:])
+	fmt.Fprintf(os.Stderr, "goliteflow sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// enterSandbox runs in the re-executed binary: it joins the task's cgroup,
+// mounts the private /tmp and read-only paths, sets the umask and rlimits,
+// drops privileges and executes the command. It only returns on error.
+func enterSandbox(encoded string, args []string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		return fmt.Errorf("invalid sandbox specification: %w", err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+
+	if spec.Cgroup != "" {
+		if err := os.WriteFile(filepath.Join(spec.Cgroup, "cgroup.procs"), []byte("0"), 0644); err != nil {
+			return fmt.Errorf("failed to join cgroup: %w", err)
+		}
+	}
+
+	if spec.PrivateTmp || len(spec.ReadOnly) > 0 {
+		// Keep the mounts below from propagating to the host
+		if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("failed to make mounts private: %w", err)
+		}
+	}
+	if spec.PrivateTmp {
+		if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("failed to mount private /tmp: %w", err)
+		}
+		os.Setenv("TMPDIR", "/tmp")
+	}
+	for _, dir := range spec.ReadOnly {
+		if err := unix.Mount(dir, dir, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", dir, err)
+		}
+		if err := unix.Mount("", dir, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, ""); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", dir, err)
+		}
+	}
+
+	if spec.Umask != nil {
+		unix.Umask(*spec.Umask)
+	}
+	for resource, value := range spec.Rlimits {
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("failed to set resource limit %d: %w", resource, err)
+		}
+	}
+	if cred := spec.Credential; cred != nil {
+		if err := syscall.Setgroups([]int{cred.GID}); err != nil {
+			return fmt.Errorf("failed to set groups: %w", err)
+		}
+		if err := syscall.Setgid(cred.GID); err != nil {
+			return fmt.Errorf("failed to set group %d: %w", cred.GID, err)
+		}
+		if err := syscall.Setuid(cred.UID); err != nil {
+			return fmt.Errorf("failed to set user %d: %w", cred.UID, err)
+		}
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+// lookupCredential resolves a run_as user and group by name or numeric id
+func lookupCredential(runAs *parser.RunAs) (*credential, error) {
+	u, err := user.Lookup(runAs.User)
+	if err != nil {
+		if u, err = user.LookupId(runAs.User); err != nil {
+			return nil, fmt.Errorf("unknown user '%s'", runAs.User)
+		}
+	}
+
+	cred := &credential{Name: u.Username, Home: u.HomeDir}
+	if cred.UID, err = strconv.Atoi(u.Uid); err != nil {
+		return nil, fmt.Errorf("user '%s' has a non-numeric uid %s", runAs.User, u.Uid)
+	}
+	gid := u.Gid
+	if runAs.Group != "" {
+		g, err := user.LookupGroup(runAs.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(runAs.Group); err != nil {
+				return nil, fmt.Errorf("unknown group '%s'", runAs.Group)
+			}
+		}
+		gid = g.Gid
+	}
+	if cred.GID, err = strconv.Atoi(gid); err != nil {
+		return nil, fmt.Errorf("group of user '%s' has a non-numeric gid %s", runAs.User, gid)
+	}
+	return cred, nil
+}
+
+// checkIsolation verifies that the user and group exist and that this
+// process has the privileges to switch to them and create namespaces
+func checkIsolation(runAs *parser.RunAs, sandbox *parser.Sandbox) error {
+	root := geteuid() == 0
+	if runAs != nil {
+		cred, err := lookupCredential(runAs)
+		if err != nil {
+			return err
+		}
+		if !root && (cred.UID != os.Geteuid() || cred.GID != os.Getegid()) {
+			return fmt.Errorf("run_as user '%s' requires goliteflow to run as root", runAs.User)
+		}
+	}
+	if sandbox.NeedsNamespaces() && !root {
+		return fmt.Errorf("sandbox private_tmp, read_only and no_network require goliteflow to run as root (CAP_SYS_ADMIN)")
+	}
+	if sandbox != nil && sandbox.Umask != "" {
+		if _, err := parser.ParseUmask(sandbox.Umask); err != nil {
+			return fmt.Errorf("invalid umask '%s': %w", sandbox.Umask, err)
+		}
+	}
+	return nil
+}
+
+// isolatedCommand creates the command of a task. A run_as alone is applied
+// through SysProcAttr.Credential. Resource limits and sandboxes re-execute
+// this binary, in new namespaces when needed, which applies them before the
+// command starts.
+func isolatedCommand(ctx context.Context, parts []string, runAs *parser.RunAs, sandbox *parser.Sandbox, lim *limiter) (*exec.Cmd, error) {
+	if runAs == nil && sandbox == nil && lim == nil {
+		return exec.CommandContext(ctx, parts[0], parts[1:]...), nil
+	}
+	if err := checkIsolation(runAs, sandbox); err != nil {
+		return nil, err
+	}
+
+	env := os.Environ()
+	var cred *credential
+	if runAs != nil {
+		cred, _ = lookupCredential(runAs) // checked above
+		env = append(env, "HOME="+cred.Home, "USER="+cred.Name, "LOGNAME="+cred.Name)
+	}
+
+	if sandbox == nil && lim == nil {
+		cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+		cmd.Env = env
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: uint32(cred.UID), Gid: uint32(cred.GID), Groups: []uint32{uint32(cred.GID)}},
+		}
+		return cmd, nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the goliteflow binary for the sandbox: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, self, parts...)
+	cmd.Args[0] = sandboxArg0
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+
+	spec := sandboxSpec{Credential: cred}
+	if lim != nil {
+		spec.Cgroup = lim.cgroup
+		spec.Rlimits = lim.rlimits()
+	}
+	if sandbox != nil {
+		spec.PrivateTmp = sandbox.PrivateTmp
+		spec.ReadOnly = sandbox.ReadOnly
+		if sandbox.Umask != "" {
+			umask, _ := parser.ParseUmask(sandbox.Umask) // checked above
+			spec.Umask = &umask
+		}
+		if sandbox.PrivateTmp || len(sandbox.ReadOnly) > 0 {
+			cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
+		}
+		if sandbox.NoNetwork {
+			cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+		}
+	}
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = append(env, sandboxEnv+"="+string(encoded))
+	return cmd, nil
+}
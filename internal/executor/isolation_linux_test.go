@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func requireRoot(t *testing.T) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+}
+
+func TestTaskRunner_ExecuteTask_Sandbox(t *testing.T) {
+	readOnly := t.TempDir()
+
+	tests := []struct {
+		name    string
+		task    parser.Task
+		root    bool
+		success bool
+		stdout  string
+	}{
+		{
+			name:    "umask",
+			task:    parser.Task{ID: "umask", Command: "sh -c umask", Sandbox: &parser.Sandbox{Umask: "0027"}},
+			success: true,
+			stdout:  "0027",
+		},
+		{
+			name:    "run as nobody",
+			task:    parser.Task{ID: "user", Command: "id -u", RunAs: &parser.RunAs{User: "nobody"}},
+			root:    true,
+			success: true,
+			stdout:  "65534",
+		},
+		{
+			name:    "run as nobody in a sandbox",
+			task:    parser.Task{ID: "sandboxed-user", Command: "id -u", RunAs: &parser.RunAs{User: "nobody"}, Sandbox: &parser.Sandbox{PrivateTmp: true}},
+			root:    true,
+			success: true,
+			stdout:  "65534",
+		},
+		{
+			name:    "private tmp",
+			task:    parser.Task{ID: "tmp", Command: "ls -A /tmp", Sandbox: &parser.Sandbox{PrivateTmp: true}},
+			root:    true,
+			success: true,
+			stdout:  "",
+		},
+		{
+			name:    "read-only path",
+			task:    parser.Task{ID: "ro", Command: "touch " + filepath.Join(readOnly, "written"), Sandbox: &parser.Sandbox{ReadOnly: []string{readOnly}}},
+			root:    true,
+			success: false,
+		},
+		{
+			name:    "no network",
+			task:    parser.Task{ID: "net", Command: "cat /proc/net/dev", Sandbox: &parser.Sandbox{NoNetwork: true}},
+			root:    true,
+			success: true,
+		},
+	}
+
+	runner := NewTaskRunner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.root {
+				requireRoot(t)
+			}
+
+			result := runner.ExecuteTask(context.Background(), tt.task, "sandbox")
+			if result.Success != tt.success {
+				t.Fatalf("Expected success %v, got %v: %s %s", tt.success, result.Success, result.Error, result.Stderr)
+			}
+			if tt.success && tt.task.ID != "net" && strings.TrimSpace(result.Stdout) != tt.stdout {
+				t.Errorf("Expected stdout %q, got %q", tt.stdout, result.Stdout)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(readOnly, "written")); err == nil {
+		t.Error("Expected the read-only path to stay unchanged")
+	}
+}
+
+func TestTaskRunner_ExecuteTask_NoNetworkInterfaces(t *testing.T) {
+	requireRoot(t)
+
+	runner := NewTaskRunner()
+	result := runner.ExecuteTask(context.Background(), parser.Task{ID: "net", Command: "cat /proc/net/dev", Sandbox: &parser.Sandbox{NoNetwork: true}}, "sandbox")
+	if !result.Success {
+		t.Fatalf("Expected success, got %s %s", result.Error, result.Stderr)
+	}
+
+	// Two header lines, then one line per interface
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], "lo:") {
+		t.Errorf("Expected only the loopback interface, got:\n%s", result.Stdout)
+	}
+}
+
+func TestIsolatedCommand_MarksReexec(t *testing.T) {
+	cmd, err := isolatedCommand(context.Background(), []string{"echo", "hi"}, nil, &parser.Sandbox{Umask: "0077"}, nil)
+	if err != nil {
+		t.Fatalf("isolatedCommand() error = %v", err)
+	}
+	if cmd.Args[0] != sandboxArg0 || cmd.Args[1] != "echo" {
+		t.Errorf("Expected the re-executed binary to be marked by argv[0], got %v", cmd.Args)
+	}
+	if env := cmd.Env[len(cmd.Env)-1]; !strings.HasPrefix(env, sandboxEnv+"=") {
+		t.Errorf("Expected the sandbox in the environment, got %s", env)
+	}
+}
+
+func TestCheckPrivileges(t *testing.T) {
+	defer func(original func() int) { geteuid = original }(geteuid)
+	geteuid = func() int { return 1000 }
+
+	workflow := parser.Workflow{
+		Name:    "isolated",
+		Sandbox: &parser.Sandbox{NoNetwork: true},
+		Tasks: []parser.Task{
+			{ID: "umask-only", Command: "echo hi", Sandbox: &parser.Sandbox{Umask: "0077"}},
+			{ID: "inherits", Command: "echo hi"},
+			{ID: "unknown-user", Command: "echo hi", RunAs: &parser.RunAs{User: "no-such-user-goliteflow"}, Sandbox: &parser.Sandbox{}},
+		},
+	}
+
+	err := CheckPrivileges(workflow)
+	if err == nil {
+		t.Fatal("Expected an error for an unprivileged process")
+	}
+	for _, want := range []string{"task 'inherits': sandbox", "require goliteflow to run as root", "task 'unknown-user': unknown user"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "umask-only") {
+		t.Errorf("Expected a umask-only sandbox to need no privileges, got %v", err)
+	}
+
+	geteuid = func() int { return 0 }
+	workflow.Tasks = workflow.Tasks[:2]
+	if err := CheckPrivileges(workflow); err != nil {
+		t.Errorf("Expected no error as root, got %v", err)
+	}
+}
+
+func TestPlanWorkflow_Warnings(t *testing.T) {
+	defer func(original func() int) { geteuid = original }(geteuid)
+	geteuid = func() int { return 1000 }
+
+	workflow := parser.Workflow{
+		Name: "isolated",
+		Tasks: []parser.Task{
+			{ID: "compute", Func: "compute"},
+			{ID: "as-daemon", Command: "echo hi", RunAs: &parser.RunAs{User: "daemon"}},
+			{ID: "plain", Command: "echo hi"},
+		},
+	}
+
+	plan := NewTaskRunner().PlanWorkflow(&workflow, RunOptions{})
+	if plan.Error != "" || len(plan.Tasks) != 3 {
+		t.Fatalf("Expected a plan of 3 tasks, got %+v", plan)
+	}
+	warnings := make(map[string]string)
+	for _, task := range plan.Tasks {
+		warnings[task.ID] = strings.Join(task.Warnings, "; ")
+	}
+	if !strings.Contains(warnings["compute"], "function 'compute' is not registered") {
+		t.Errorf("Expected a warning about the missing function, got %q", warnings["compute"])
+	}
+	if !strings.Contains(warnings["as-daemon"], "root") {
+		t.Errorf("Expected a warning about run_as without root, got %q", warnings["as-daemon"])
+	}
+	if warnings["plain"] != "" {
+		t.Errorf("Expected no warning for a plain command, got %q", warnings["plain"])
+	}
+}
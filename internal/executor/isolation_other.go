@@ -0,0 +1,26 @@
+//go:build !linux
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// checkIsolation reports that run_as and sandbox are unsupported outside Linux
+func checkIsolation(runAs *parser.RunAs, sandbox *parser.Sandbox) error {
+	return fmt.Errorf("run_as and sandbox are not supported on %s", runtime.GOOS)
+}
+
+// isolatedCommand creates the command of a task; run_as and sandbox are
+// rejected, and newLimiter has already rejected resource limits
+func isolatedCommand(ctx context.Context, parts []string, runAs *parser.RunAs, sandbox *parser.Sandbox, lim *limiter) (*exec.Cmd, error) {
+	if runAs != nil || sandbox != nil {
+		return nil, checkIsolation(runAs, sandbox)
+	}
+	return exec.CommandContext(ctx, parts[0], parts[1:]...), nil
+}
@@ -0,0 +1,221 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// WorkflowPlan describes what a workflow run would do, without running anything
+type WorkflowPlan struct {
+	Name        string            `json:"name"`
+	Schedule    string            `json:"schedule,omitempty"`
+	TriggeredBy []string          `json:"triggered_by,omitempty"` // "upstream (status)"
+	NextRuns    []time.Time       `json:"next_runs,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	Stages      [][]string        `json:"stages"` // task IDs; tasks in a stage do not depend on each other
+	Tasks       []TaskPlan        `json:"tasks"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// TaskPlan describes how a task would run
+type TaskPlan struct {
+	ID          string            `json:"id"`
+	Stage       int               `json:"stage"`
+	Command     string            `json:"command,omitempty"` // with {{ ... }} placeholders expanded
+	Image       string            `json:"image,omitempty"`   // container image the command runs in
+	HTTP        string            `json:"http,omitempty"`    // method and URL of an http task
+	Func        string            `json:"func,omitempty"`    // registered Go function
+	DependsOn   []string          `json:"depends_on,omitempty"`
+	WaitFor     string            `json:"wait_for,omitempty"`
+	Timeout     string            `json:"timeout"` // covers all attempts
+	Attempts    int               `json:"attempts"`
+	Backoff     []string          `json:"backoff,omitempty"`   // delay before each retry
+	Resources   *parser.Resources `json:"resources,omitempty"` // merged with the workflow defaults
+	RunAs       *parser.RunAs     `json:"run_as,omitempty"`
+	Sandbox     *parser.Sandbox   `json:"sandbox,omitempty"`
+	SubWorkflow *WorkflowPlan     `json:"sub_workflow,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"` // privileges or functions this process lacks to run the task
+}
+
+// PlanWorkflow resolves a workflow the way ExecuteWorkflowWithOptions would
+// run it: params are merged, commands templated and tasks grouped into stages
+func (tr *TaskRunner) PlanWorkflow(workflow *parser.Workflow, opts RunOptions) WorkflowPlan {
+	return tr.planWorkflow(workflow, mergeParams(workflow.Params, opts.Params), nil)
+}
+
+func (tr *TaskRunner) planWorkflow(workflow *parser.Workflow, params map[string]string, parents []string) WorkflowPlan {
+	plan := WorkflowPlan{
+		Name:     workflow.Name,
+		Schedule: workflow.Schedule,
+		Params:   params,
+		Stages:   [][]string{},
+		Tasks:    []TaskPlan{},
+	}
+	for _, trigger := range workflow.TriggeredBy {
+		status := trigger.Status
+		if status == "" {
+			status = parser.TriggerOnCompleted
+		}
+		plan.TriggeredBy = append(plan.TriggeredBy, fmt.Sprintf("%s (%s)", trigger.Workflow, status))
+	}
+
+	sorted, err := parser.NewYAMLParser().TopologicalSort(workflow)
+	if err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+
+	// A task's stage is one past the latest stage of its dependencies
+	stages := make(map[string]int)
+	for _, task := range sorted {
+		stage := 1
+		for _, dep := range task.DependsOn {
+			if stages[dep]+1 > stage {
+				stage = stages[dep] + 1
+			}
+		}
+		stages[task.ID] = stage
+
+		for len(plan.Stages) < stage {
+			plan.Stages = append(plan.Stages, []string{})
+		}
+		plan.Stages[stage-1] = append(plan.Stages[stage-1], task.ID)
+		warnings := tr.taskWarnings(task, *workflow)
+		task.Resources = task.Resources.WithDefaults(workflow.Resources)
+		task.RunAs, task.Sandbox = effectiveIsolation(task, *workflow)
+		taskPlan := tr.planTask(task, stage, workflow.Name, params, parents)
+		taskPlan.Warnings = warnings
+		plan.Tasks = append(plan.Tasks, taskPlan)
+	}
+
+	return plan
+}
+
+func (tr *TaskRunner) planTask(task parser.Task, stage int, workflowName string, params map[string]string, parents []string) TaskPlan {
+	plan := TaskPlan{
+		ID:        task.ID,
+		Stage:     stage,
+		DependsOn: task.DependsOn,
+		Timeout:   tr.timeout.String(),
+		Attempts:  task.Retry,
+		Image:     task.Image,
+		Func:      task.Func,
+		Resources: task.Resources,
+		RunAs:     task.RunAs,
+		Sandbox:   task.Sandbox,
+	}
+
+	if task.Timeout != "" {
+		if timeout, err := time.ParseDuration(task.Timeout); err == nil {
+			plan.Timeout = timeout.String()
+		}
+	}
+
+	// Mirrors the retry loop of ExecuteTask
+	if plan.Attempts == 0 {
+		plan.Attempts = 1
+	}
+	for attempt := 0; attempt < plan.Attempts-1; attempt++ {
+		plan.Backoff = append(plan.Backoff, tr.calculateBackoff(attempt).String())
+	}
+
+	if task.WaitFor != nil {
+		plan.WaitFor = describeWait(*task.WaitFor)
+	}
+
+	data := templateData{Workflow: workflowName, Params: params}
+	if task.Command != "" {
+		command, err := planTemplate(task.Command, data)
+		if err != nil {
+			plan.Error = err.Error()
+		}
+		plan.Command = command
+	}
+	if task.HTTP != nil {
+		method := strings.ToUpper(task.HTTP.Method)
+		if method == "" {
+			method = "GET"
+		}
+		url, err := planTemplate(task.HTTP.URL, data)
+		if err != nil {
+			plan.Error = err.Error()
+		}
+		plan.HTTP = method + " " + url
+	}
+
+	if task.Workflow != "" {
+		plan.SubWorkflow, plan.Error = tr.planSubWorkflow(task, data, append(append([]string{}, parents...), workflowName))
+	}
+
+	return plan
+}
+
+// taskWarnings lists what would keep a task from running in this process:
+// the checks AddWorkflows fails on, reported without failing the plan
+func (tr *TaskRunner) taskWarnings(task parser.Task, workflow parser.Workflow) []string {
+	var warnings []string
+	if task.Func != "" {
+		if _, ok := tr.lookupFunc(task.Func); !ok {
+			warnings = append(warnings, fmt.Sprintf("function '%s' is not registered", task.Func))
+		}
+	}
+	if err := checkTaskPrivileges(task, workflow); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	return warnings
+}
+
+// planTemplate renders a template for a plan. Task outputs are only known
+// during a run, so templates that reference them are shown as written.
+func planTemplate(text string, data templateData) (string, error) {
+	if strings.Contains(text, ".Outputs") {
+		return text, nil
+	}
+	return renderTemplate(text, data)
+}
+
+// planSubWorkflow plans the child run of a workflow task
+func (tr *TaskRunner) planSubWorkflow(task parser.Task, data templateData, parents []string) (*WorkflowPlan, string) {
+	for _, parent := range parents {
+		if parent == task.Workflow {
+			return nil, fmt.Sprintf("recursive sub-workflow detected: %s -> %s", strings.Join(parents, " -> "), task.Workflow)
+		}
+	}
+
+	if tr.workflows == nil {
+		return nil, fmt.Sprintf("cannot plan sub-workflow '%s': no workflows available", task.Workflow)
+	}
+	child, ok := tr.workflows.GetWorkflow(task.Workflow)
+	if !ok {
+		return nil, fmt.Sprintf("sub-workflow '%s' not found", task.Workflow)
+	}
+
+	params := make(map[string]string, len(task.Params))
+	for key, value := range task.Params {
+		rendered, err := renderTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Sprintf("param '%s': %v", key, err)
+		}
+		params[key] = rendered
+	}
+
+	plan := tr.planWorkflow(&child, mergeParams(child.Params, params), parents)
+	return &plan, ""
+}
+
+// describeWait summarises a wait_for sensor
+func describeWait(dep parser.ExternalDependency) string {
+	description := fmt.Sprintf("successful run of '%s'", dep.Workflow)
+	if dep.Within != "" {
+		description += " within " + dep.Within
+	}
+	pollInterval := dep.PollInterval
+	if pollInterval == "" {
+		pollInterval = defaultPollInterval.String()
+	}
+	return description + ", polling every " + pollInterval
+}
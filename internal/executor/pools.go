@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// QueueStats counts the tasks running and waiting for slots
+type QueueStats struct {
+	Running            int                  `json:"running"`
+	Queued             int                  `json:"queued"`
+	MaxConcurrentTasks int                  `json:"max_concurrent_tasks,omitempty"` // 0 for no limit
+	Pools              map[string]PoolStats `json:"pools,omitempty"`
+}
+
+// PoolStats counts the slots of a pool in use and the tasks waiting for them
+type PoolStats struct {
+	Slots  int `json:"slots"`
+	Used   int `json:"used"`
+	Queued int `json:"queued"`
+}
+
+// slotRequest is a task waiting for its slots
+type slotRequest struct {
+	pool  string
+	slots int
+	ready chan struct{} // closed once the slots are taken for the task
+}
+
+// slotLimits caps the tasks running at once, in total and per pool. Tasks
+// get their slots in the order they asked for them, except that a task does
+// not wait behind the tasks of another pool that is full.
+type slotLimits struct {
+	mu      sync.Mutex
+	max     int            // tasks at once, 0 for no limit
+	pools   map[string]int // slots of each pool
+	running int
+	used    map[string]int // slots in use, by pool
+	waiting []*slotRequest // oldest first
+}
+
+func newSlotLimits() *slotLimits {
+	return &slotLimits{pools: make(map[string]int), used: make(map[string]int)}
+}
+
+// SetLimits caps the tasks running at once across workflows, 0 for no limit,
+// and sets the pools tasks take slots of with pool. Tasks waiting for slots
+// are started if the new limits allow it; running tasks keep their slots.
+func (tr *TaskRunner) SetLimits(maxConcurrentTasks int, pools map[string]parser.Pool) {
+	l := tr.slots
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.max = maxConcurrentTasks
+	l.pools = make(map[string]int, len(pools))
+	for name, pool := range pools {
+		l.pools[name] = pool.Slots
+	}
+	l.grant()
+}
+
+// QueueStats returns the number of tasks running and waiting for slots, in
+// total and per pool
+func (tr *TaskRunner) QueueStats() QueueStats {
+	l := tr.slots
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := QueueStats{Running: l.running, Queued: len(l.waiting), MaxConcurrentTasks: l.max}
+	if len(l.pools) > 0 {
+		stats.Pools = make(map[string]PoolStats, len(l.pools))
+		for name, slots := range l.pools {
+			stats.Pools[name] = PoolStats{Slots: slots, Used: l.used[name]}
+		}
+		for _, req := range l.waiting {
+			if pool, ok := stats.Pools[req.pool]; ok {
+				pool.Queued++
+				stats.Pools[req.pool] = pool
+			}
+		}
+	}
+	return stats
+}
+
+// takesSlots reports whether a task counts against the limits. Sub-workflow
+// tasks do not, their tasks do, and neither do tasks that only wait_for.
+func takesSlots(task parser.Task) bool {
+	if task.Workflow != "" {
+		return false
+	}
+	return task.Command != "" || task.Image != "" || task.HTTP != nil || task.Func != ""
+}
+
+// acquire takes a slot for the task and its pool_slots of its pool, waiting
+// until they are free or ctx is done. queued is called with the reason
+// before the task starts to wait. The returned function gives them back.
+func (l *slotLimits) acquire(ctx context.Context, task parser.Task, queued func(reason string)) (func(), error) {
+	req := &slotRequest{pool: task.Pool, slots: task.PoolSlots, ready: make(chan struct{})}
+	if req.pool != "" && req.slots == 0 {
+		req.slots = 1
+	}
+
+	l.mu.Lock()
+	if req.pool != "" {
+		capacity, ok := l.pools[req.pool]
+		if !ok {
+			l.mu.Unlock()
+			return nil, fmt.Errorf("pool '%s' is not defined", req.pool)
+		}
+		if req.slots > capacity {
+			// The pool shrank below what the task asks for
+			req.slots = capacity
+		}
+	}
+	l.waiting = append(l.waiting, req)
+	l.grant()
+	reason := l.blockedBy(req)
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.running--
+		l.used[req.pool] -= req.slots
+		l.grant()
+	}
+
+	select {
+	case <-req.ready:
+		return release, nil
+	default:
+	}
+	queued(reason)
+
+	select {
+	case <-req.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	select {
+	case <-req.ready:
+		// Granted at the same time: give the slots back
+		l.mu.Unlock()
+		release()
+	default:
+		l.remove(req)
+		l.grant()
+		l.mu.Unlock()
+	}
+	return nil, fmt.Errorf("cancelled while waiting for slots: %w", ctx.Err())
+}
+
+// grant gives slots to the waiting tasks that fit, oldest first; l.mu is held
+func (l *slotLimits) grant() {
+	full := false                  // no task can start
+	fullPools := map[string]bool{} // no task of these pools can start
+	waiting := l.waiting[:0]
+	for _, req := range l.waiting {
+		if full || fullPools[req.pool] {
+			waiting = append(waiting, req)
+			continue
+		}
+		if l.max > 0 && l.running >= l.max {
+			full = true
+			waiting = append(waiting, req)
+			continue
+		}
+		if req.pool != "" && l.used[req.pool]+req.slots > l.pools[req.pool] {
+			fullPools[req.pool] = true
+			waiting = append(waiting, req)
+			continue
+		}
+		l.running++
+		l.used[req.pool] += req.slots
+		close(req.ready)
+	}
+	for i := len(waiting); i < len(l.waiting); i++ {
+		l.waiting[i] = nil
+	}
+	l.waiting = waiting
+}
+
+// blockedBy describes what a waiting task waits for; l.mu is held
+func (l *slotLimits) blockedBy(req *slotRequest) string {
+	if l.max > 0 && l.running >= l.max {
+		return fmt.Sprintf("max_concurrent_tasks reached (%d running)", l.running)
+	}
+	if req.pool != "" {
+		return fmt.Sprintf("pool '%s' has %d of %d slot(s) free, needs %d", req.pool,
+			l.pools[req.pool]-l.used[req.pool], l.pools[req.pool], req.slots)
+	}
+	return "waiting behind earlier tasks"
+}
+
+// remove drops a waiting task; l.mu is held
+func (l *slotLimits) remove(req *slotRequest) {
+	for i, waiting := range l.waiting {
+		if waiting == req {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func TestTaskRunner_SetLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       int
+		pools     map[string]parser.Pool
+		pool      string
+		poolSlots int
+		wantPeak  int
+	}{
+		{"no limits", 0, nil, "", 0, 4},
+		{"max_concurrent_tasks", 2, nil, "", 0, 2},
+		{"pool slots", 0, map[string]parser.Pool{"db": {Slots: 3}}, "db", 0, 3},
+		{"pool_slots", 0, map[string]parser.Pool{"db": {Slots: 3}}, "db", 2, 1},
+		{"both", 1, map[string]parser.Pool{"db": {Slots: 3}}, "db", 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewTaskRunner()
+			runner.SetLimits(tt.max, tt.pools)
+
+			var mu sync.Mutex
+			running, peak := 0, 0
+			runner.RegisterFunc("work", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+				mu.Lock()
+				if running++; running > peak {
+					peak = running
+				}
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil, nil
+			})
+
+			results := make(chan parser.ExecutionResult, 4)
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				workflow := &parser.Workflow{Name: fmt.Sprintf("wf%d", i), Tasks: []parser.Task{
+					{ID: "work", Func: "work", Pool: tt.pool, PoolSlots: tt.poolSlots},
+				}}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					execution := runner.ExecuteWorkflow(context.Background(), workflow)
+					results <- execution.TaskResults[0]
+				}()
+			}
+			wg.Wait()
+			close(results)
+
+			if peak != tt.wantPeak {
+				t.Errorf("Expected at most %d tasks at once, got %d", tt.wantPeak, peak)
+			}
+			queued := 0
+			for result := range results {
+				if !result.Success {
+					t.Errorf("Unexpected failure: %s", result.Error)
+				}
+				if result.QueueTime > 0 {
+					queued++
+				}
+				if result.Duration >= 100*time.Millisecond {
+					t.Errorf("Expected the duration to leave out the queue time, got %v", result.Duration)
+				}
+			}
+			if tt.wantPeak < 4 && queued == 0 {
+				t.Error("Expected queued tasks to record their queue time")
+			} else if tt.wantPeak == 4 && queued != 0 {
+				t.Errorf("Expected no queue time without limits, got %d queued tasks", queued)
+			}
+			if stats := runner.QueueStats(); stats.Running != 0 || stats.Queued != 0 {
+				t.Errorf("Expected no tasks left, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestSlotLimits_Acquire(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetLimits(0, map[string]parser.Pool{"db": {Slots: 1}})
+	l := runner.slots
+	noWait := func(reason string) { t.Errorf("Unexpected wait: %s", reason) }
+
+	release, err := l.acquire(context.Background(), parser.Task{ID: "load", Pool: "db"}, noWait)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// A task of the full pool waits, and is given up when its run is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	reasons := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx, parser.Task{ID: "vacuum", Pool: "db"}, func(reason string) { reasons <- reason })
+		errs <- err
+	}()
+	if reason := <-reasons; !strings.Contains(reason, "pool 'db'") {
+		t.Errorf("Unexpected reason: %s", reason)
+	}
+	stats := runner.QueueStats()
+	if stats.Running != 1 || stats.Queued != 1 || stats.Pools["db"] != (PoolStats{Slots: 1, Used: 1, Queued: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Tasks of other pools do not wait behind it
+	other, err := l.acquire(context.Background(), parser.Task{ID: "report", Command: "true"}, noWait)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	other()
+
+	cancel()
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "cancelled while waiting for slots") {
+		t.Errorf("Expected the waiting task to be cancelled, got %v", err)
+	}
+	release()
+	if stats := runner.QueueStats(); stats.Running != 0 || stats.Queued != 0 || stats.Pools["db"].Used != 0 {
+		t.Errorf("Expected all slots free, got %+v", stats)
+	}
+
+	if _, err := l.acquire(context.Background(), parser.Task{ID: "load", Pool: "cache"}, noWait); err == nil {
+		t.Error("Expected an error for an undefined pool")
+	}
+}
+
+// historyFunc adapts a function to ExecutionHistory
+type historyFunc func(workflowName, status string) (parser.WorkflowExecution, bool)
+
+func (f historyFunc) LatestExecution(workflowName, status string) (parser.WorkflowExecution, bool) {
+	return f(workflowName, status)
+}
+
+func TestTaskRunner_SetLimits_SlotsPerAttempt(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetLimits(1, nil)
+
+	// The upstream workflow succeeds once released, and the sensor polls for it
+	upstreamDone := make(chan struct{})
+	polling := make(chan struct{}, 1)
+	runner.SetHistory(historyFunc(func(workflowName, status string) (parser.WorkflowExecution, bool) {
+		select {
+		case polling <- struct{}{}:
+		default:
+		}
+		select {
+		case <-upstreamDone:
+			return parser.WorkflowExecution{WorkflowID: workflowName, Status: status, EndTime: time.Now()}, true
+		default:
+			return parser.WorkflowExecution{}, false
+		}
+	}))
+	sensor := &parser.Workflow{Name: "sensor", Tasks: []parser.Task{
+		{ID: "sense", Command: "echo sensed", WaitFor: &parser.ExternalDependency{Workflow: "upstream", PollInterval: "10ms"}},
+	}}
+	sensed := make(chan parser.WorkflowExecution, 1)
+	go func() { sensed <- runner.ExecuteWorkflow(context.Background(), sensor) }()
+	<-polling
+
+	// A task of another workflow runs while the sensor waits
+	other := &parser.Workflow{Name: "other", Tasks: []parser.Task{{ID: "work", Command: "echo work"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if execution := runner.ExecuteWorkflow(ctx, other); execution.Status != "completed" || execution.TaskResults[0].QueueTime != 0 {
+		t.Fatalf("Expected the task to run while the sensor waits, got %+v", execution.TaskResults)
+	}
+
+	close(upstreamDone)
+	if execution := <-sensed; execution.Status != "completed" || execution.TaskResults[0].Stdout != "sensed\n" {
+		t.Fatalf("Expected the sensor to run its command, got %+v", execution.TaskResults)
+	}
+
+	// A task gives its slot back during the backoff before its retry
+	failed := make(chan struct{})
+	var attempts int
+	runner.RegisterFunc("flaky", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+		if attempts++; attempts == 1 {
+			close(failed)
+			return nil, fmt.Errorf("first attempt fails")
+		}
+		return nil, nil
+	})
+	flaky := &parser.Workflow{Name: "flaky", Tasks: []parser.Task{{ID: "flaky", Func: "flaky", Retry: 2}}}
+	retried := make(chan parser.WorkflowExecution, 1)
+	go func() { retried <- runner.ExecuteWorkflow(context.Background(), flaky) }()
+	<-failed
+
+	start := time.Now()
+	if execution := runner.ExecuteWorkflow(ctx, other); execution.Status != "completed" {
+		t.Fatalf("Expected the task to run during the backoff, got %+v", execution.TaskResults)
+	}
+	if waited := time.Since(start); waited >= 500*time.Millisecond {
+		t.Errorf("Expected the task not to wait for the retry, waited %v", waited)
+	}
+	if execution := <-retried; execution.Status != "completed" || execution.TaskResults[0].RetryCount != 1 {
+		t.Errorf("Expected the retry to succeed, got %+v", execution.TaskResults)
+	}
+}
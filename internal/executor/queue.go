@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
+)
+
+// Work is one attempt of a task, with everything needed to run it away from
+// the scheduler
+type Work struct {
+	ID         string        `json:"id"`
+	RunID      string        `json:"run_id"`
+	WorkflowID string        `json:"workflow_id"`
+	Task       parser.Task   `json:"task"`
+	Command    string        `json:"command"` // the task's command with its placeholders expanded
+	Attempt    int           `json:"attempt"`
+	Timeout    time.Duration `json:"timeout,omitempty"` // time left before the task times out, if known
+
+	Params  map[string]string            `json:"params,omitempty"`
+	Outputs map[string]map[string]string `json:"outputs,omitempty"` // outputs of the run's finished tasks
+}
+
+// WorkQueue runs the attempts of command, container and HTTP tasks on behalf
+// of a TaskRunner. Sub-workflow and func tasks always run in the runner's
+// process. Execute returns once the attempt finished or ctx is done.
+type WorkQueue interface {
+	Execute(ctx context.Context, work Work) CommandResult
+}
+
+// LocalQueue runs every attempt in the process of its runner. It is the
+// default queue of a TaskRunner.
+type LocalQueue struct {
+	runner *TaskRunner
+}
+
+// NewLocalQueue creates a queue running attempts with runner
+func NewLocalQueue(runner *TaskRunner) *LocalQueue {
+	return &LocalQueue{runner: runner}
+}
+
+// Execute runs the attempt in this process
+func (q *LocalQueue) Execute(ctx context.Context, work Work) CommandResult {
+	if runStateFrom(ctx).runID != work.RunID {
+		return q.runner.RunWork(ctx, work)
+	}
+	return q.runner.executeAttempt(ctx, work.Task, work.WorkflowID, work.Command)
+}
+
+// SetWorkQueue hands the attempts of command, container and HTTP tasks to
+// queue; nil restores the LocalQueue
+func (tr *TaskRunner) SetWorkQueue(queue WorkQueue) {
+	if queue == nil {
+		queue = NewLocalQueue(tr)
+	}
+	tr.queue = queue
+}
+
+// RunWork runs an attempt handed out by a WorkQueue, such as on a worker
+func (tr *TaskRunner) RunWork(ctx context.Context, work Work) CommandResult {
+	if work.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, work.Timeout)
+		defer cancel()
+	}
+	ctx = withRunState(ctx, runState{
+		workflow: work.WorkflowID,
+		params:   work.Params,
+		outputs:  work.Outputs,
+		runID:    work.RunID,
+	})
+	return tr.executeAttempt(ctx, work.Task, work.WorkflowID, work.Command)
+}
+
+// runAttempt runs an attempt of a task through the work queue, or in this
+// process for the task types that need it
+func (tr *TaskRunner) runAttempt(ctx context.Context, task parser.Task, workflowID, command string, attempt int) CommandResult {
+	if task.Workflow != "" || task.Func != "" || tr.queue == nil {
+		return tr.executeAttempt(ctx, task, workflowID, command)
+	}
+
+	state := runStateFrom(ctx)
+	work := Work{
+		ID:         runid.New(),
+		RunID:      state.runID,
+		WorkflowID: workflowID,
+		Task:       task,
+		Command:    command,
+		Attempt:    attempt,
+		Params:     state.params,
+		Outputs:    state.outputs,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		work.Timeout = time.Until(deadline)
+	}
+	return tr.queue.Execute(ctx, work)
+}
+
+// effectiveRunsOn returns the worker labels of a task, falling back to the
+// workflow default. Sub-workflow and func tasks have none.
+func effectiveRunsOn(task parser.Task, workflow parser.Workflow) []string {
+	if task.Workflow != "" || task.Func != "" {
+		return nil
+	}
+	if task.RunsOn != nil {
+		return task.RunsOn
+	}
+	return workflow.RunsOn
+}
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// recordingQueue runs attempts locally, recording them and their worker
+type recordingQueue struct {
+	local *LocalQueue
+	mu    sync.Mutex
+	work  []Work
+}
+
+func (q *recordingQueue) Execute(ctx context.Context, work Work) CommandResult {
+	q.mu.Lock()
+	q.work = append(q.work, work)
+	q.mu.Unlock()
+
+	result := q.local.Execute(ctx, work)
+	if len(work.Task.RunsOn) > 0 {
+		result.Worker = "worker-1"
+	}
+	return result
+}
+
+func TestTaskRunner_SetWorkQueue(t *testing.T) {
+	runner := NewTaskRunner()
+	queue := &recordingQueue{local: NewLocalQueue(runner)}
+	runner.SetWorkQueue(queue)
+	runner.RegisterFunc("noop", func(ctx context.Context, tc TaskContext) (map[string]string, error) {
+		return nil, nil
+	})
+
+	workflow := &parser.Workflow{
+		Name:   "etl",
+		Params: map[string]string{"env": "prod"},
+		RunsOn: []string{"etl"},
+		Tasks: []parser.Task{
+			{ID: "extract", Command: "echo {{ .Params.env }}"},
+			{ID: "train", Command: "echo train", RunsOn: []string{"gpu-free"}, DependsOn: []string{"extract"}},
+			{ID: "cleanup", Func: "noop", DependsOn: []string{"train"}},
+		},
+	}
+
+	execution := runner.ExecuteWorkflow(context.Background(), workflow)
+	if execution.Status != "completed" {
+		t.Fatalf("Expected completed run, got %s: %s", execution.Status, execution.ErrorMessage)
+	}
+
+	// The func task runs in-process, bypassing the queue
+	if len(queue.work) != 2 {
+		t.Fatalf("Expected 2 attempts in the queue, got %d", len(queue.work))
+	}
+	extract, train := queue.work[0], queue.work[1]
+	if extract.Command != "echo prod" || extract.RunID != execution.ID || extract.Attempt != 1 {
+		t.Errorf("Unexpected work: %+v", extract)
+	}
+	if len(extract.Task.RunsOn) != 1 || extract.Task.RunsOn[0] != "etl" {
+		t.Errorf("Expected the workflow's labels, got %v", extract.Task.RunsOn)
+	}
+	if len(train.Task.RunsOn) != 1 || train.Task.RunsOn[0] != "gpu-free" {
+		t.Errorf("Expected the task's labels, got %v", train.Task.RunsOn)
+	}
+	if extract.Timeout <= 0 {
+		t.Errorf("Expected the task's time left, got %v", extract.Timeout)
+	}
+
+	if result := execution.TaskResults[0]; result.Worker != "worker-1" || result.Stdout != "prod\n" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result := execution.TaskResults[2]; result.Worker != "" {
+		t.Errorf("Expected the func task to run on the scheduler, got worker %s", result.Worker)
+	}
+}
+
+func TestTaskRunner_RunWork(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+	defer server.Close()
+
+	// Away from the scheduler, the task still sees the params and outputs of its run
+	work := Work{
+		ID:         "w1",
+		RunID:      "run-1",
+		WorkflowID: "etl",
+		Task:       parser.Task{ID: "notify", HTTP: &parser.HTTPRequest{URL: server.URL + "/{{ .Params.env }}/{{ .Outputs.extract.rows }}"}},
+		Params:     map[string]string{"env": "prod"},
+		Outputs:    map[string]map[string]string{"extract": {"rows": "42"}},
+		Timeout:    time.Minute,
+	}
+	result := NewTaskRunner().RunWork(context.Background(), work)
+	if result.ExitCode != 0 {
+		t.Fatalf("RunWork() failed: %+v", result)
+	}
+	if path != "/prod/42" {
+		t.Errorf("Expected request to /prod/42, got %s", path)
+	}
+}
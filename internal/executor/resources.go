@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// processLimits are the resolved resources of a task; zero means unlimited
+type processLimits struct {
+	memory       int64  // bytes
+	cpuSeconds   uint64 // rounded up
+	maxProcesses uint64
+	nofile       uint64
+}
+
+// limitsFrom resolves task resources; the parser has already validated them
+func limitsFrom(resources *parser.Resources) (processLimits, error) {
+	var limits processLimits
+	if resources.IsZero() {
+		return limits, nil
+	}
+
+	if resources.MemoryLimit != "" {
+		memory, err := parser.ParseMemory(resources.MemoryLimit)
+		if err != nil {
+			return limits, fmt.Errorf("invalid memory limit '%s': %w", resources.MemoryLimit, err)
+		}
+		limits.memory = memory
+	}
+	if resources.CPUTime != "" {
+		cpu, err := time.ParseDuration(resources.CPUTime)
+		if err != nil {
+			return limits, fmt.Errorf("invalid cpu time '%s': %w", resources.CPUTime, err)
+		}
+		limits.cpuSeconds = uint64((cpu + time.Second - 1) / time.Second)
+	}
+	if resources.MaxProcesses > 0 {
+		limits.maxProcesses = uint64(resources.MaxProcesses)
+	}
+	if resources.Nofile > 0 {
+		limits.nofile = uint64(resources.Nofile)
+	}
+	return limits, nil
+}
+
+// isZero reports whether no limit is set
+func (l processLimits) isZero() bool {
+	return l == processLimits{}
+}
+
+// processUsage returns the peak resident set size in bytes and the CPU time
+// of a finished process
+func processUsage(state *os.ProcessState) (int64, time.Duration) {
+	if state == nil {
+		return 0, 0
+	}
+	return peakRSS(state), state.UserTime() + state.SystemTime()
+}
@@ -0,0 +1,206 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupMount is where the cgroup v2 hierarchy is mounted, and
+// procSelfCgroup where this process's cgroup is listed; replaced in tests
+var (
+	cgroupMount    = "/sys/fs/cgroup"
+	procSelfCgroup = "/proc/self/cgroup"
+)
+
+// daemonCgroup is the leaf group the daemon's processes move into, so the
+// group it was started in can enable controllers for the task groups
+const daemonCgroup = "goliteflow-daemon"
+
+// cgroupSeq numbers the cgroups created by this process
+var cgroupSeq uint64
+
+// cgroupSetup is the group task groups are created in, prepared once
+type cgroupSetup struct {
+	once   sync.Once
+	parent string
+	ok     bool
+}
+
+var taskCgroups cgroupSetup
+
+// limiter holds the processLimits of a task. Memory and process limits go to
+// a cgroup v2 sub-group when one can be created, and fall back to RLIMIT_AS
+// and RLIMIT_NPROC otherwise. The sandbox helper joins the cgroup and sets
+// the rlimits before executing the command.
+type limiter struct {
+	limits processLimits
+	cgroup string // empty when limits are applied with rlimits only
+}
+
+// newLimiter prepares the cgroup for a task, if the limits need one and
+// cgroup v2 is available with the memory and pids controllers delegated
+func newLimiter(limits processLimits) (*limiter, error) {
+	l := &limiter{limits: limits}
+	if limits.memory == 0 && limits.maxProcesses == 0 {
+		return l, nil
+	}
+
+	// Any failure below leaves the limits to rlimits
+	taskCgroups.once.Do(func() {
+		taskCgroups.parent, taskCgroups.ok = prepareTaskCgroups()
+	})
+	parent, ok := taskCgroups.parent, taskCgroups.ok
+	if !ok {
+		return l, nil
+	}
+	dir := filepath.Join(parent, fmt.Sprintf("goliteflow-%d-%d", os.Getpid(), atomic.AddUint64(&cgroupSeq, 1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return l, nil
+	}
+
+	files := map[string]uint64{}
+	if limits.memory > 0 {
+		files["memory.max"] = uint64(limits.memory)
+		files["memory.swap.max"] = 0
+	}
+	if limits.maxProcesses > 0 {
+		files["pids.max"] = limits.maxProcesses
+	}
+	for name, value := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(strconv.FormatUint(value, 10)), 0644)
+		if err != nil && !(name == "memory.swap.max" && os.IsNotExist(err)) {
+			os.Remove(dir)
+			return l, nil
+		}
+	}
+
+	l.cgroup = dir
+	return l, nil
+}
+
+// prepareTaskCgroups returns the group to create task groups in, with the
+// memory and pids controllers enabled for them. A group holding processes
+// cannot enable controllers for its children, so the processes of the
+// daemon's own group move into a leaf group next to the task groups first.
+func prepareTaskCgroups() (string, bool) {
+	own, ok := ownCgroup()
+	if !ok {
+		return "", false
+	}
+	if filepath.Base(own) == daemonCgroup {
+		return filepath.Dir(own), true // moved by an earlier process
+	}
+
+	available, err := os.ReadFile(filepath.Join(own, "cgroup.controllers"))
+	if err != nil {
+		return "", false
+	}
+	var enable []string
+	for _, controller := range strings.Fields(string(available)) {
+		if controller == "memory" || controller == "pids" {
+			enable = append(enable, "+"+controller)
+		}
+	}
+	if len(enable) == 0 {
+		return "", false
+	}
+
+	leaf := filepath.Join(own, daemonCgroup)
+	if err := os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+		return "", false
+	}
+	if err := moveProcesses(own, leaf); err != nil {
+		return "", false
+	}
+	if err := os.WriteFile(filepath.Join(own, "cgroup.subtree_control"), []byte(strings.Join(enable, " ")), 0644); err != nil {
+		return "", false
+	}
+	return own, true
+}
+
+// moveProcesses moves the processes of cgroup from to cgroup to. Processes
+// other than this one may exit while they are moved.
+func moveProcesses(from, to string) error {
+	data, err := os.ReadFile(filepath.Join(from, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	procs, err := os.OpenFile(filepath.Join(to, "cgroup.procs"), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer procs.Close()
+
+	self := strconv.Itoa(os.Getpid())
+	if _, err := procs.Write([]byte(self + "\n")); err != nil {
+		return fmt.Errorf("failed to move process %s: %w", self, err)
+	}
+	for _, pid := range strings.Fields(string(data)) {
+		if pid != self {
+			procs.Write([]byte(pid + "\n"))
+		}
+	}
+	return nil
+}
+
+// ownCgroup returns the cgroup v2 directory of this process
+func ownCgroup() (string, bool) {
+	data, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", false
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		if path := strings.TrimPrefix(scanner.Text(), "0::"); path != scanner.Text() {
+			dir := filepath.Join(cgroupMount, path)
+			if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+				return "", false
+			}
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// rlimits returns the resource limits to set on the process. Memory and
+// process counts only use rlimits when no cgroup enforces them.
+func (l *limiter) rlimits() map[int]uint64 {
+	rlimits := make(map[int]uint64)
+	if l.limits.cpuSeconds > 0 {
+		rlimits[unix.RLIMIT_CPU] = l.limits.cpuSeconds
+	}
+	if l.limits.nofile > 0 {
+		rlimits[unix.RLIMIT_NOFILE] = l.limits.nofile
+	}
+	if l.cgroup == "" && l.limits.memory > 0 {
+		rlimits[unix.RLIMIT_AS] = uint64(l.limits.memory)
+	}
+	if l.cgroup == "" && l.limits.maxProcesses > 0 {
+		rlimits[unix.RLIMIT_NPROC] = l.limits.maxProcesses
+	}
+	return rlimits
+}
+
+// release removes the cgroup once the process has exited
+func (l *limiter) release() {
+	if l.cgroup != "" {
+		os.Remove(l.cgroup)
+	}
+}
+
+// peakRSS reads the maximum resident set size, reported by Linux in kilobytes
+func peakRSS(state *os.ProcessState) int64 {
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return usage.Maxrss * 1024
+	}
+	return 0
+}
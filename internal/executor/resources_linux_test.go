@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"golang.org/x/sys/unix"
+)
+
+// writeScript creates an executable shell script, since commands are not run through a shell
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestTaskRunner_ExecuteWorkflow_ResourceLimits(t *testing.T) {
+	runner := NewTaskRunner()
+	script := writeScript(t, "ulimit -n; ulimit -t")
+
+	workflow := &parser.Workflow{
+		Name:      "limited",
+		Resources: &parser.Resources{Nofile: 64, CPUTime: "1m"},
+		Tasks: []parser.Task{
+			{ID: "defaults", Command: script},
+			{ID: "override", Command: script, Resources: &parser.Resources{Nofile: 32}},
+		},
+	}
+
+	execution := runner.ExecuteWorkflow(context.Background(), workflow)
+	if execution.Status != "completed" {
+		t.Fatalf("Expected status completed, got %s: %s", execution.Status, execution.ErrorMessage)
+	}
+
+	expected := map[string]string{
+		"defaults": "64\n60",
+		"override": "32\n60",
+	}
+	for _, result := range execution.TaskResults {
+		if got := strings.TrimSpace(result.Stdout); got != expected[result.TaskID] {
+			t.Errorf("Task %s: expected limits %q, got %q", result.TaskID, expected[result.TaskID], got)
+		}
+		if result.PeakRSS <= 0 {
+			t.Errorf("Task %s: expected peak RSS to be recorded, got %d", result.TaskID, result.PeakRSS)
+		}
+	}
+}
+
+func TestTaskRunner_ExecuteTask_ResourceUsageWithoutLimits(t *testing.T) {
+	runner := NewTaskRunner()
+
+	result := runner.ExecuteTask(context.Background(), parser.Task{ID: "plain", Command: "echo hello"}, "test-workflow")
+	if !result.Success {
+		t.Fatalf("Expected success, got %s", result.Error)
+	}
+	if result.PeakRSS <= 0 {
+		t.Errorf("Expected peak RSS to be recorded, got %d", result.PeakRSS)
+	}
+	if result.CPUTime < 0 {
+		t.Errorf("Expected non-negative CPU time, got %s", result.CPUTime)
+	}
+}
+
+func TestLimitsFrom(t *testing.T) {
+	limits, err := limitsFrom(&parser.Resources{MemoryLimit: "512Mi", CPUTime: "1500ms", MaxProcesses: 10, Nofile: 128})
+	if err != nil {
+		t.Fatalf("limitsFrom() error = %v", err)
+	}
+	expected := processLimits{memory: 512 << 20, cpuSeconds: 2, maxProcesses: 10, nofile: 128}
+	if limits != expected {
+		t.Errorf("Expected %+v, got %+v", expected, limits)
+	}
+
+	if limits, err := limitsFrom(nil); err != nil || !limits.isZero() {
+		t.Errorf("Expected no limits for nil resources, got %+v, %v", limits, err)
+	}
+}
+
+// fakeCgroup creates a cgroup v2 hierarchy in a temporary directory with this
+// process in a service group offering controllers, as systemd delegates it
+func fakeCgroup(t *testing.T, controllers string) string {
+	t.Helper()
+	root := t.TempDir()
+	own := filepath.Join(root, "system.slice", "app.service")
+	files := map[string]string{
+		"cgroup.controllers":     controllers,
+		"cgroup.procs":           fmt.Sprintf("%d\n4242\n", os.Getpid()),
+		"cgroup.subtree_control": "",
+	}
+	if err := os.MkdirAll(own, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(own, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	procFile := filepath.Join(root, "proc-self-cgroup")
+	if err := os.WriteFile(procFile, []byte("0::/system.slice/app.service\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	originalMount, originalProc := cgroupMount, procSelfCgroup
+	cgroupMount, procSelfCgroup = root, procFile
+	taskCgroups = cgroupSetup{}
+	t.Cleanup(func() {
+		cgroupMount, procSelfCgroup = originalMount, originalProc
+		taskCgroups = cgroupSetup{}
+	})
+	return own
+}
+
+func TestNewLimiter_DelegatedCgroup(t *testing.T) {
+	own := fakeCgroup(t, "cpu memory pids")
+
+	l, err := newLimiter(processLimits{memory: 64 << 20, maxProcesses: 10})
+	if err != nil {
+		t.Fatalf("newLimiter() error = %v", err)
+	}
+	if filepath.Dir(l.cgroup) != own {
+		t.Fatalf("Expected a task group next to the daemon's leaf group, got %q", l.cgroup)
+	}
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+	if got := read(filepath.Join(own, "cgroup.subtree_control")); got != "+memory +pids" {
+		t.Errorf("subtree_control = %q, want +memory +pids", got)
+	}
+	if got := strings.Fields(read(filepath.Join(own, daemonCgroup, "cgroup.procs"))); len(got) != 2 || got[0] != strconv.Itoa(os.Getpid()) || got[1] != "4242" {
+		t.Errorf("Expected the group's processes in the leaf group, got %v", got)
+	}
+	if got := read(filepath.Join(l.cgroup, "memory.max")); got != strconv.Itoa(64<<20) {
+		t.Errorf("memory.max = %s", got)
+	}
+	if got := read(filepath.Join(l.cgroup, "pids.max")); got != "10" {
+		t.Errorf("pids.max = %s", got)
+	}
+	if rlimits := l.rlimits(); len(rlimits) != 0 {
+		t.Errorf("Expected no rlimits with a cgroup, got %v", rlimits)
+	}
+}
+
+func TestNewLimiter_ControllersNotDelegated(t *testing.T) {
+	fakeCgroup(t, "cpu")
+
+	l, err := newLimiter(processLimits{memory: 64 << 20})
+	if err != nil {
+		t.Fatalf("newLimiter() error = %v", err)
+	}
+	if l.cgroup != "" {
+		t.Errorf("Expected no cgroup without the memory controller, got %q", l.cgroup)
+	}
+	if _, ok := l.rlimits()[unix.RLIMIT_AS]; !ok {
+		t.Error("Expected memory to fall back to RLIMIT_AS")
+	}
+}
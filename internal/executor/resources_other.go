@@ -0,0 +1,23 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// limiter reports that resource limits are unsupported outside Linux
+type limiter struct{}
+
+func newLimiter(limits processLimits) (*limiter, error) {
+	return nil, fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
+
+func (l *limiter) release() {}
+
+// peakRSS is not recorded outside Linux
+func peakRSS(state *os.ProcessState) int64 {
+	return 0
+}
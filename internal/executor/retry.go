@@ -0,0 +1,43 @@
+package executor
+
+import "github.com/sintakaridina/goliteflow/internal/parser"
+
+// reusableResults returns, by task ID, the results of the run being retried
+// that the retry keeps: those of the tasks that succeeded and are neither
+// opts.From nor downstream of it. It returns nil when the run is no retry.
+func reusableResults(workflow *parser.Workflow, opts RunOptions) map[string]parser.ExecutionResult {
+	if opts.Retry == nil {
+		return nil
+	}
+
+	rerun := downstreamTasks(workflow, opts.From)
+	reused := make(map[string]parser.ExecutionResult)
+	for _, result := range opts.Retry.TaskResults {
+		if result.Success && !rerun[result.TaskID] {
+			reused[result.TaskID] = result
+		}
+	}
+	return reused
+}
+
+// downstreamTasks returns task and every task that depends on it, directly
+// or through other tasks
+func downstreamTasks(workflow *parser.Workflow, task string) map[string]bool {
+	downstream := map[string]bool{task: true}
+	for changed := true; changed; {
+		changed = false
+		for _, t := range workflow.Tasks {
+			if downstream[t.ID] {
+				continue
+			}
+			for _, dep := range t.DependsOn {
+				if downstream[dep] {
+					downstream[t.ID] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return downstream
+}
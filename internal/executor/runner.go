@@ -1,25 +1,65 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/logger"
 	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
 )
 
+// defaultPollInterval is how often wait_for checks the history when no poll_interval is set
+const defaultPollInterval = 30 * time.Second
+
+// hostname is recorded on every run
+var hostname, _ = os.Hostname()
+
 // TaskRunner handles execution of individual tasks
 type TaskRunner struct {
-	timeout time.Duration
+	timeout          time.Duration
+	history          ExecutionHistory
+	workflows        WorkflowLookup
+	containerRuntime string // default runtime of tasks with an image
+	funcs            map[string]TaskFunc
+	funcsMu          sync.RWMutex
+	events           *events.Bus                   // optional, receives run and task events
+	tracker          RunTracker                    // optional, follows runs as they happen
+	cancels          map[string]context.CancelFunc // runs in progress, by run ID
+	cancelsMu        sync.Mutex
+	queue            WorkQueue // runs the attempts of command, container and HTTP tasks
+	slots            *slotLimits
+	log              *logger.Logger
+}
+
+// ExecutionHistory gives tasks access to finished runs of other workflows
+type ExecutionHistory interface {
+	// LatestExecution returns the most recent finished run of a workflow with the given status
+	LatestExecution(workflowName, status string) (parser.WorkflowExecution, bool)
+}
+
+// RunTracker is told about each run and task event in the goroutine running
+// the workflow, before the event is published
+type RunTracker interface {
+	Track(event events.Event)
 }
 
 // NewTaskRunner creates a new task runner
 func NewTaskRunner() *TaskRunner {
-	return &TaskRunner{
+	tr := &TaskRunner{
 		timeout: 30 * time.Minute, // default timeout
+		slots:   newSlotLimits(),
+		log:     logger.GetGlobalLogger(),
 	}
+	tr.queue = NewLocalQueue(tr)
+	return tr
 }
 
 // SetDefaultTimeout sets the default timeout for tasks
@@ -27,8 +67,43 @@ func (tr *TaskRunner) SetDefaultTimeout(timeout time.Duration) {
 	tr.timeout = timeout
 }
 
-// ExecuteTask executes a single task with retry logic
+// SetHistory sets the execution history used by wait_for tasks
+func (tr *TaskRunner) SetHistory(history ExecutionHistory) {
+	tr.history = history
+}
+
+// SetEventBus publishes the events of runs and tasks to bus
+func (tr *TaskRunner) SetEventBus(bus *events.Bus) {
+	tr.events = bus
+}
+
+// SetTracker reports the events of runs and tasks to tracker as they happen
+func (tr *TaskRunner) SetTracker(tracker RunTracker) {
+	tr.tracker = tracker
+}
+
+// publish sends an event about the current run
+func (tr *TaskRunner) publish(ctx context.Context, event events.Event) {
+	state := runStateFrom(ctx)
+	event.RunID, event.ParentRunID = state.runID, state.parentRunID
+	event.Time = time.Now()
+	if tr.tracker != nil {
+		tr.tracker.Track(event)
+	}
+	tr.events.Publish(event)
+}
+
+// ExecuteTask executes a single task with retry logic. Each attempt takes
+// the slots of SetLimits once it can start and gives them back before the
+// backoff, so a task waiting for a workflow or for its retry holds none.
 func (tr *TaskRunner) ExecuteTask(ctx context.Context, task parser.Task, workflowID string) parser.ExecutionResult {
+	result := tr.executeTask(ctx, task, workflowID)
+	tr.publish(ctx, events.Event{Type: events.TaskFinished, Workflow: workflowID, Task: task.ID,
+		Attempt: result.RetryCount + 1, Error: result.Error, Result: &result})
+	return result
+}
+
+func (tr *TaskRunner) executeTask(ctx context.Context, task parser.Task, workflowID string) parser.ExecutionResult {
 	result := parser.ExecutionResult{
 		TaskID:     task.ID,
 		WorkflowID: workflowID,
@@ -43,9 +118,48 @@ func (tr *TaskRunner) ExecuteTask(ctx context.Context, task parser.Task, workflo
 		}
 	}
 
-	// Create context with timeout
-	taskCtx, cancel := context.WithTimeout(ctx, taskTimeout)
-	defer cancel()
+	// The timeout covers the task but not the time its attempts wait for
+	// slots, which moves the deadline back
+	deadline := result.StartTime.Add(taskTimeout)
+	started := false
+	start := func(attempt int) {
+		started = true
+		tr.publish(ctx, events.Event{Type: events.TaskStarted, Workflow: workflowID, Task: task.ID, Attempt: attempt})
+	}
+	finish := func() parser.ExecutionResult {
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime) - result.QueueTime
+		return result
+	}
+	if task.WaitFor != nil || !takesSlots(task) {
+		start(1)
+	}
+
+	// Wait for an external workflow before running the command
+	if task.WaitFor != nil {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		message, err := tr.waitForWorkflow(waitCtx, *task.WaitFor)
+		cancel()
+		if err != nil {
+			result.ExitCode = 1
+			result.Error = err.Error()
+		} else {
+			result.Stdout = message
+			result.Success = true
+		}
+		if err != nil || (task.Command == "" && task.Image == "" && task.HTTP == nil && task.Func == "") {
+			return finish()
+		}
+	}
+
+	// Expand {{ .Params.name }} and {{ .Outputs.task.name }} placeholders in the command
+	state := runStateFrom(ctx)
+	command, err := renderTemplate(task.Command, templateData{Workflow: workflowID, Params: state.params, Outputs: state.outputs})
+	if err != nil {
+		result.ExitCode = 1
+		result.Error = err.Error()
+		return finish()
+	}
 
 	// Execute with retries
 	maxRetries := task.Retry
@@ -57,18 +171,42 @@ func (tr *TaskRunner) ExecuteTask(ctx context.Context, task parser.Task, workflo
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		result.RetryCount = attempt
 
+		// Wait for the slots of this attempt
+		release, waited, err := tr.takeSlots(ctx, task, workflowID)
+		if err != nil {
+			result.ExitCode = 1
+			result.Success = false
+			lastErr = err
+			break
+		}
+		result.QueueTime += waited
+		deadline = deadline.Add(waited)
+		if !started || waited > 0 {
+			start(attempt + 1)
+		}
+
 		// Execute the command
-		cmdResult := tr.executeCommand(taskCtx, task.Command)
+		attemptCtx, cancel := context.WithDeadline(ctx, deadline)
+		cmdResult := tr.runAttempt(attemptCtx, task, workflowID, command, attempt+1)
+		release()
 
 		// Merge results
+		result.SubWorkflow = cmdResult.SubWorkflow
 		result.ExitCode = cmdResult.ExitCode
 		result.Stdout = cmdResult.Stdout
 		result.Stderr = cmdResult.Stderr
 		result.Error = cmdResult.Error
+		result.Outputs = cmdResult.Outputs
+		result.Worker = cmdResult.Worker
 		result.Success = cmdResult.ExitCode == 0
+		result.CPUTime += cmdResult.CPUTime
+		if cmdResult.PeakRSS > result.PeakRSS {
+			result.PeakRSS = cmdResult.PeakRSS
+		}
 
 		// If successful, break out of retry loop
 		if result.Success {
+			cancel()
 			break
 		}
 
@@ -77,38 +215,121 @@ func (tr *TaskRunner) ExecuteTask(ctx context.Context, task parser.Task, workflo
 		// If this is not the last attempt, wait before retrying
 		if attempt < maxRetries-1 {
 			backoffDuration := tr.calculateBackoff(attempt)
+			tr.publish(ctx, events.Event{Type: events.TaskRetrying, Workflow: workflowID, Task: task.ID,
+				Attempt: attempt + 2, Delay: backoffDuration, Error: lastErr.Error()})
 			select {
-			case <-taskCtx.Done():
-				result.Error = "task cancelled or timed out"
-				result.Success = false
-				break
+			case <-attemptCtx.Done():
 			case <-time.After(backoffDuration):
 				// Continue to next attempt
 			}
+			if attemptCtx.Err() != nil {
+				// No attempt can succeed once the task is cancelled or timed out
+				cancel()
+				break
+			}
 		}
+		cancel()
 	}
 
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
-
 	// If all retries failed, set the final error
 	if !result.Success && lastErr != nil {
 		result.Error = lastErr.Error()
 	}
 
-	return result
+	return finish()
+}
+
+// takeSlots waits for the slots of an attempt, if the task takes any, and
+// returns the function giving them back and how long it was queued
+func (tr *TaskRunner) takeSlots(ctx context.Context, task parser.Task, workflowID string) (func(), time.Duration, error) {
+	if !takesSlots(task) {
+		return func() {}, 0, nil
+	}
+	var queued time.Time
+	release, err := tr.slots.acquire(ctx, task, func(reason string) {
+		queued = time.Now()
+		tr.publish(ctx, events.Event{Type: events.TaskQueued, Workflow: workflowID, Task: task.ID, Reason: reason})
+	})
+	var waited time.Duration
+	if !queued.IsZero() {
+		waited = time.Since(queued)
+	}
+	return release, waited, err
+}
+
+// waitForWorkflow polls the execution history until the external workflow has a
+// successful run inside the requested window, or the context expires
+func (tr *TaskRunner) waitForWorkflow(ctx context.Context, dep parser.ExternalDependency) (string, error) {
+	if tr.history == nil {
+		return "", fmt.Errorf("cannot wait for workflow '%s': no execution history available", dep.Workflow)
+	}
+
+	var within time.Duration
+	if dep.Within != "" {
+		if parsed, err := time.ParseDuration(dep.Within); err == nil {
+			within = parsed
+		}
+	}
+
+	pollInterval := defaultPollInterval
+	if dep.PollInterval != "" {
+		if parsed, err := time.ParseDuration(dep.PollInterval); err == nil && parsed > 0 {
+			pollInterval = parsed
+		}
+	}
+
+	for {
+		if execution, ok := tr.history.LatestExecution(dep.Workflow, "completed"); ok {
+			if within == 0 || time.Since(execution.EndTime) <= within {
+				return fmt.Sprintf("workflow '%s' completed at %s", dep.Workflow, execution.EndTime.Format(time.RFC3339)), nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if within > 0 {
+				return "", fmt.Errorf("timed out waiting for a successful run of workflow '%s' within %s", dep.Workflow, dep.Within)
+			}
+			return "", fmt.Errorf("timed out waiting for a successful run of workflow '%s'", dep.Workflow)
+		case <-time.After(pollInterval):
+		}
+	}
 }
 
 // CommandResult represents the result of a single command execution
 type CommandResult struct {
-	ExitCode int
-	Stdout   string
-	Stderr   string
-	Error    string
+	ExitCode int               `json:"exit_code"`
+	Stdout   string            `json:"stdout,omitempty"`
+	Stderr   string            `json:"stderr,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	PeakRSS  int64             `json:"peak_rss_bytes,omitempty"` // bytes
+	CPUTime  time.Duration     `json:"cpu_time,omitempty"`       // user and system time
+	Outputs  map[string]string `json:"outputs,omitempty"`
+	Worker   string            `json:"worker,omitempty"` // worker that ran the attempt, if not the scheduler
+
+	SubWorkflow *parser.WorkflowExecution `json:"sub_workflow,omitempty"`
+}
+
+// executeAttempt runs a single attempt of a task according to its type
+func (tr *TaskRunner) executeAttempt(ctx context.Context, task parser.Task, workflowID, command string) CommandResult {
+	if task.Workflow != "" {
+		return tr.executeSubWorkflow(ctx, task)
+	}
+	if task.Image != "" {
+		return tr.executeContainer(ctx, task, workflowID, command)
+	}
+	if task.HTTP != nil {
+		return tr.executeHTTP(ctx, task, workflowID)
+	}
+	if task.Func != "" {
+		return tr.executeFunc(ctx, task, workflowID)
+	}
+	return tr.executeCommand(ctx, command, task)
 }
 
-// executeCommand executes a single command
-func (tr *TaskRunner) executeCommand(ctx context.Context, command string) CommandResult {
+// executeCommand executes a single command with the task's resource limits,
+// user and sandbox
+func (tr *TaskRunner) executeCommand(ctx context.Context, command string, task parser.Task) CommandResult {
 	result := CommandResult{}
 
 	// Parse command and arguments
@@ -119,21 +340,52 @@ func (tr *TaskRunner) executeCommand(ctx context.Context, command string) Comman
 		return result
 	}
 
-	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	limits, err := limitsFrom(task.Resources)
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = 1
+		return result
+	}
+	var lim *limiter
+	if !limits.isZero() {
+		if lim, err = newLimiter(limits); err != nil {
+			result.Error = err.Error()
+			result.ExitCode = 1
+			return result
+		}
+		defer lim.release()
+	}
+
+	cmd, err := isolatedCommand(ctx, parts, task.RunAs, task.Sandbox, lim)
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = 1
+		return result
+	}
 
 	// Capture stdout and stderr
-	stdout, err := cmd.Output()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		result.ExitCode = 1
+		result.Error = err.Error()
+		return result
+	}
+	err = cmd.Wait()
+	result.PeakRSS, result.CPUTime = processUsage(cmd.ProcessState)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			result.ExitCode = exitError.ExitCode()
-			result.Stderr = string(exitError.Stderr)
+			result.Stderr = stderr.String()
 		} else {
 			result.ExitCode = 1
 			result.Error = err.Error()
 		}
 	} else {
 		result.ExitCode = 0
-		result.Stdout = string(stdout)
+		result.Stdout = stdout.String()
 	}
 
 	return result
@@ -156,12 +408,74 @@ func (tr *TaskRunner) calculateBackoff(attempt int) time.Duration {
 
 // ExecuteWorkflow executes all tasks in a workflow in dependency order
 func (tr *TaskRunner) ExecuteWorkflow(ctx context.Context, workflow *parser.Workflow) parser.WorkflowExecution {
+	return tr.ExecuteWorkflowWithOptions(ctx, workflow, RunOptions{})
+}
+
+// ExecuteWorkflowWithOptions executes a workflow with per-run settings such as params
+func (tr *TaskRunner) ExecuteWorkflowWithOptions(ctx context.Context, workflow *parser.Workflow, opts RunOptions) parser.WorkflowExecution {
+	// Carry params, outputs and the sub-workflow call stack to the tasks
+	parent := runStateFrom(ctx)
+	params := mergeParams(workflow.Params, opts.Params)
+	state := runState{
+		workflow: workflow.Name,
+		params:   params,
+		outputs:  make(map[string]map[string]string),
+		runID:    runid.New(),
+		trigger:  opts.Trigger,
+	}
+	if parent.workflow != "" {
+		state.parents = append(append([]string{}, parent.parents...), parent.workflow)
+		state.parentRunID = parent.runID
+		if state.trigger == "" {
+			state.trigger = parent.trigger
+		}
+	}
+	if state.trigger == "" {
+		state.trigger = parser.RunTriggerManual
+	}
+	ctx, done := tr.withCancel(ctx, state.runID)
+	defer done()
+	ctx = withRunState(ctx, state)
+
+	log := tr.log.WithWorkflow(workflow.Name).WithExecution(state.runID)
+	log.Debugf("Run started (trigger %s)", state.trigger)
+	tr.publish(ctx, events.Event{Type: events.WorkflowStarted, Workflow: workflow.Name, Trigger: state.trigger})
+	watch := tr.watchSLA(ctx, workflow.SLA, opts.ScheduledTime, workflow.Name, "")
+	execution := tr.executeWorkflow(ctx, workflow, opts, state)
+	execution.SLAViolations = append(execution.SLAViolations, watch.stop(execution.EndTime)...)
+	log.Debugf("Run %s in %v", execution.Status, execution.Duration)
+	tr.publish(ctx, events.Event{Type: events.WorkflowFinished, Workflow: workflow.Name,
+		Error: execution.ErrorMessage, Execution: &execution})
+	return execution
+}
+
+func (tr *TaskRunner) executeWorkflow(ctx context.Context, workflow *parser.Workflow, opts RunOptions, state runState) parser.WorkflowExecution {
 	execution := parser.WorkflowExecution{
-		WorkflowID:  workflow.Name,
-		StartTime:   time.Now(),
-		Status:      "running",
-		TaskResults: []parser.ExecutionResult{},
+		ID:            state.runID,
+		ParentID:      state.parentRunID,
+		Trigger:       state.trigger,
+		ScheduledTime: opts.ScheduledTime,
+		Attempt:       opts.Attempt,
+		Host:          hostname,
+		PID:           os.Getpid(),
+		WorkflowID:    workflow.Name,
+		StartTime:     time.Now(),
+		Status:        "running",
+		TaskResults:   []parser.ExecutionResult{},
+		Params:        state.params,
+	}
+	if execution.Attempt == 0 {
+		execution.Attempt = 1
+	}
+	if len(opts.Chain) > 0 {
+		execution.TriggeredBy = opts.Chain[len(opts.Chain)-1]
+		execution.Chain = opts.Chain
 	}
+	if opts.Retry != nil {
+		execution.RetryOf = opts.Retry.ID
+	}
+	outputs := state.outputs
+	reused := reusableResults(workflow, opts)
 
 	// Sort tasks by dependencies
 	sortedTasks, err := tr.sortTasksByDependencies(workflow)
@@ -178,6 +492,11 @@ func (tr *TaskRunner) ExecuteWorkflow(ctx context.Context, workflow *parser.Work
 
 	// Execute tasks in order
 	for _, task := range sortedTasks {
+		// A cancelled run starts no more tasks
+		if ctx.Err() != nil {
+			return cancelled(execution)
+		}
+
 		// Check if all dependencies are completed
 		if !tr.areDependenciesCompleted(task, completedTasks) {
 			execution.Status = "failed"
@@ -187,12 +506,34 @@ func (tr *TaskRunner) ExecuteWorkflow(ctx context.Context, workflow *parser.Work
 			return execution
 		}
 
-		// Execute the task
+		// A retry keeps the results and outputs of tasks it does not run again
+		if result, ok := reused[task.ID]; ok {
+			result.Reused = true
+			execution.TaskResults = append(execution.TaskResults, result)
+			completedTasks[task.ID] = true
+			if len(result.Outputs) > 0 {
+				outputs[task.ID] = result.Outputs
+			}
+			continue
+		}
+
+		// Execute the task, with the workflow's resources, user, sandbox and labels as defaults
+		task.Resources = task.Resources.WithDefaults(workflow.Resources)
+		task.RunAs, task.Sandbox = effectiveIsolation(task, *workflow)
+		task.RunsOn = effectiveRunsOn(task, *workflow)
+		watch := tr.watchSLA(ctx, task.SLA, opts.ScheduledTime, workflow.Name, task.ID)
 		result := tr.ExecuteTask(ctx, task, workflow.Name)
+		execution.SLAViolations = append(execution.SLAViolations, watch.stop(result.EndTime)...)
 		execution.TaskResults = append(execution.TaskResults, result)
 		completedTasks[task.ID] = true
+		if len(result.Outputs) > 0 {
+			outputs[task.ID] = result.Outputs
+		}
 
 		// If task failed and we should stop on failure, mark workflow as failed
+		if !result.Success && ctx.Err() != nil {
+			return cancelled(execution)
+		}
 		if !result.Success {
 			execution.Status = "failed"
 			execution.ErrorMessage = fmt.Sprintf("task '%s' failed: %s", task.ID, result.Error)
@@ -210,6 +551,15 @@ func (tr *TaskRunner) ExecuteWorkflow(ctx context.Context, workflow *parser.Work
 	return execution
 }
 
+// cancelled finishes an execution whose run was cancelled
+func cancelled(execution parser.WorkflowExecution) parser.WorkflowExecution {
+	execution.Status = "cancelled"
+	execution.ErrorMessage = "run cancelled"
+	execution.EndTime = time.Now()
+	execution.Duration = execution.EndTime.Sub(execution.StartTime)
+	return execution
+}
+
 // sortTasksByDependencies sorts tasks by their dependencies using topological sort
 func (tr *TaskRunner) sortTasksByDependencies(workflow *parser.Workflow) ([]parser.Task, error) {
 	// Create a map of task dependencies
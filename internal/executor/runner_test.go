@@ -2,10 +2,15 @@ package executor
 
 import (
 	"context"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sintakaridina/goliteflow/internal/events"
 	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
 )
 
 func TestTaskRunner_ExecuteTask(t *testing.T) {
@@ -158,3 +163,273 @@ func TestTaskRunner_CalculateBackoff(t *testing.T) {
 		})
 	}
 }
+
+// workflowMap is a WorkflowLookup backed by a map
+type workflowMap map[string]parser.Workflow
+
+func (m workflowMap) GetWorkflow(name string) (parser.Workflow, bool) {
+	workflow, ok := m[name]
+	return workflow, ok
+}
+
+func TestTaskRunner_ExecuteWorkflow_SubWorkflow(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetWorkflowLookup(workflowMap{
+		"notify": {
+			Name:   "notify",
+			Params: map[string]string{"channel": "ops", "message": "default"},
+			Tasks: []parser.Task{
+				{ID: "send", Command: "echo {{ .Params.channel }} {{ .Params.message }}"},
+			},
+		},
+	})
+
+	workflow := &parser.Workflow{
+		Name:     "etl",
+		Schedule: "0 0 * * *",
+		Params:   map[string]string{"env": "prod"},
+		Tasks: []parser.Task{
+			{ID: "load", Command: "echo load"},
+			{
+				ID:        "notify",
+				Workflow:  "notify",
+				Params:    map[string]string{"message": "etl-{{ .Params.env }}"},
+				DependsOn: []string{"load"},
+			},
+		},
+	}
+
+	execution := runner.ExecuteWorkflow(context.Background(), workflow)
+	if execution.Status != "completed" {
+		t.Fatalf("Expected status completed, got %s (%s)", execution.Status, execution.ErrorMessage)
+	}
+
+	child := execution.TaskResults[1].SubWorkflow
+	if child == nil {
+		t.Fatal("Expected sub-workflow execution to be nested in the task result")
+	}
+	if child.WorkflowID != "notify" {
+		t.Errorf("Expected child WorkflowID notify, got %s", child.WorkflowID)
+	}
+	if got := child.TaskResults[0].Stdout; got != "ops etl-prod\n" {
+		t.Errorf("Expected child output %q, got %q", "ops etl-prod\n", got)
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_Events(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetWorkflowLookup(workflowMap{
+		"child": {Name: "child", Tasks: []parser.Task{{ID: "step", Command: "echo child"}}},
+	})
+	bus := events.NewBus()
+	var got []events.Event
+	bus.Subscribe(func(e events.Event) { got = append(got, e) }, events.Sync())
+	runner.SetEventBus(bus)
+
+	workflow := &parser.Workflow{
+		Name: "parent",
+		Tasks: []parser.Task{
+			{ID: "flaky", Command: "false", Retry: 2},
+		},
+	}
+	runner.ExecuteWorkflow(context.Background(), workflow)
+	workflow.Tasks = []parser.Task{{ID: "call", Workflow: "child"}}
+	runner.ExecuteWorkflow(context.Background(), workflow)
+
+	expected := []struct {
+		typ      events.Type
+		workflow string
+		task     string
+		attempt  int
+	}{
+		{events.WorkflowStarted, "parent", "", 0},
+		{events.TaskStarted, "parent", "flaky", 1},
+		{events.TaskRetrying, "parent", "flaky", 2},
+		{events.TaskFinished, "parent", "flaky", 2},
+		{events.WorkflowFinished, "parent", "", 0},
+		{events.WorkflowStarted, "parent", "", 0},
+		{events.TaskStarted, "parent", "call", 1},
+		{events.WorkflowStarted, "child", "", 0},
+		{events.TaskStarted, "child", "step", 1},
+		{events.TaskFinished, "child", "step", 1},
+		{events.WorkflowFinished, "child", "", 0},
+		{events.TaskFinished, "parent", "call", 1},
+		{events.WorkflowFinished, "parent", "", 0},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(got), got)
+	}
+	for i, want := range expected {
+		e := got[i]
+		if e.Type != want.typ || e.Workflow != want.workflow || e.Task != want.task || e.Attempt != want.attempt {
+			t.Errorf("Event %d: expected %v, got %s %s %s %d", i, want, e.Type, e.Workflow, e.Task, e.Attempt)
+		}
+	}
+
+	if got[0].RunID == "" || got[0].RunID == got[5].RunID || got[4].RunID != got[0].RunID {
+		t.Errorf("Expected one run ID per run, got %s, %s and %s", got[0].RunID, got[4].RunID, got[5].RunID)
+	}
+	if got[7].ParentRunID != got[5].RunID {
+		t.Errorf("Expected the child run to reference its parent run %s, got %s", got[5].RunID, got[7].ParentRunID)
+	}
+	if got[3].Result == nil || got[3].Result.Success || got[4].Execution.Status != "failed" {
+		t.Errorf("Expected the results in the finished events, got %+v and %+v", got[3].Result, got[4].Execution)
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_SLA(t *testing.T) {
+	runner := NewTaskRunner()
+	bus := events.NewBus()
+	var mu sync.Mutex
+	var missed []events.Event
+	var finishedAfterMiss bool
+	bus.Subscribe(func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e.Type {
+		case events.SLAMissed:
+			missed = append(missed, e)
+		case events.TaskFinished:
+			finishedAfterMiss = len(missed) > 0
+		}
+	}, events.Sync())
+	runner.SetEventBus(bus)
+
+	workflow := &parser.Workflow{
+		Name: "slow",
+		SLA:  &parser.SLA{MaxDuration: "300ms"},
+		Tasks: []parser.Task{
+			{ID: "fast", Command: "true", SLA: &parser.SLA{MaxDuration: "10s"}},
+			{ID: "sleep", Command: "sleep 1", SLA: &parser.SLA{MaxDuration: "100ms"}},
+		},
+	}
+	execution := runner.ExecuteWorkflow(context.Background(), workflow)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !finishedAfterMiss {
+		t.Error("Expected the breach to be reported while the task was still running")
+	}
+	if len(missed) != 2 || missed[0].Task != "sleep" || missed[1].Task != "" || missed[0].RunID == "" {
+		t.Fatalf("Expected the task's and the workflow's SLA to be missed, got %+v", missed)
+	}
+	if missed[1].Violation.Type != parser.SLAMaxDuration || !strings.Contains(missed[1].Reason, "max_duration 300ms") {
+		t.Errorf("Unexpected violation: %+v", missed[1].Violation)
+	}
+	if execution.Status != "completed" || len(execution.SLAViolations) != 2 {
+		t.Errorf("Expected a completed run with 2 SLA violations, got %s with %+v", execution.Status, execution.SLAViolations)
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_RunMetadata(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetWorkflowLookup(workflowMap{
+		"child": {Name: "child", Tasks: []parser.Task{{ID: "step", Command: "echo child"}}},
+	})
+	bus := events.NewBus()
+	var started []events.Event
+	bus.Subscribe(func(e events.Event) {
+		if e.Type == events.WorkflowStarted {
+			started = append(started, e)
+		}
+	}, events.Sync())
+	runner.SetEventBus(bus)
+
+	workflow := &parser.Workflow{Name: "parent", Tasks: []parser.Task{{ID: "call", Workflow: "child"}}}
+	scheduled := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	execution := runner.ExecuteWorkflowWithOptions(context.Background(), workflow,
+		RunOptions{Trigger: parser.RunTriggerCron, ScheduledTime: scheduled})
+
+	if err := runid.Validate(execution.ID); err != nil {
+		t.Fatalf("Expected a valid run ID: %v", err)
+	}
+	if len(started) != 2 || started[0].RunID != execution.ID {
+		t.Fatalf("Expected the run ID %s in the events, got %+v", execution.ID, started)
+	}
+	if execution.Trigger != parser.RunTriggerCron || !execution.ScheduledTime.Equal(scheduled) || execution.Attempt != 1 {
+		t.Errorf("Unexpected run metadata: trigger %s, scheduled %v, attempt %d", execution.Trigger, execution.ScheduledTime, execution.Attempt)
+	}
+	if execution.Host == "" || execution.PID != os.Getpid() {
+		t.Errorf("Expected the host and PID of the run, got %s and %d", execution.Host, execution.PID)
+	}
+
+	child := execution.TaskResults[0].SubWorkflow
+	if child.ID != started[1].RunID || child.ParentID != execution.ID || child.Trigger != parser.RunTriggerCron {
+		t.Errorf("Expected the child run to inherit the trigger and reference its parent, got %+v", child)
+	}
+
+	execution = runner.ExecuteWorkflow(context.Background(), workflow)
+	if execution.Trigger != parser.RunTriggerManual || execution.ParentID != "" {
+		t.Errorf("Expected a manual top-level run, got trigger %s, parent %s", execution.Trigger, execution.ParentID)
+	}
+}
+
+func TestTaskRunner_ExecuteWorkflow_SubWorkflowRecursion(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetWorkflowLookup(workflowMap{
+		"a": {Name: "a", Tasks: []parser.Task{{ID: "call_b", Workflow: "b"}}},
+		"b": {Name: "b", Tasks: []parser.Task{{ID: "call_a", Workflow: "a"}}},
+	})
+
+	workflow := &parser.Workflow{Name: "a", Tasks: []parser.Task{{ID: "call_b", Workflow: "b"}}}
+	execution := runner.ExecuteWorkflow(context.Background(), workflow)
+
+	if execution.Status != "failed" {
+		t.Errorf("Expected recursive sub-workflow to fail, got %s", execution.Status)
+	}
+}
+
+func TestTaskRunner_PlanWorkflow(t *testing.T) {
+	runner := NewTaskRunner()
+	runner.SetWorkflowLookup(workflowMap{
+		"notify": {
+			Name:   "notify",
+			Params: map[string]string{"channel": "ops"},
+			Tasks:  []parser.Task{{ID: "send", Command: "echo {{ .Params.channel }} {{ .Params.message }}"}},
+		},
+	})
+
+	workflow := &parser.Workflow{
+		Name:   "etl",
+		Params: map[string]string{"env": "dev"},
+		Tasks: []parser.Task{
+			{ID: "extract", Command: "echo extract {{ .Params.env }}", Retry: 3, Timeout: "5m"},
+			{ID: "lookup", Command: "echo lookup"},
+			{ID: "transform", Command: "echo transform", DependsOn: []string{"extract", "lookup"}},
+			{ID: "notify", Workflow: "notify", Params: map[string]string{"message": "etl-{{ .Params.env }}"}, DependsOn: []string{"transform"}},
+		},
+	}
+
+	plan := runner.PlanWorkflow(workflow, RunOptions{Params: map[string]string{"env": "prod"}})
+	if plan.Error != "" {
+		t.Fatalf("Unexpected plan error: %s", plan.Error)
+	}
+
+	if len(plan.Stages) != 3 || len(plan.Stages[0]) != 2 || plan.Stages[2][0] != "notify" {
+		t.Errorf("Expected stages [[extract lookup] [transform] [notify]], got %v", plan.Stages)
+	}
+
+	tasks := make(map[string]TaskPlan)
+	for _, task := range plan.Tasks {
+		tasks[task.ID] = task
+	}
+
+	extract := tasks["extract"]
+	if extract.Command != "echo extract prod" {
+		t.Errorf("Expected templated command, got %q", extract.Command)
+	}
+	if extract.Timeout != "5m0s" || extract.Attempts != 3 || len(extract.Backoff) != 2 || extract.Backoff[1] != "2s" {
+		t.Errorf("Unexpected retry policy: %+v", extract)
+	}
+	if tasks["lookup"].Timeout != "30m0s" || tasks["lookup"].Attempts != 1 {
+		t.Errorf("Expected default timeout and a single attempt, got %+v", tasks["lookup"])
+	}
+
+	child := tasks["notify"].SubWorkflow
+	if child == nil {
+		t.Fatal("Expected the sub-workflow to be planned")
+	}
+	if got := child.Tasks[0].Command; got != "echo ops etl-prod" {
+		t.Errorf("Expected child command %q, got %q", "echo ops etl-prod", got)
+	}
+}
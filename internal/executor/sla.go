@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// slaWatch reports the SLA deadlines of a run or task that pass before it
+// finishes, publishing an SLAMissed event the moment each one passes
+type slaWatch struct {
+	tr       *TaskRunner
+	ctx      context.Context
+	workflow string
+	task     string
+	limits   *parser.SLA
+
+	mu         sync.Mutex
+	done       bool
+	deadlines  map[string]time.Time
+	timers     []*time.Timer
+	violations []parser.SLAViolation
+}
+
+// watchSLA starts watching the deadlines of sla for a run or task started
+// now, of a run for the schedule time scheduled, if any. It returns nil when
+// sla sets no deadline.
+func (tr *TaskRunner) watchSLA(ctx context.Context, sla *parser.SLA, scheduled time.Time, workflow, task string) *slaWatch {
+	deadlines := sla.Deadlines(time.Now(), scheduled)
+	if len(deadlines) == 0 {
+		return nil
+	}
+
+	w := &slaWatch{tr: tr, ctx: ctx, workflow: workflow, task: task, limits: sla, deadlines: deadlines}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, kind := range []string{parser.SLAMaxDuration, parser.SLAMustFinishBy} {
+		deadline, ok := deadlines[kind]
+		if !ok {
+			continue
+		}
+		kind := kind
+		w.timers = append(w.timers, time.AfterFunc(time.Until(deadline), func() {
+			w.mu.Lock()
+			violation, ok := w.record(kind)
+			w.mu.Unlock()
+			if ok {
+				w.publish(violation)
+			}
+		}))
+	}
+	return w
+}
+
+// record adds the violation of a passed deadline unless the watch stopped
+// or already recorded it; called with w.mu held
+func (w *slaWatch) record(kind string) (parser.SLAViolation, bool) {
+	if w.done || w.recorded(kind) {
+		return parser.SLAViolation{}, false
+	}
+	violation := parser.SLAViolation{Type: kind, Task: w.task, Time: time.Now(), Message: w.message(kind)}
+	w.violations = append(w.violations, violation)
+	return violation, true
+}
+
+func (w *slaWatch) publish(violation parser.SLAViolation) {
+	w.tr.publish(w.ctx, events.Event{Type: events.SLAMissed, Workflow: w.workflow, Task: w.task,
+		Reason: violation.Message, Violation: &violation})
+}
+
+func (w *slaWatch) recorded(kind string) bool {
+	for _, violation := range w.violations {
+		if violation.Type == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *slaWatch) message(kind string) string {
+	subject := fmt.Sprintf("workflow '%s'", w.workflow)
+	if w.task != "" {
+		subject = fmt.Sprintf("task '%s' of workflow '%s'", w.task, w.workflow)
+	}
+	switch kind {
+	case parser.SLAMaxDuration:
+		return fmt.Sprintf("%s is still running after max_duration %s", subject, w.limits.MaxDuration)
+	case parser.SLAMustFinishBy:
+		return fmt.Sprintf("%s did not finish by %s", subject, w.deadlines[kind].Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%s breached its %s SLA", subject, kind)
+}
+
+// stop ends the watch when the run or task finished at end, recording
+// deadlines that passed before a timer fired, and returns the violations
+func (w *slaWatch) stop(end time.Time) []parser.SLAViolation {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	kinds := make([]string, 0, len(w.deadlines))
+	for kind, deadline := range w.deadlines {
+		if end.After(deadline) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	var missed []parser.SLAViolation
+	for _, kind := range kinds {
+		if violation, ok := w.record(kind); ok {
+			missed = append(missed, violation)
+		}
+	}
+	w.done = true
+	violations := append([]parser.SLAViolation(nil), w.violations...)
+	w.mu.Unlock()
+
+	for _, violation := range missed {
+		w.publish(violation)
+	}
+	return violations
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// WorkflowLookup resolves workflows invoked by name from workflow tasks
+type WorkflowLookup interface {
+	GetWorkflow(name string) (parser.Workflow, bool)
+}
+
+// RunOptions holds per-run settings for ExecuteWorkflowWithOptions
+type RunOptions struct {
+	Params map[string]string // overrides for the workflow's default params
+	Chain  []string          // upstream workflows whose triggers started this run, oldest first
+
+	Trigger       string    // what started the run, default manual; sub-workflow runs inherit their parent's
+	ScheduledTime time.Time // schedule time the run is for, if any
+	Attempt       int       // default 1
+
+	Retry *parser.WorkflowExecution // finished run to resume, reusing the results of its succeeded tasks
+	From  string                    // task a retry runs again, with the tasks downstream of it
+}
+
+// SetWorkflowLookup sets the lookup used to resolve sub-workflow tasks
+func (tr *TaskRunner) SetWorkflowLookup(lookup WorkflowLookup) {
+	tr.workflows = lookup
+}
+
+// runState is the per-run information carried in the context of a workflow run
+type runState struct {
+	workflow string
+	params   map[string]string
+	parents  []string                     // workflows on the sub-workflow call stack, outermost first
+	outputs  map[string]map[string]string // outputs of the finished tasks, by task ID
+
+	runID       string // identifies the run in events
+	parentRunID string // run that invoked this one as a sub-workflow
+	trigger     string // what started the outermost run
+}
+
+type runStateKey struct{}
+
+func withRunState(ctx context.Context, state runState) context.Context {
+	return context.WithValue(ctx, runStateKey{}, state)
+}
+
+func runStateFrom(ctx context.Context) runState {
+	state, _ := ctx.Value(runStateKey{}).(runState)
+	return state
+}
+
+// templateData is the data available to templated commands and params
+type templateData struct {
+	Workflow string
+	Params   map[string]string
+	Outputs  map[string]map[string]string
+}
+
+// renderTemplate expands {{ ... }} placeholders in a command or parameter value
+func renderTemplate(text string, data templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// mergeParams returns the workflow defaults overlaid with the given overrides
+func mergeParams(defaults, overrides map[string]string) map[string]string {
+	if len(defaults) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		params[key] = value
+	}
+	for key, value := range overrides {
+		params[key] = value
+	}
+	return params
+}
+
+// executeSubWorkflow runs the workflow referenced by a task as a child run
+func (tr *TaskRunner) executeSubWorkflow(ctx context.Context, task parser.Task) CommandResult {
+	result := CommandResult{}
+	state := runStateFrom(ctx)
+
+	for _, parent := range append(state.parents, state.workflow) {
+		if parent == task.Workflow {
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("recursive sub-workflow detected: %s -> %s",
+				strings.Join(append(state.parents, state.workflow), " -> "), task.Workflow)
+			return result
+		}
+	}
+
+	if tr.workflows == nil {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("cannot run sub-workflow '%s': no workflows available", task.Workflow)
+		return result
+	}
+
+	child, ok := tr.workflows.GetWorkflow(task.Workflow)
+	if !ok {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("sub-workflow '%s' not found", task.Workflow)
+		return result
+	}
+
+	// Parameter values may reference the parent's own params
+	data := templateData{Workflow: state.workflow, Params: state.params, Outputs: state.outputs}
+	params := make(map[string]string, len(task.Params))
+	for key, value := range task.Params {
+		rendered, err := renderTemplate(value, data)
+		if err != nil {
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("param '%s': %v", key, err)
+			return result
+		}
+		params[key] = rendered
+	}
+
+	execution := tr.ExecuteWorkflowWithOptions(ctx, &child, RunOptions{Params: params})
+	result.SubWorkflow = &execution
+	if execution.Status != "completed" {
+		result.ExitCode = 1
+		result.Error = fmt.Sprintf("sub-workflow '%s' %s: %s", task.Workflow, execution.Status, execution.ErrorMessage)
+	}
+
+	return result
+}
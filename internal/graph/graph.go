@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// Task statuses used to colour nodes
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusNotRun  = "not_run" // the run ended before the task started
+)
+
+// Node kinds, drawn with different shapes
+const (
+	KindCommand  = "command"
+	KindWorkflow = "workflow" // sub-workflow task
+	KindSensor   = "sensor"   // wait_for task without a command
+)
+
+// Node is a task in the graph
+type Node struct {
+	ID     string
+	Label  string
+	Kind   string
+	Status string // empty when no run is known
+	Level  int    // 0-based stage in the dependency order
+}
+
+// Edge points from a dependency to the task that depends on it
+type Edge struct {
+	From string
+	To   string
+}
+
+// Graph is the task dependency graph of a workflow
+type Graph struct {
+	Name  string
+	Nodes []Node
+	Edges []Edge
+}
+
+// Build creates the graph of a workflow. When execution is not nil, nodes are
+// coloured by the status of its tasks.
+func Build(workflow parser.Workflow, execution *parser.WorkflowExecution) (Graph, error) {
+	sorted, err := parser.NewYAMLParser().TopologicalSort(&workflow)
+	if err != nil {
+		return Graph{}, err
+	}
+
+	statuses := TaskStatuses(workflow, execution)
+	levels := make(map[string]int)
+	g := Graph{Name: workflow.Name}
+
+	for _, task := range sorted {
+		level := 0
+		for _, dep := range task.DependsOn {
+			if levels[dep]+1 > level {
+				level = levels[dep] + 1
+			}
+			g.Edges = append(g.Edges, Edge{From: dep, To: task.ID})
+		}
+		levels[task.ID] = level
+
+		node := Node{ID: task.ID, Label: task.ID, Kind: KindCommand, Status: statuses[task.ID], Level: level}
+		switch {
+		case task.Workflow != "":
+			node.Kind = KindWorkflow
+			node.Label = fmt.Sprintf("%s → %s", task.ID, task.Workflow)
+		case task.WaitFor != nil && task.Command == "" && task.Image == "" && task.HTTP == nil && task.Func == "":
+			node.Kind = KindSensor
+			node.Label = fmt.Sprintf("%s ⏳ %s", task.ID, task.WaitFor.Workflow)
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	return g, nil
+}
+
+// TaskStatuses maps task IDs to their status in an execution. Tasks of the
+// workflow without a result are not_run; a nil execution gives no statuses.
+func TaskStatuses(workflow parser.Workflow, execution *parser.WorkflowExecution) map[string]string {
+	statuses := make(map[string]string)
+	if execution == nil {
+		return statuses
+	}
+
+	for _, task := range workflow.Tasks {
+		statuses[task.ID] = StatusNotRun
+	}
+	for _, result := range execution.TaskResults {
+		if result.Success {
+			statuses[result.TaskID] = StatusSuccess
+		} else {
+			statuses[result.TaskID] = StatusFailed
+		}
+	}
+	return statuses
+}
+
+// colors are the fill and stroke of each status
+var colors = map[string][2]string{
+	StatusSuccess: {"#d4edda", "#28a745"},
+	StatusFailed:  {"#f8d7da", "#dc3545"},
+	StatusNotRun:  {"#e2e3e5", "#6c757d"},
+	"":            {"#ffffff", "#667eea"},
+}
+
+// DOT renders the graph in Graphviz format
+func (g Graph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	for _, node := range g.Nodes {
+		color := colors[node.Status]
+		shape := "box"
+		switch node.Kind {
+		case KindWorkflow:
+			shape = "box3d"
+		case KindSensor:
+			shape = "hexagon"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s, fillcolor=%s, color=%s];\n",
+			dotQuote(node.ID), dotQuote(node.Label), shape, dotQuote(color[0]), dotQuote(color[1]))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g Graph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("t%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, node := range g.Nodes {
+		label := mermaidLabel(node.Label)
+		switch node.Kind {
+		case KindWorkflow:
+			fmt.Fprintf(&b, "  %s[[%s]]\n", ids[node.ID], label)
+		case KindSensor:
+			fmt.Fprintf(&b, "  %s{{%s}}\n", ids[node.ID], label)
+		default:
+			fmt.Fprintf(&b, "  %s[%s]\n", ids[node.ID], label)
+		}
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	for _, status := range []string{StatusSuccess, StatusFailed, StatusNotRun} {
+		var members []string
+		for _, node := range g.Nodes {
+			if node.Status == status {
+				members = append(members, ids[node.ID])
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		color := colors[status]
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", status, color[0], color[1])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(members, ","), status)
+	}
+
+	return b.String()
+}
+
+// mermaidLabel quotes a label, escaping the characters Mermaid treats specially
+func mermaidLabel(label string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(label) + `"`
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func testWorkflow() parser.Workflow {
+	return parser.Workflow{
+		Name: "etl",
+		Tasks: []parser.Task{
+			{ID: "load", Command: "echo load", DependsOn: []string{"transform"}},
+			{ID: "extract", Command: "echo extract"},
+			{ID: "upstream", WaitFor: &parser.ExternalDependency{Workflow: "ingest"}},
+			{ID: "transform", Command: "echo transform", DependsOn: []string{"extract", "upstream"}},
+			{ID: "publish", Workflow: "reports", DependsOn: []string{"load"}},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Build(testWorkflow(), nil)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	levels := map[string]int{"extract": 0, "upstream": 0, "transform": 1, "load": 2, "publish": 3}
+	kinds := map[string]string{"upstream": KindSensor, "publish": KindWorkflow}
+	if len(g.Nodes) != len(levels) {
+		t.Fatalf("Expected %d nodes, got %d", len(levels), len(g.Nodes))
+	}
+	for _, node := range g.Nodes {
+		if node.Level != levels[node.ID] {
+			t.Errorf("Node %s: expected level %d, got %d", node.ID, levels[node.ID], node.Level)
+		}
+		kind := kinds[node.ID]
+		if kind == "" {
+			kind = KindCommand
+		}
+		if node.Kind != kind {
+			t.Errorf("Node %s: expected kind %s, got %s", node.ID, kind, node.Kind)
+		}
+		if node.Status != "" {
+			t.Errorf("Node %s: expected no status without an execution, got %s", node.ID, node.Status)
+		}
+	}
+	if len(g.Edges) != 4 {
+		t.Errorf("Expected 4 edges, got %d", len(g.Edges))
+	}
+}
+
+func TestBuild_Cycle(t *testing.T) {
+	workflow := parser.Workflow{
+		Name: "cycle",
+		Tasks: []parser.Task{
+			{ID: "a", Command: "echo a", DependsOn: []string{"b"}},
+			{ID: "b", Command: "echo b", DependsOn: []string{"a"}},
+		},
+	}
+	if _, err := Build(workflow, nil); err == nil {
+		t.Error("Expected an error for a cyclic workflow")
+	}
+}
+
+func TestTaskStatuses(t *testing.T) {
+	execution := &parser.WorkflowExecution{
+		TaskResults: []parser.ExecutionResult{
+			{TaskID: "extract", Success: true},
+			{TaskID: "upstream", Success: true},
+			{TaskID: "transform", Success: false},
+		},
+	}
+
+	statuses := TaskStatuses(testWorkflow(), execution)
+	expected := map[string]string{
+		"extract":   StatusSuccess,
+		"upstream":  StatusSuccess,
+		"transform": StatusFailed,
+		"load":      StatusNotRun,
+		"publish":   StatusNotRun,
+	}
+	for id, status := range expected {
+		if statuses[id] != status {
+			t.Errorf("Task %s: expected %s, got %s", id, status, statuses[id])
+		}
+	}
+}
+
+func TestGraph_Formats(t *testing.T) {
+	execution := &parser.WorkflowExecution{
+		TaskResults: []parser.ExecutionResult{
+			{TaskID: "extract", Success: true},
+			{TaskID: "upstream", Success: false},
+		},
+	}
+	g, err := Build(testWorkflow(), execution)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		contains []string
+	}{
+		{
+			name:   "dot",
+			output: g.DOT(),
+			contains: []string{
+				`digraph "etl" {`,
+				`"extract" -> "transform";`,
+				`"publish" [label="publish → reports", shape=box3d`,
+				`"upstream" [label="upstream ⏳ ingest", shape=hexagon, fillcolor="#f8d7da"`,
+			},
+		},
+		{
+			name:   "mermaid",
+			output: g.Mermaid(),
+			contains: []string{
+				"flowchart LR",
+				`t0["extract"]`,
+				`t1{{"upstream ⏳ ingest"}}`,
+				`t4[["publish → reports"]]`,
+				"t0 --> t2",
+				"class t0 success",
+				"class t1 failed",
+				"class t2,t3,t4 not_run",
+			},
+		},
+		{
+			name:   "svg",
+			output: g.SVG(),
+			contains: []string{
+				"<title>etl</title>",
+				`<g class="task failed"><title>upstream</title>`,
+				"publish → reports",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.contains {
+				if !strings.Contains(tt.output, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, tt.output)
+				}
+			}
+		})
+	}
+}
+
+func TestGraph_SVGWellFormed(t *testing.T) {
+	workflow := testWorkflow()
+	workflow.Tasks[1].ID = `ex<tract>&"`
+	workflow.Tasks[3].DependsOn = []string{`ex<tract>&"`, "upstream"}
+
+	g, err := Build(workflow, nil)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(g.SVG()))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed XML: %v", err)
+		}
+	}
+}
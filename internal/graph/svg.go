@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout of the SVG rendering: one column per dependency level
+const (
+	svgNodeHeight = 36
+	svgColumnGap  = 60
+	svgRowGap     = 20
+	svgMargin     = 20
+	svgCharWidth  = 7
+	svgMinWidth   = 100
+)
+
+// SVG renders the graph as a standalone SVG image, without external tools.
+// Tasks are placed left to right by dependency level.
+func (g Graph) SVG() string {
+	// Group nodes into columns and size each column by its widest label
+	var columns [][]Node
+	for _, node := range g.Nodes {
+		for len(columns) <= node.Level {
+			columns = append(columns, nil)
+		}
+		columns[node.Level] = append(columns[node.Level], node)
+	}
+
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = svgMinWidth
+		for _, node := range column {
+			if w := utf8.RuneCountInString(node.Label)*svgCharWidth + 24; w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	type box struct{ x, y, w int }
+	boxes := make(map[string]box, len(g.Nodes))
+	width, height := svgMargin, 0
+	for i, column := range columns {
+		for row, node := range column {
+			boxes[node.ID] = box{x: width, y: svgMargin + row*(svgNodeHeight+svgRowGap), w: widths[i]}
+		}
+		if h := len(column) * (svgNodeHeight + svgRowGap); h > height {
+			height = h
+		}
+		width += widths[i] + svgColumnGap
+	}
+	width += svgMargin - svgColumnGap
+	height += 2*svgMargin - svgRowGap
+	if len(g.Nodes) == 0 {
+		width, height = 2*svgMargin, 2*svgMargin
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, "  <title>%s</title>\n", html.EscapeString(g.Name))
+	b.WriteString(`  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#6c757d"/></marker></defs>` + "\n")
+
+	for _, edge := range g.Edges {
+		from, to := boxes[edge.From], boxes[edge.To]
+		x1, y1 := from.x+from.w, from.y+svgNodeHeight/2
+		x2, y2 := to.x, to.y+svgNodeHeight/2
+		mid := (x1 + x2) / 2
+		fmt.Fprintf(&b, `  <path d="M %d %d C %d %d, %d %d, %d %d" fill="none" stroke="#6c757d" stroke-width="1.5" marker-end="url(#arrow)"/>`+"\n",
+			x1, y1, mid, y1, mid, y2, x2, y2)
+	}
+
+	for _, node := range g.Nodes {
+		nb := boxes[node.ID]
+		color := colors[node.Status]
+		radius := 6
+		dash := ""
+		switch node.Kind {
+		case KindWorkflow:
+			radius = 0
+		case KindSensor:
+			radius = svgNodeHeight / 2
+			dash = ` stroke-dasharray="4 2"`
+		}
+		fmt.Fprintf(&b, `  <g class="task %s"><title>%s</title>`, html.EscapeString(node.Status), html.EscapeString(node.ID))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s" stroke="%s" stroke-width="1.5"%s/>`,
+			nb.x, nb.y, nb.w, svgNodeHeight, radius, color[0], color[1], dash)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central">%s</text></g>`+"\n",
+			nb.x+nb.w/2, nb.y+svgNodeHeight/2, html.EscapeString(node.Label))
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
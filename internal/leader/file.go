@@ -0,0 +1,162 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// lease is the content of a lease file
+type lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileLease elects a leader through a lease file on a filesystem shared by
+// every host, such as NFS. The file is created atomically, renewed only by its
+// holder and taken over by another instance once it expired.
+type FileLease struct {
+	path    string
+	ttl     time.Duration
+	id      string
+	mu      sync.Mutex
+	expires time.Time // end of the lease this instance holds, if any
+}
+
+// NewFileLease creates an elector using the lease file at path. A ttl of
+// zero means DefaultTTL.
+func NewFileLease(path string, ttl time.Duration) *FileLease {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &FileLease{path: path, ttl: ttl, id: Identity()}
+}
+
+// TTL is how long the lease lasts without being renewed
+func (l *FileLease) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire takes the lease if it is free or expired, or renews it if held
+func (l *FileLease) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create lease directory: %w", err)
+	}
+
+	now := time.Now()
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l.create(now)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read lease file: %w", err)
+	}
+
+	var current lease
+	if err := json.Unmarshal(data, &current); err != nil {
+		// Leases are written whole, so this one is corrupt and can be taken over
+		current = lease{}
+	}
+
+	// Only the holder writes over a lease that has not expired
+	if current.Holder == l.id && now.Before(l.expires) && now.Before(current.ExpiresAt) {
+		return l.renew(now)
+	}
+	if now.Before(current.ExpiresAt) {
+		l.expires = time.Time{}
+		return false, nil
+	}
+
+	removed, err := removeIfUnchanged(l.path, data)
+	if err != nil {
+		return false, fmt.Errorf("failed to take over expired lease: %w", err)
+	}
+	if !removed {
+		l.expires = time.Time{}
+		return false, nil
+	}
+	return l.create(now)
+}
+
+// create takes a free lease, unless another instance takes it first
+func (l *FileLease) create(now time.Time) (bool, error) {
+	data, err := json.Marshal(lease{Holder: l.id, ExpiresAt: now.Add(l.ttl)})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal lease: %w", err)
+	}
+	created, err := createExclusive(l.path, data)
+	if err != nil {
+		return false, fmt.Errorf("failed to create lease file: %w", err)
+	}
+	if !created {
+		l.expires = time.Time{}
+		return false, nil
+	}
+	l.expires = now.Add(l.ttl)
+	return true, nil
+}
+
+// renew extends the lease this instance holds
+func (l *FileLease) renew(now time.Time) (bool, error) {
+	data, err := json.Marshal(lease{Holder: l.id, ExpiresAt: now.Add(l.ttl)})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal lease: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), "."+filepath.Base(l.path)+".*")
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	l.expires = now.Add(l.ttl)
+	return true, nil
+}
+
+// Release removes the lease file if this instance holds it
+func (l *FileLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.expires.IsZero() {
+		return nil
+	}
+	l.expires = time.Time{}
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lease file: %w", err)
+	}
+	var current lease
+	if json.Unmarshal(data, &current) != nil || current.Holder != l.id {
+		return nil
+	}
+	if _, err := removeIfUnchanged(l.path, data); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileLease(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shared", "leader.lease")
+	ttl := 200 * time.Millisecond
+	a, b := NewFileLease(path, ttl), NewFileLease(path, ttl)
+
+	acquire := func(l *FileLease, want bool, step string) {
+		t.Helper()
+		got, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("%s: Acquire() error = %v", step, err)
+		}
+		if got != want {
+			t.Fatalf("%s: Acquire() = %v, want %v", step, got, want)
+		}
+	}
+
+	acquire(a, true, "free lease")
+	acquire(b, false, "held lease")
+	acquire(a, true, "renewal")
+
+	// a stops renewing, as if it crashed
+	time.Sleep(ttl + 50*time.Millisecond)
+	acquire(b, true, "expired lease")
+	acquire(a, false, "lease taken over")
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	acquire(a, true, "released lease")
+}
+
+func TestFileLease_OneLeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lease")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	leaders := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := NewFileLease(path, time.Minute).Acquire(context.Background())
+			if err != nil {
+				t.Errorf("Acquire() error = %v", err)
+			}
+			if ok {
+				mu.Lock()
+				leaders++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if leaders != 1 {
+		t.Errorf("Expected exactly one leader, got %d", leaders)
+	}
+}
@@ -0,0 +1,44 @@
+// Package leader keeps schedulers that run the same workflows from running
+// them twice: a lock file stops a second daemon on one host, and leader
+// election lets only one of several hosts fire cron runs
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/runid"
+)
+
+// DefaultTTL is how long a leader keeps its lease without renewing it
+const DefaultTTL = 30 * time.Second
+
+// Elector elects one leader among schedulers running the same workflows.
+// Leadership is a lease with a TTL that the leader renews; when the leader
+// stops renewing, another instance takes over once the lease expired.
+// Hosts' clocks must agree to well within the TTL.
+type Elector interface {
+	// Acquire takes the lease, or renews it when this instance holds it, and
+	// reports whether this instance leads for the next TTL
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives up the lease, if held, so another instance takes over at once
+	Release(ctx context.Context) error
+	// TTL is how long the lease lasts without being renewed
+	TTL() time.Duration
+}
+
+// Identity returns an ID that tells this instance apart from every other one:
+// its host, its PID and a unique suffix
+func Identity() string {
+	return fmt.Sprintf("%s:%d:%s", hostname(), os.Getpid(), runid.New())
+}
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
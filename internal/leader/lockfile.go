@@ -0,0 +1,186 @@
+package leader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockInfo is what a lock file records about the process holding it
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// LockedError is returned by AcquireLockFile when a live process holds the lock
+type LockedError struct {
+	Path   string
+	Holder LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("another goliteflow daemon (pid %d on %s, since %s) holds %s",
+		e.Holder.PID, e.Holder.Host, e.Holder.StartedAt.Format(time.RFC3339), e.Path)
+}
+
+// unreadableGrace is how long a lock file that cannot be parsed is assumed to
+// be in the middle of being written rather than left over by a crash
+const unreadableGrace = 10 * time.Second
+
+// LockFile stops a second daemon on the same host from starting. The file
+// holds the PID of its owner; a lock whose process is gone is stale and is
+// taken over. Locks of other hosts are never considered stale.
+type LockFile struct {
+	path string
+	info LockInfo
+}
+
+// AcquireLockFile creates the lock file at path, replacing a stale one
+func AcquireLockFile(path string) (*LockFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	info := LockInfo{PID: os.Getpid(), Host: hostname(), StartedAt: time.Now()}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock: %w", err)
+	}
+
+	// A stale lock is replaced once; losing that race to another process means it holds the lock
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := createExclusive(path, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		if created {
+			return &LockFile{path: path, info: info}, nil
+		}
+
+		current, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock file: %w", err)
+		}
+		holder, stale := staleLock(path, current)
+		if !stale {
+			return nil, &LockedError{Path: path, Holder: holder}
+		}
+		if _, err := removeIfUnchanged(path, current); err != nil {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+
+	holder, _ := readLockInfo(path)
+	return nil, &LockedError{Path: path, Holder: holder}
+}
+
+// Path returns the path of the lock file
+func (l *LockFile) Path() string {
+	return l.path
+}
+
+// Release removes the lock file, unless another process has taken it over
+func (l *LockFile) Release() error {
+	holder, err := readLockInfo(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil || holder.PID != l.info.PID || !holder.StartedAt.Equal(l.info.StartedAt) {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+func readLockInfo(path string) (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid lock file %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// staleLock parses a lock and reports whether its process is gone. A lock
+// that cannot be parsed is stale once it is older than unreadableGrace.
+func staleLock(path string, data []byte) (LockInfo, bool) {
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.PID <= 0 {
+		stat, statErr := os.Stat(path)
+		return info, statErr == nil && time.Since(stat.ModTime()) > unreadableGrace
+	}
+	if info.Host != hostname() {
+		return info, false
+	}
+	return info, !processAlive(info.PID)
+}
+
+// createExclusive creates path with data unless it exists. The file appears
+// with its whole content, so readers never see it half written.
+func createExclusive(path string, data []byte) (bool, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// removeIfUnchanged removes path if it still holds seen. The file is moved
+// aside first, so that of several processes removing the same stale file only
+// one does, and a file created since is put back.
+func removeIfUnchanged(path string, seen []byte) (bool, error) {
+	aside, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".stale.*")
+	if err != nil {
+		return false, err
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+
+	if err := os.Rename(path, aside.Name()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	data, err := os.ReadFile(aside.Name())
+	if err == nil && bytes.Equal(data, seen) {
+		return true, nil
+	}
+
+	// Another process replaced the file in the meantime: restore its file,
+	// unless yet another one has been created
+	if err := os.Link(aside.Name(), path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return false, err
+	}
+	return false, nil
+}
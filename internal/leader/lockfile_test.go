@@ -0,0 +1,136 @@
+package leader
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// deadPID returns the PID of a process that has exited
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot start a process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func writeLock(t *testing.T, path string, info LockInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "goliteflow.lock")
+
+	lock, err := AcquireLockFile(path)
+	if err != nil {
+		t.Fatalf("AcquireLockFile() error = %v", err)
+	}
+
+	_, err = AcquireLockFile(path)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	if locked.Holder.PID != os.Getpid() {
+		t.Errorf("Expected the lock to be held by pid %d, got %d", os.Getpid(), locked.Holder.PID)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+
+	lock, err = AcquireLockFile(path)
+	if err != nil {
+		t.Fatalf("AcquireLockFile() after Release error = %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireLockFile_Stale(t *testing.T) {
+	dir := t.TempDir()
+	pid := deadPID(t)
+
+	tests := []struct {
+		name       string
+		write      func(path string)
+		wantLocked bool
+	}{
+		{
+			name: "dead process",
+			write: func(path string) {
+				writeLock(t, path, LockInfo{PID: pid, Host: hostname(), StartedAt: time.Now()})
+			},
+		},
+		{
+			name: "live process",
+			write: func(path string) {
+				writeLock(t, path, LockInfo{PID: os.Getppid(), Host: hostname(), StartedAt: time.Now()})
+			},
+			wantLocked: true,
+		},
+		{
+			name: "other host",
+			write: func(path string) {
+				writeLock(t, path, LockInfo{PID: pid, Host: hostname() + "-other", StartedAt: time.Now()})
+			},
+			wantLocked: true,
+		},
+		{
+			name: "unreadable and old",
+			write: func(path string) {
+				os.WriteFile(path, []byte("{"), 0644)
+				old := time.Now().Add(-time.Minute)
+				os.Chtimes(path, old, old)
+			},
+		},
+		{
+			name: "unreadable and new",
+			write: func(path string) {
+				os.WriteFile(path, []byte(""), 0644)
+			},
+			wantLocked: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".lock")
+			tt.write(path)
+
+			lock, err := AcquireLockFile(path)
+			var locked *LockedError
+			if tt.wantLocked {
+				if !errors.As(err, &locked) {
+					t.Fatalf("Test %d: expected LockedError, got %v", i, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test %d: expected the stale lock to be replaced, got %v", i, err)
+			}
+			defer lock.Release()
+
+			data, _ := os.ReadFile(path)
+			var info LockInfo
+			if json.Unmarshal(data, &info) != nil || info.PID != os.Getpid() {
+				t.Errorf("Test %d: expected the lock to hold pid %d, got %s", i, os.Getpid(), data)
+			}
+		})
+	}
+}
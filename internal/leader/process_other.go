@@ -0,0 +1,16 @@
+//go:build !unix
+
+package leader
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists; finding
+// a process fails when it has exited
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,160 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTable is the table SQLLease keeps its leases in
+const DefaultTable = "goliteflow_leases"
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLLease elects a leader through a row of a database table that every host
+// can reach. The row is taken with a conditional UPDATE or an INSERT, so the
+// database decides between instances racing for it. Any database/sql driver
+// works; SQLite, PostgreSQL and MySQL are known to.
+type SQLLease struct {
+	db       *sql.DB
+	name     string
+	ttl      time.Duration
+	id       string
+	table    string
+	numbered bool // $1, $2 placeholders instead of ?
+
+	mu      sync.Mutex
+	created bool // whether the table is known to exist
+	holding bool
+}
+
+// NewSQLLease creates an elector using the row called name of DefaultTable
+// in db, created when missing. Schedulers that share a name elect one leader.
+// A ttl of zero means DefaultTTL.
+func NewSQLLease(db *sql.DB, name string, ttl time.Duration) *SQLLease {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &SQLLease{db: db, name: name, ttl: ttl, id: Identity(), table: DefaultTable}
+}
+
+// SetTable keeps the leases in another table
+func (l *SQLLease) SetTable(table string) error {
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("invalid table name '%s'", table)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.table = table
+	l.created = false
+	return nil
+}
+
+// SetNumberedPlaceholders writes queries with $1, $2 placeholders, as
+// PostgreSQL drivers expect, instead of ?
+func (l *SQLLease) SetNumberedPlaceholders(numbered bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.numbered = numbered
+}
+
+// TTL is how long the lease lasts without being renewed
+func (l *SQLLease) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire takes the row if it is free or expired, or renews it if held
+func (l *SQLLease) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.createTable(ctx); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	expires := now.Add(l.ttl).UnixNano() / int64(time.Millisecond)
+	result, err := l.db.ExecContext(ctx, l.query(`UPDATE %s SET holder = ?, expires_at = ? WHERE name = ? AND (holder = ? OR expires_at <= ?)`),
+		l.id, expires, l.name, l.id, now.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		l.holding = false
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 1 {
+		l.holding = true
+		return true, nil
+	}
+
+	// No row yet, or another instance holds it
+	_, insertErr := l.db.ExecContext(ctx, l.query(`INSERT INTO %s (name, holder, expires_at) VALUES (?, ?, ?)`),
+		l.name, l.id, expires)
+	if insertErr == nil {
+		l.holding = true
+		return true, nil
+	}
+
+	l.holding = false
+	var holder string
+	err = l.db.QueryRowContext(ctx, l.query(`SELECT holder FROM %s WHERE name = ?`), l.name).Scan(&holder)
+	if err != nil {
+		// The INSERT failed for another reason than an existing row
+		return false, fmt.Errorf("failed to take lease: %w", insertErr)
+	}
+	return false, nil
+}
+
+// Release deletes the row if this instance holds it
+func (l *SQLLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.holding {
+		return nil
+	}
+	l.holding = false
+	if _, err := l.db.ExecContext(ctx, l.query(`DELETE FROM %s WHERE name = ? AND holder = ?`), l.name, l.id); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// createTable creates the lease table unless it was already created
+func (l *SQLLease) createTable(ctx context.Context) error {
+	if l.created {
+		return nil
+	}
+	_, err := l.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	holder VARCHAR(255) NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, l.table))
+	if err != nil {
+		return fmt.Errorf("failed to create lease table %s: %w", l.table, err)
+	}
+	l.created = true
+	return nil
+}
+
+// query fills in the table name and numbers the placeholders if needed
+func (l *SQLLease) query(format string) string {
+	query := fmt.Sprintf(format, l.table)
+	if !l.numbered {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&sb, "$%d", n)
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,148 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSQLLease(t *testing.T) {
+	ctx := context.Background()
+	db := sql.OpenDB(&leaseConnector{leases: make(map[string]leaseRow)})
+	defer db.Close()
+
+	ttl := 200 * time.Millisecond
+	a, b := NewSQLLease(db, "production", ttl), NewSQLLease(db, "production", ttl)
+	other := NewSQLLease(db, "staging", ttl)
+
+	acquire := func(l *SQLLease, want bool, step string) {
+		t.Helper()
+		got, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("%s: Acquire() error = %v", step, err)
+		}
+		if got != want {
+			t.Fatalf("%s: Acquire() = %v, want %v", step, got, want)
+		}
+	}
+
+	acquire(a, true, "free lease")
+	acquire(b, false, "held lease")
+	acquire(other, true, "lease of another name")
+	acquire(a, true, "renewal")
+
+	// a stops renewing, as if it crashed
+	time.Sleep(ttl + 50*time.Millisecond)
+	acquire(b, true, "expired lease")
+	acquire(a, false, "lease taken over")
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	acquire(a, true, "released lease")
+}
+
+func TestSQLLease_Query(t *testing.T) {
+	l := NewSQLLease(nil, "production", 0)
+	if err := l.SetTable("ha.leases"); err != nil {
+		t.Fatalf("SetTable() error = %v", err)
+	}
+	if err := l.SetTable("leases; DROP TABLE runs"); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+
+	query := `UPDATE %s SET holder = ? WHERE name = ?`
+	if got, want := l.query(query), `UPDATE ha.leases SET holder = ? WHERE name = ?`; got != want {
+		t.Errorf("query() = %s, want %s", got, want)
+	}
+	l.SetNumberedPlaceholders(true)
+	if got, want := l.query(query), `UPDATE ha.leases SET holder = $1 WHERE name = $2`; got != want {
+		t.Errorf("query() = %s, want %s", got, want)
+	}
+}
+
+// leaseConnector is an in-memory database that runs the statements of
+// SQLLease, with a primary key on name like the real table
+type leaseConnector struct {
+	mu     sync.Mutex
+	leases map[string]leaseRow
+}
+
+type leaseRow struct {
+	holder  string
+	expires int64
+}
+
+func (c *leaseConnector) Connect(context.Context) (driver.Conn, error) { return leaseConn{c}, nil }
+func (c *leaseConnector) Driver() driver.Driver                        { return nil }
+
+type leaseConn struct{ db *leaseConnector }
+
+func (leaseConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (leaseConn) Close() error                        { return nil }
+func (leaseConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c leaseConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	arg := func(i int) interface{} { return args[i].Value }
+	switch statement := strings.Fields(query)[0]; statement {
+	case "CREATE":
+		return driver.RowsAffected(0), nil
+	case "UPDATE": // holder, expires_at, name, holder, now
+		row, ok := c.db.leases[arg(2).(string)]
+		if !ok || (row.holder != arg(3).(string) && row.expires > arg(4).(int64)) {
+			return driver.RowsAffected(0), nil
+		}
+		c.db.leases[arg(2).(string)] = leaseRow{holder: arg(0).(string), expires: arg(1).(int64)}
+		return driver.RowsAffected(1), nil
+	case "INSERT": // name, holder, expires_at
+		if _, ok := c.db.leases[arg(0).(string)]; ok {
+			return nil, errors.New("UNIQUE constraint failed")
+		}
+		c.db.leases[arg(0).(string)] = leaseRow{holder: arg(1).(string), expires: arg(2).(int64)}
+		return driver.RowsAffected(1), nil
+	case "DELETE": // name, holder
+		if row, ok := c.db.leases[arg(0).(string)]; ok && row.holder == arg(1).(string) {
+			delete(c.db.leases, arg(0).(string))
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	default:
+		return nil, fmt.Errorf("unexpected statement %s", statement)
+	}
+}
+
+func (c leaseConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if !strings.HasPrefix(query, "SELECT holder") {
+		return nil, fmt.Errorf("unexpected query %s", query)
+	}
+	rows := &leaseRows{}
+	if row, ok := c.db.leases[args[0].Value.(string)]; ok {
+		rows.holders = []string{row.holder}
+	}
+	return rows, nil
+}
+
+type leaseRows struct{ holders []string }
+
+func (r *leaseRows) Columns() []string { return []string{"holder"} }
+func (r *leaseRows) Close() error      { return nil }
+func (r *leaseRows) Next(dest []driver.Value) error {
+	if len(r.holders) == 0 {
+		return io.EOF
+	}
+	dest[0], r.holders = r.holders[0], r.holders[1:]
+	return nil
+}
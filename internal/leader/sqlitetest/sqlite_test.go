@@ -0,0 +1,50 @@
+// Package sqlitetest runs SQLLease against a real SQLite database. It is a
+// module of its own because the driver needs a newer Go than goliteflow.
+package sqlitetest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/leader"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLLease_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "leases.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ttl := time.Second
+	a, b := leader.NewSQLLease(db, "production", ttl), leader.NewSQLLease(db, "production", ttl)
+	acquire := func(l *leader.SQLLease, want bool, step string) {
+		t.Helper()
+		got, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("%s: Acquire() error = %v", step, err)
+		}
+		if got != want {
+			t.Fatalf("%s: Acquire() = %v, want %v", step, got, want)
+		}
+	}
+
+	acquire(a, true, "free lease")
+	acquire(b, false, "held lease")
+	acquire(a, true, "renewal")
+
+	// a stops renewing, as if it crashed
+	time.Sleep(ttl + 100*time.Millisecond)
+	acquire(b, true, "expired lease")
+	acquire(a, false, "lease taken over")
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	acquire(a, true, "released lease")
+}
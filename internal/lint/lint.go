@@ -0,0 +1,209 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the lint configuration looked up next to the workflow file
+const DefaultConfigFile = ".goliteflow-lint.yml"
+
+// Severity controls how a finding is reported
+type Severity string
+
+// Severities in increasing order of importance
+const (
+	SeverityOff     Severity = "off"
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Rank orders severities, off being the lowest
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity validates a severity name
+func ParseSeverity(name string) (Severity, error) {
+	switch s := Severity(name); s {
+	case SeverityOff, SeverityInfo, SeverityWarning, SeverityError:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid severity '%s' (expected off, info, warning or error)", name)
+	}
+}
+
+// Finding is a single best-practice violation
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Workflow string   `json:"workflow,omitempty"`
+	Task     string   `json:"task,omitempty"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// String formats the finding as file:line:column: severity: message [rule]
+func (f Finding) String() string {
+	location := ""
+	if pos := (parser.Position{File: f.File, Line: f.Line, Column: f.Column}); pos.IsValid() {
+		location = pos.String() + ": "
+	}
+	return fmt.Sprintf("%s%s: %s [%s]", location, f.Severity, f.Message, f.Rule)
+}
+
+// RuleConfig overrides the defaults of a rule. Options only apply to the rule
+// named next to them.
+type RuleConfig struct {
+	Severity       Severity `yaml:"severity,omitempty"`
+	MaxRetries     int      `yaml:"max_retries,omitempty"`      // excessive-retries: attempts after the first
+	MinInterval    string   `yaml:"min_interval,omitempty"`     // frequent-schedule
+	BaseDir        string   `yaml:"base_dir,omitempty"`         // missing-script
+	MinChainLength int      `yaml:"min_chain_length,omitempty"` // orphan-task
+}
+
+// Config is the content of a .goliteflow-lint.yml file
+type Config struct {
+	Rules map[string]RuleConfig `yaml:"rules"`
+}
+
+// LoadConfig reads a lint configuration file
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint config %s: %w", filename, err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse lint config %s: %w", filename, err)
+	}
+
+	for name, rule := range config.Rules {
+		if _, ok := findRule(name); !ok {
+			return nil, fmt.Errorf("lint config %s: unknown rule '%s'", filename, name)
+		}
+		if rule.Severity != "" {
+			if _, err := ParseSeverity(string(rule.Severity)); err != nil {
+				return nil, fmt.Errorf("lint config %s: rule '%s': %w", filename, name, err)
+			}
+		}
+	}
+
+	return &config, nil
+}
+
+// Linter checks workflow configurations against the lint rules
+type Linter struct {
+	config *Config
+}
+
+// NewLinter creates a linter; a nil config uses the rule defaults
+func NewLinter(config *Config) *Linter {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Linter{config: config}
+}
+
+// Lint runs every enabled rule and returns the findings ordered by position
+func (l *Linter) Lint(config *parser.WorkflowConfig) ([]Finding, error) {
+	var findings []Finding
+
+	for _, rule := range Rules() {
+		ruleConfig := l.ruleConfig(rule)
+		if ruleConfig.Severity == SeverityOff {
+			continue
+		}
+
+		ruleFindings, err := rule.check(config, ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", rule.Name, err)
+		}
+		for _, finding := range ruleFindings {
+			finding.Rule = rule.Name
+			finding.Severity = ruleConfig.Severity
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return findings, nil
+}
+
+// ruleConfig merges the configured options over the rule defaults
+func (l *Linter) ruleConfig(rule Rule) RuleConfig {
+	merged := rule.Defaults
+	merged.Severity = rule.Severity
+
+	override, ok := l.config.Rules[rule.Name]
+	if !ok {
+		return merged
+	}
+	if override.Severity != "" {
+		merged.Severity = override.Severity
+	}
+	if override.MaxRetries != 0 {
+		merged.MaxRetries = override.MaxRetries
+	}
+	if override.MinInterval != "" {
+		merged.MinInterval = override.MinInterval
+	}
+	if override.BaseDir != "" {
+		merged.BaseDir = override.BaseDir
+	}
+	if override.MinChainLength != 0 {
+		merged.MinChainLength = override.MinChainLength
+	}
+	return merged
+}
+
+// HasFindingsAtOrAbove reports whether any finding is at least as severe as
+// threshold. An off threshold never matches.
+func HasFindingsAtOrAbove(findings []Finding, threshold Severity) bool {
+	if threshold == SeverityOff {
+		return false
+	}
+	for _, finding := range findings {
+		if finding.Severity.Rank() >= threshold.Rank() {
+			return true
+		}
+	}
+	return false
+}
+
+func at(pos parser.Position, finding Finding) Finding {
+	finding.File = pos.File
+	finding.Line = pos.Line
+	finding.Column = pos.Column
+	return finding
+}
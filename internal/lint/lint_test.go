@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+func workflowConfig(workflows ...parser.Workflow) *parser.WorkflowConfig {
+	return &parser.WorkflowConfig{Version: "1.0", Workflows: workflows}
+}
+
+func TestLinter_Rules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "exists.py"), []byte("print(1)"), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *parser.WorkflowConfig
+		rule     string
+		expected []string // task or workflow names the rule should flag
+	}{
+		{
+			name: "missing timeout",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "with_timeout", Command: "echo", Timeout: "1m"},
+				{ID: "without_timeout", Command: "echo"},
+			}}),
+			rule:     "missing-timeout",
+			expected: []string{"without_timeout"},
+		},
+		{
+			name: "excessive retries",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "few", Command: "echo", Timeout: "1m", Retry: 6}, // 5 retries
+				{ID: "many", Command: "echo", Timeout: "1m", Retry: 10},
+			}}),
+			rule:     "excessive-retries",
+			expected: []string{"many"},
+		},
+		{
+			name: "dependency always fails",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "broken", Command: "false", Timeout: "1m"},
+				{ID: "next", Command: "echo", Timeout: "1m", DependsOn: []string{"broken"}},
+				{ID: "last", Command: "echo", Timeout: "1m", DependsOn: []string{"next"}},
+			}}),
+			rule:     "unreachable-task",
+			expected: []string{"next", "last"},
+		},
+		{
+			name: "schedule never fires",
+			config: workflowConfig(parser.Workflow{Name: "feb30", Schedule: "0 0 30 2 *", Tasks: []parser.Task{
+				{ID: "task1", Command: "echo", Timeout: "1m"},
+			}}),
+			rule:     "unreachable-task",
+			expected: []string{"feb30"},
+		},
+		{
+			name: "frequent schedule",
+			config: workflowConfig(
+				parser.Workflow{Name: "every_minute", Schedule: "* * * * *", Tasks: []parser.Task{{ID: "t", Command: "echo", Timeout: "1m"}}},
+				parser.Workflow{Name: "hourly", Schedule: "0 * * * *", Tasks: []parser.Task{{ID: "t", Command: "echo", Timeout: "1m"}}},
+			),
+			rule:     "frequent-schedule",
+			expected: []string{"every_minute"},
+		},
+		{
+			name: "missing script",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "present", Command: "python exists.py", Timeout: "1m"},
+				{ID: "absent", Command: "python missing.py --verbose", Timeout: "1m"},
+				{ID: "relative_program", Command: "./run.sh", Timeout: "1m"},
+				{ID: "templated", Command: "python {{ .Params.script }}.py", Timeout: "1m"},
+			}}),
+			rule:     "missing-script",
+			expected: []string{"absent", "relative_program"},
+		},
+		{
+			name: "orphan task in long chain",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "a", Command: "echo", Timeout: "1m"},
+				{ID: "b", Command: "echo", Timeout: "1m", DependsOn: []string{"a"}},
+				{ID: "c", Command: "echo", Timeout: "1m", DependsOn: []string{"b"}},
+				{ID: "forgotten", Command: "echo", Timeout: "1m"},
+			}}),
+			rule:     "orphan-task",
+			expected: []string{"forgotten"},
+		},
+		{
+			name: "independent tasks without chains",
+			config: workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+				{ID: "a", Command: "echo", Timeout: "1m"},
+				{ID: "b", Command: "echo", Timeout: "1m"},
+			}}),
+			rule: "orphan-task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linter := NewLinter(&Config{Rules: map[string]RuleConfig{"missing-script": {BaseDir: dir}}})
+
+			findings, err := linter.Lint(tt.config)
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+
+			var flagged []string
+			for _, finding := range findings {
+				if finding.Rule != tt.rule {
+					continue
+				}
+				if finding.Task != "" {
+					flagged = append(flagged, finding.Task)
+				} else {
+					flagged = append(flagged, finding.Workflow)
+				}
+			}
+
+			if len(flagged) != len(tt.expected) {
+				t.Fatalf("Expected %v to be flagged, got %v", tt.expected, flagged)
+			}
+			for i := range flagged {
+				if flagged[i] != tt.expected[i] {
+					t.Errorf("Expected %v to be flagged, got %v", tt.expected, flagged)
+				}
+			}
+		})
+	}
+}
+
+func TestLinter_ConfigOverrides(t *testing.T) {
+	config := workflowConfig(parser.Workflow{Name: "wf", Schedule: "0 0 * * *", Tasks: []parser.Task{
+		{ID: "task1", Command: "echo", Retry: 4},
+	}})
+
+	linter := NewLinter(&Config{Rules: map[string]RuleConfig{
+		"missing-timeout":   {Severity: SeverityOff},
+		"excessive-retries": {Severity: SeverityError, MaxRetries: 2},
+	}})
+
+	findings, err := linter.Lint(config)
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", findings)
+	}
+	if findings[0].Rule != "excessive-retries" || findings[0].Severity != SeverityError {
+		t.Errorf("Expected excessive-retries error, got %+v", findings[0])
+	}
+	if want := "task 'task1' makes 4 attempts, 3 retries (more than 2)"; findings[0].Message != want {
+		t.Errorf("Message = %q, want %q", findings[0].Message, want)
+	}
+	if !HasFindingsAtOrAbove(findings, SeverityWarning) || HasFindingsAtOrAbove(findings, SeverityOff) {
+		t.Error("Unexpected HasFindingsAtOrAbove result")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid config",
+			content: "rules:\n  missing-timeout:\n    severity: error\n  frequent-schedule:\n    min_interval: 1h\n",
+		},
+		{
+			name:    "unknown rule",
+			content: "rules:\n  no-such-rule:\n    severity: error\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid severity",
+			content: "rules:\n  missing-timeout:\n    severity: fatal\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown option",
+			content: "rules:\n  missing-timeout:\n    level: error\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultConfigFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			_, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// Rule is a single best-practice check
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity   // default severity
+	Defaults    RuleConfig // default options
+	check       func(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error)
+}
+
+// Rules returns every lint rule in the order they run
+func Rules() []Rule {
+	return []Rule{
+		{
+			Name:        "missing-timeout",
+			Description: "Tasks should set a timeout so a hung command cannot block the workflow",
+			Severity:    SeverityWarning,
+			check:       checkMissingTimeout,
+		},
+		{
+			Name:        "excessive-retries",
+			Description: "More retries than max_retries usually hide a real failure",
+			Severity:    SeverityWarning,
+			Defaults:    RuleConfig{MaxRetries: 5},
+			check:       checkExcessiveRetries,
+		},
+		{
+			Name:        "unreachable-task",
+			Description: "Tasks that can never run, because their workflow never fires or a dependency always fails",
+			Severity:    SeverityWarning,
+			check:       checkUnreachableTasks,
+		},
+		{
+			Name:        "frequent-schedule",
+			Description: "Schedules firing more often than min_interval",
+			Severity:    SeverityWarning,
+			Defaults:    RuleConfig{MinInterval: "5m"},
+			check:       checkFrequentSchedules,
+		},
+		{
+			Name:        "missing-script",
+			Description: "Commands referencing relative script paths that do not exist under base_dir",
+			Severity:    SeverityError,
+			Defaults:    RuleConfig{BaseDir: "."},
+			check:       checkMissingScripts,
+		},
+		{
+			Name:        "orphan-task",
+			Description: "Tasks with no dependencies and no dependants in workflows with chains of min_chain_length or more",
+			Severity:    SeverityInfo,
+			Defaults:    RuleConfig{MinChainLength: 3},
+			check:       checkOrphanTasks,
+		},
+	}
+}
+
+func findRule(name string) (Rule, bool) {
+	for _, rule := range Rules() {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func checkMissingTimeout(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			if (task.Command == "" && task.HTTP == nil && task.Func == "") || task.Timeout != "" {
+				continue
+			}
+			findings = append(findings, at(task.Pos, Finding{
+				Workflow: workflow.Name,
+				Task:     task.ID,
+				Message:  fmt.Sprintf("task '%s' has no timeout", task.ID),
+			}))
+		}
+	}
+	return findings, nil
+}
+
+func checkExcessiveRetries(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			// retry is the total number of attempts; the first is not a retry
+			retries := task.Retry - 1
+			if retries <= options.MaxRetries {
+				continue
+			}
+			findings = append(findings, at(task.Pos, Finding{
+				Workflow: workflow.Name,
+				Task:     task.ID,
+				Message: fmt.Sprintf("task '%s' makes %d attempts, %d retries (more than %d)",
+					task.ID, task.Retry, retries, options.MaxRetries),
+			}))
+		}
+	}
+	return findings, nil
+}
+
+// alwaysFails matches commands that cannot succeed
+var alwaysFails = regexp.MustCompile(`^(false|exit\s+[1-9][0-9]*)$`)
+
+// scheduleReference is the fixed point schedules are evaluated from, so
+// findings do not depend on when lint runs
+var scheduleReference = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func checkUnreachableTasks(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	invoked := make(map[string]bool)
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			if task.Workflow != "" {
+				invoked[task.Workflow] = true
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		// A schedule that never fires leaves the whole workflow unreachable
+		if workflow.Schedule != "" && len(workflow.TriggeredBy) == 0 && !invoked[workflow.Name] {
+			if schedule, err := cron.ParseStandard(workflow.Schedule); err == nil && schedule.Next(scheduleReference).IsZero() {
+				findings = append(findings, at(workflow.Pos, Finding{
+					Workflow: workflow.Name,
+					Message:  fmt.Sprintf("schedule '%s' of workflow '%s' never fires, none of its tasks can run", workflow.Schedule, workflow.Name),
+				}))
+				continue
+			}
+		}
+
+		tasks := make(map[string]parser.Task)
+		for _, task := range workflow.Tasks {
+			tasks[task.ID] = task
+		}
+
+		// blocker returns the always-failing task that prevents id from running
+		blockers := make(map[string]string)
+		var blocker func(id string, visiting map[string]bool) string
+		blocker = func(id string, visiting map[string]bool) string {
+			if b, ok := blockers[id]; ok {
+				return b
+			}
+			if visiting[id] {
+				return ""
+			}
+			visiting[id] = true
+			result := ""
+			for _, dep := range tasks[id].DependsOn {
+				if alwaysFails.MatchString(strings.TrimSpace(tasks[dep].Command)) {
+					result = dep
+					break
+				}
+				if b := blocker(dep, visiting); b != "" {
+					result = b
+					break
+				}
+			}
+			blockers[id] = result
+			return result
+		}
+
+		for _, task := range workflow.Tasks {
+			if b := blocker(task.ID, make(map[string]bool)); b != "" {
+				findings = append(findings, at(task.Pos, Finding{
+					Workflow: workflow.Name,
+					Task:     task.ID,
+					Message:  fmt.Sprintf("task '%s' can never run, it depends on '%s' which always fails", task.ID, b),
+				}))
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkFrequentSchedules(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	minInterval, err := time.ParseDuration(options.MinInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid min_interval '%s': %w", options.MinInterval, err)
+	}
+
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		if workflow.Schedule == "" {
+			continue
+		}
+		schedule, err := cron.ParseStandard(workflow.Schedule)
+		if err != nil {
+			continue // reported by validation
+		}
+
+		if interval := shortestInterval(schedule); interval > 0 && interval < minInterval {
+			findings = append(findings, at(workflow.Pos, Finding{
+				Workflow: workflow.Name,
+				Message:  fmt.Sprintf("workflow '%s' runs every %s (more often than every %s)", workflow.Name, interval, minInterval),
+			}))
+		}
+	}
+	return findings, nil
+}
+
+// shortestInterval returns the smallest gap between the next fire times of a
+// schedule over one week, or 0 if it fires fewer than twice
+func shortestInterval(schedule cron.Schedule) time.Duration {
+	var shortest time.Duration
+	end := scheduleReference.Add(7 * 24 * time.Hour)
+
+	previous := schedule.Next(scheduleReference)
+	for !previous.IsZero() && previous.Before(end) {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(previous); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		previous = next
+	}
+	return shortest
+}
+
+// scriptExtensions are the file types treated as scripts when passed as arguments
+var scriptExtensions = map[string]bool{
+	".sh": true, ".bash": true, ".py": true, ".rb": true, ".js": true,
+	".pl": true, ".php": true, ".ps1": true, ".bat": true, ".cmd": true,
+}
+
+func checkMissingScripts(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			if task.Image != "" {
+				continue // scripts live in the container
+			}
+			for i, field := range strings.Fields(task.Command) {
+				if !isRelativeScript(field, i == 0) {
+					continue
+				}
+				path := filepath.Join(options.BaseDir, field)
+				if _, err := os.Stat(path); err == nil {
+					continue
+				}
+				findings = append(findings, at(task.Pos, Finding{
+					Workflow: workflow.Name,
+					Task:     task.ID,
+					Message:  fmt.Sprintf("task '%s' references %s which does not exist", task.ID, path),
+				}))
+			}
+		}
+	}
+	return findings, nil
+}
+
+// isRelativeScript reports whether a command field names a relative script.
+// The program itself counts when it contains a path separator; arguments
+// count when they have a script extension. Templated fields are skipped.
+func isRelativeScript(field string, program bool) bool {
+	if field == "" || filepath.IsAbs(field) || strings.ContainsAny(field, "{}$*?[=") || strings.HasPrefix(field, "-") {
+		return false
+	}
+	if program {
+		return strings.ContainsRune(field, '/')
+	}
+	return scriptExtensions[strings.ToLower(filepath.Ext(field))]
+}
+
+func checkOrphanTasks(config *parser.WorkflowConfig, options RuleConfig) ([]Finding, error) {
+	var findings []Finding
+	for _, workflow := range config.Workflows {
+		dependants := make(map[string]int)
+		for _, task := range workflow.Tasks {
+			for _, dep := range task.DependsOn {
+				dependants[dep]++
+			}
+		}
+
+		if longestChain(workflow) < options.MinChainLength {
+			continue
+		}
+
+		for _, task := range workflow.Tasks {
+			if len(task.DependsOn) > 0 || dependants[task.ID] > 0 {
+				continue
+			}
+			findings = append(findings, at(task.Pos, Finding{
+				Workflow: workflow.Name,
+				Task:     task.ID,
+				Message:  fmt.Sprintf("task '%s' is not connected to the other tasks of workflow '%s', is a depends_on missing?", task.ID, workflow.Name),
+			}))
+		}
+	}
+	return findings, nil
+}
+
+// longestChain returns the number of tasks on the longest dependency path
+func longestChain(workflow parser.Workflow) int {
+	tasks := make(map[string]parser.Task)
+	for _, task := range workflow.Tasks {
+		tasks[task.ID] = task
+	}
+
+	depth := make(map[string]int)
+	var visit func(id string, visiting map[string]bool) int
+	visit = func(id string, visiting map[string]bool) int {
+		if d, ok := depth[id]; ok {
+			return d
+		}
+		if visiting[id] {
+			return 0 // cycles are reported by validation
+		}
+		visiting[id] = true
+		d := 1
+		for _, dep := range tasks[id].DependsOn {
+			if n := visit(dep, visiting) + 1; n > d {
+				d = n
+			}
+		}
+		depth[id] = d
+		return d
+	}
+
+	longest := 0
+	for _, task := range workflow.Tasks {
+		if d := visit(task.ID, make(map[string]bool)); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	commandTimeout = 30 * time.Second
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// sendEmail sends a plain-text email, authenticating when a username is set
+func sendEmail(email *parser.EmailChannel, subject, message string) error {
+	host, _, err := net.SplitHostPort(email.SMTP)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address '%s': %w", email.SMTP, err)
+	}
+	var auth smtp.Auth
+	if username := os.ExpandEnv(email.Username); username != "" {
+		auth = smtp.PlainAuth("", username, os.ExpandEnv(email.Password), host)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", email.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	if err := smtp.SendMail(email.SMTP, auth, email.From, email.To, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email via %s: %w", email.SMTP, err)
+	}
+	return nil
+}
+
+// webhookPayload builds the request body of a webhook format
+func webhookPayload(format string, msg Message, subject, message string) interface{} {
+	switch format {
+	case parser.WebhookSlack:
+		return map[string]string{"text": "*" + subject + "*\n" + message}
+	case parser.WebhookDiscord:
+		return map[string]string{"content": "**" + subject + "**\n" + message}
+	case parser.WebhookTeams:
+		color := "2EB67D"
+		if msg.Trigger != parser.NotifyOnSuccess && msg.Trigger != parser.NotifyOnRecovery {
+			color = "E01E5A"
+		}
+		return map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    subject,
+			"title":      subject,
+			"text":       message,
+			"themeColor": color,
+		}
+	}
+	return struct {
+		Trigger   string                    `json:"trigger"`
+		Workflow  string                    `json:"workflow"`
+		RunID     string                    `json:"run_id,omitempty"`
+		Status    string                    `json:"status"`
+		Error     string                    `json:"error,omitempty"`
+		Task      string                    `json:"task,omitempty"`
+		Attempt   int                       `json:"attempt,omitempty"`
+		Failures  int                       `json:"failures,omitempty"`
+		Host      string                    `json:"host,omitempty"`
+		Subject   string                    `json:"subject"`
+		Message   string                    `json:"message"`
+		Execution *parser.WorkflowExecution `json:"execution,omitempty"`
+		Violation *parser.SLAViolation      `json:"violation,omitempty"`
+	}{msg.Trigger, msg.Workflow, msg.RunID, msg.Status, msg.Error, msg.Task, msg.Attempt, msg.Failures, msg.Host, subject, message, msg.Execution, msg.Violation}
+}
+
+// sendWebhook posts the notification as JSON and expects a 2xx response
+func sendWebhook(webhook *parser.WebhookChannel, msg Message, subject, message string) error {
+	body, err := json.Marshal(webhookPayload(webhook.Format, msg, subject, message))
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	url := os.ExpandEnv(webhook.URL)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// runCommand runs a command with the message on stdin and the notification
+// in GOLITEFLOW_* environment variables
+func runCommand(command string, msg Message, subject, message string) error {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Env = append(os.Environ(),
+		"GOLITEFLOW_TRIGGER="+msg.Trigger,
+		"GOLITEFLOW_WORKFLOW="+msg.Workflow,
+		"GOLITEFLOW_RUN_ID="+msg.RunID,
+		"GOLITEFLOW_STATUS="+msg.Status,
+		"GOLITEFLOW_ERROR="+msg.Error,
+		"GOLITEFLOW_TASK="+msg.Task,
+		"GOLITEFLOW_SUBJECT="+subject,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command '%s' failed: %w: %s", command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
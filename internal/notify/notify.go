@@ -0,0 +1,335 @@
+// Package notify sends notifications about workflow runs over email,
+// webhooks and commands, as configured by notifications: blocks
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// WorkflowLookup finds the notifications of a workflow by name
+type WorkflowLookup interface {
+	GetWorkflow(name string) (parser.Workflow, bool)
+}
+
+// History lists the recorded runs of a workflow, oldest first
+type History interface {
+	List(workflowName string) ([]parser.WorkflowExecution, error)
+}
+
+// Message is the data subject and message templates are filled with
+type Message struct {
+	Trigger   string // on_failure, on_success, on_recovery, on_retry or on_sla_miss
+	Workflow  string
+	RunID     string
+	Status    string // status of the run, retrying for on_retry or sla_missed for on_sla_miss
+	Error     string
+	Task      string // task being retried or missing its SLA
+	Attempt   int    // attempt about to start, for on_retry
+	Failures  int    // consecutive failed runs, including this one for on_failure
+	StartTime time.Time
+	Duration  time.Duration
+	Host      string
+	Execution *parser.WorkflowExecution // nil for on_retry and on_sla_miss
+	Violation *parser.SLAViolation      // breached SLA, for on_sla_miss
+
+	Subject string // rendered subject, available to the message template
+}
+
+// Default templates, used when a channel sets no subject or message
+var (
+	defaultSubjects = map[string]string{
+		parser.NotifyOnFailure:  "[goliteflow] {{ .Workflow }} failed",
+		parser.NotifyOnSuccess:  "[goliteflow] {{ .Workflow }} succeeded",
+		parser.NotifyOnRecovery: "[goliteflow] {{ .Workflow }} recovered",
+		parser.NotifyOnRetry:    "[goliteflow] {{ .Workflow }}: retrying task {{ .Task }}",
+		parser.NotifyOnSLAMiss:  "[goliteflow] {{ .Workflow }} missed its SLA",
+	}
+	defaultMessages = map[string]string{
+		parser.NotifyOnFailure:  "Workflow {{ .Workflow }} failed on {{ .Host }} after {{ .Duration }}{{ if gt .Failures 1 }} ({{ .Failures }} failures in a row){{ end }}: {{ .Error }}",
+		parser.NotifyOnSuccess:  "Workflow {{ .Workflow }} completed on {{ .Host }} in {{ .Duration }}",
+		parser.NotifyOnRecovery: "Workflow {{ .Workflow }} completed on {{ .Host }} in {{ .Duration }} after {{ .Failures }} failed runs",
+		parser.NotifyOnRetry:    "Task {{ .Task }} of workflow {{ .Workflow }} failed on {{ .Host }} and starts attempt {{ .Attempt }}: {{ .Error }}",
+		parser.NotifyOnSLAMiss:  "SLA missed on {{ .Host }}: {{ .Error }}",
+	}
+)
+
+// Notifier turns run events into notifications. Configuration-level
+// notifications cover every top-level run; a workflow's own notifications
+// also cover its runs as a sub-workflow.
+type Notifier struct {
+	defaults  *parser.Notifications
+	workflows WorkflowLookup
+	host      string
+	now       func() time.Time
+	history   History // optional, seeds the failures of each workflow
+
+	mu       sync.Mutex
+	sent     map[string]time.Time // last notification, by dedup key
+	failures map[string]int       // consecutive failed runs, by workflow
+	seeded   map[string]bool      // workflows whose failures were loaded from history
+}
+
+// NewNotifier creates a notifier for the configuration-level notifications
+// defaults, which may be nil, and the workflows found through lookup
+func NewNotifier(defaults *parser.Notifications, workflows WorkflowLookup) *Notifier {
+	host, _ := os.Hostname()
+	return &Notifier{
+		defaults:  defaults,
+		workflows: workflows,
+		host:      host,
+		now:       time.Now,
+		sent:      make(map[string]time.Time),
+		failures:  make(map[string]int),
+		seeded:    make(map[string]bool),
+	}
+}
+
+// SetHistory counts the failed runs in a row of each workflow from history,
+// so on_recovery and Failures outlast restarts
+func (n *Notifier) SetHistory(history History) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.history = history
+}
+
+// Handle sends the notifications of an event; it is meant to be subscribed
+// to the scheduler's event bus
+func (n *Notifier) Handle(event events.Event) {
+	switch event.Type {
+	case events.WorkflowFinished:
+		n.handleFinished(event)
+	case events.TaskRetrying:
+		n.notify(parser.NotifyOnRetry, event, Message{
+			Status:  "retrying",
+			Error:   event.Error,
+			Task:    event.Task,
+			Attempt: event.Attempt,
+		})
+	case events.SLAMissed:
+		n.notify(parser.NotifyOnSLAMiss, event, Message{
+			Status:    "sla_missed",
+			Error:     event.Reason,
+			Task:      event.Task,
+			Violation: event.Violation,
+		})
+	}
+}
+
+func (n *Notifier) handleFinished(event events.Event) {
+	execution := event.Execution
+	if execution == nil {
+		return
+	}
+	msg := Message{
+		Status:    execution.Status,
+		Error:     execution.ErrorMessage,
+		StartTime: execution.StartTime,
+		Duration:  execution.Duration,
+		Execution: execution,
+	}
+
+	n.seed(event.Workflow, execution)
+	n.mu.Lock()
+	previous := n.failures[event.Workflow]
+	switch execution.Status {
+	case "failed":
+		n.failures[event.Workflow] = previous + 1
+	case "completed":
+		delete(n.failures, event.Workflow)
+		if previous > 0 {
+			// Failures after a recovery are news again
+			n.forget(event.Workflow, parser.NotifyOnFailure, parser.NotifyOnRetry)
+		}
+	}
+	n.mu.Unlock()
+
+	switch execution.Status {
+	case "failed":
+		msg.Failures = previous + 1
+		n.notify(parser.NotifyOnFailure, event, msg)
+	case "completed":
+		msg.Failures = previous
+		n.notify(parser.NotifyOnSuccess, event, msg)
+		if previous > 0 {
+			n.notify(parser.NotifyOnRecovery, event, msg)
+		}
+	}
+}
+
+// seed loads the failed runs in a row of a workflow that ended before
+// execution started from history, on the first run the notifier sees
+func (n *Notifier) seed(workflow string, execution *parser.WorkflowExecution) {
+	n.mu.Lock()
+	history, seeded := n.history, n.seeded[workflow]
+	n.seeded[workflow] = true
+	n.mu.Unlock()
+	if history == nil || seeded {
+		return
+	}
+
+	runs, err := history.List(workflow)
+	if err != nil {
+		logger.Errorf("Failed to load the runs of workflow '%s' for notifications: %v", workflow, err)
+		return
+	}
+	failures := 0
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.ID == execution.ID || !run.StartTime.Before(execution.StartTime) {
+			continue
+		}
+		if run.Status == "completed" {
+			break
+		}
+		if run.Status == "failed" {
+			failures++
+		}
+	}
+
+	n.mu.Lock()
+	n.failures[workflow] += failures
+	n.mu.Unlock()
+}
+
+// notify sends msg to every channel of trigger, at configuration level first
+func (n *Notifier) notify(trigger string, event events.Event, msg Message) {
+	msg.Trigger = trigger
+	msg.Workflow = event.Workflow
+	msg.RunID = event.RunID
+	msg.Host = n.host
+
+	scopes := []struct {
+		name          string
+		notifications *parser.Notifications
+	}{
+		{"config", nil},
+		{"workflow", nil},
+	}
+	if event.ParentRunID == "" {
+		scopes[0].notifications = n.defaults
+	}
+	if n.workflows != nil {
+		if workflow, ok := n.workflows.GetWorkflow(event.Workflow); ok {
+			scopes[1].notifications = workflow.Notifications
+		}
+	}
+
+	for _, scope := range scopes {
+		for i, channel := range scope.notifications.Channels(trigger) {
+			key := dedupKey(event.Workflow, trigger, dedupSubject(event), scope.name, i)
+			if trigger != parser.NotifyOnRecovery && !n.claim(key, scope.notifications.DedupWindow()) {
+				logger.Debugf("Notification %s of workflow '%s' suppressed as a duplicate", trigger, event.Workflow)
+				continue
+			}
+			if err := send(channel, msg); err != nil {
+				logger.Errorf("Failed to send %s notification of workflow '%s': %v", trigger, event.Workflow, err)
+			}
+		}
+	}
+}
+
+// claim records a notification under key, reporting false if one was sent
+// within window
+func (n *Notifier) claim(key string, window time.Duration) bool {
+	if window <= 0 {
+		return true
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.now()
+	if last, ok := n.sent[key]; ok && now.Sub(last) < window {
+		return false
+	}
+	n.sent[key] = now
+	return true
+}
+
+// forget clears the dedup state of a workflow's triggers; called with n.mu held
+func (n *Notifier) forget(workflow string, triggers ...string) {
+	prefixes := make([]string, len(triggers))
+	for i, trigger := range triggers {
+		prefixes[i] = strconv.Quote(workflow) + "/" + trigger + "/"
+	}
+	for key := range n.sent {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(n.sent, key)
+			}
+		}
+	}
+}
+
+// dedupSubject tells apart notifications of the same trigger and workflow
+func dedupSubject(event events.Event) string {
+	if event.Violation != nil {
+		return event.Task + "/" + event.Violation.Type
+	}
+	return event.Task
+}
+
+func dedupKey(workflow, trigger, subject, scope string, channel int) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%d", strconv.Quote(workflow), trigger, subject, scope, channel)
+}
+
+// render fills a channel's subject and message, or the trigger's defaults
+func render(channel parser.NotificationChannel, msg Message) (string, string, error) {
+	subjectText := channel.Subject
+	if subjectText == "" {
+		subjectText = defaultSubjects[msg.Trigger]
+	}
+	subject, err := execute("subject", subjectText, msg)
+	if err != nil {
+		return "", "", err
+	}
+	msg.Subject = subject
+
+	messageText := channel.Message
+	if messageText == "" {
+		messageText = defaultMessages[msg.Trigger]
+	}
+	message, err := execute("message", messageText, msg)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, message, nil
+}
+
+func execute(name, text string, msg Message) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, msg); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// send delivers a notification over one channel
+func send(channel parser.NotificationChannel, msg Message) error {
+	subject, message, err := render(channel, msg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case channel.Email != nil:
+		return sendEmail(channel.Email, subject, message)
+	case channel.Webhook != nil:
+		return sendWebhook(channel.Webhook, msg, subject, message)
+	case channel.Command != "":
+		return runCommand(channel.Command, msg, subject, message)
+	}
+	return fmt.Errorf("channel has no email, webhook or command")
+}
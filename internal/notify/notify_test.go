@@ -0,0 +1,337 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// workflowMap is a WorkflowLookup over a fixed set of workflows
+type workflowMap map[string]parser.Workflow
+
+func (m workflowMap) GetWorkflow(name string) (parser.Workflow, bool) {
+	workflow, ok := m[name]
+	return workflow, ok
+}
+
+// webhookStub records the bodies posted to it
+type webhookStub struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+	header http.Header
+}
+
+func newWebhookStub(t *testing.T) *webhookStub {
+	stub := &webhookStub{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Invalid webhook body: %v", err)
+		}
+		stub.mu.Lock()
+		stub.bodies = append(stub.bodies, body)
+		stub.header = r.Header.Clone()
+		stub.mu.Unlock()
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *webhookStub) received() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.bodies...)
+}
+
+func finished(workflow, status, errorMessage string) events.Event {
+	return events.Event{
+		Type:     events.WorkflowFinished,
+		Workflow: workflow,
+		RunID:    "run-" + status,
+		Execution: &parser.WorkflowExecution{
+			WorkflowID:   workflow,
+			Status:       status,
+			ErrorMessage: errorMessage,
+			Duration:     2 * time.Second,
+		},
+	}
+}
+
+func TestNotifier_WebhookFormats(t *testing.T) {
+	stub := newWebhookStub(t)
+	os.Setenv("NOTIFY_TEST_TOKEN", "secret")
+	defer os.Unsetenv("NOTIFY_TEST_TOKEN")
+
+	var channels []parser.NotificationChannel
+	for _, format := range []string{"", parser.WebhookSlack, parser.WebhookTeams, parser.WebhookDiscord} {
+		channels = append(channels, parser.NotificationChannel{Webhook: &parser.WebhookChannel{
+			URL:     stub.URL,
+			Format:  format,
+			Headers: map[string]string{"Authorization": "Bearer $NOTIFY_TEST_TOKEN"},
+		}})
+	}
+	notifier := NewNotifier(&parser.Notifications{OnFailure: channels}, nil)
+	notifier.Handle(finished("backup", "failed", "task dump failed"))
+
+	bodies := stub.received()
+	if len(bodies) != 4 {
+		t.Fatalf("Expected 4 webhook calls, got %d", len(bodies))
+	}
+	if got := stub.header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Expected the header to expand the environment, got %q", got)
+	}
+
+	generic := bodies[0]
+	if generic["trigger"] != parser.NotifyOnFailure || generic["workflow"] != "backup" || generic["status"] != "failed" {
+		t.Errorf("Unexpected json payload: %v", generic)
+	}
+	if generic["subject"] != "[goliteflow] backup failed" || !strings.Contains(generic["message"].(string), "task dump failed") {
+		t.Errorf("Expected the default templates, got %v", generic)
+	}
+	if execution, ok := generic["execution"].(map[string]interface{}); !ok || execution["workflow_id"] != "backup" {
+		t.Errorf("Expected the execution in the json payload, got %v", generic["execution"])
+	}
+	if text, _ := bodies[1]["text"].(string); !strings.Contains(text, "backup failed") {
+		t.Errorf("Unexpected slack payload: %v", bodies[1])
+	}
+	if bodies[2]["@type"] != "MessageCard" || bodies[2]["title"] != "[goliteflow] backup failed" {
+		t.Errorf("Unexpected teams payload: %v", bodies[2])
+	}
+	if content, _ := bodies[3]["content"].(string); !strings.Contains(content, "task dump failed") {
+		t.Errorf("Unexpected discord payload: %v", bodies[3])
+	}
+}
+
+func TestNotifier_DedupAndRecovery(t *testing.T) {
+	stub := newWebhookStub(t)
+	webhook := []parser.NotificationChannel{{
+		Webhook: &parser.WebhookChannel{URL: stub.URL},
+		Subject: "{{ .Trigger }} {{ .Failures }}",
+	}}
+	notifier := NewNotifier(nil, workflowMap{
+		"backup": {Name: "backup", Notifications: &parser.Notifications{
+			OnFailure:  webhook,
+			OnSuccess:  webhook,
+			OnRecovery: webhook,
+		}},
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return now }
+
+	notifier.Handle(finished("backup", "failed", "boom"))
+	now = now.Add(5 * time.Minute)
+	notifier.Handle(finished("backup", "failed", "boom")) // within the dedup window
+	now = now.Add(5 * time.Minute)
+	notifier.Handle(finished("backup", "completed", ""))
+	now = now.Add(5 * time.Minute)
+	notifier.Handle(finished("backup", "completed", ""))  // within the dedup window
+	notifier.Handle(finished("backup", "failed", "boom")) // first failure since recovering
+
+	var got []string
+	for _, body := range stub.received() {
+		got = append(got, body["subject"].(string))
+	}
+	want := []string{"on_failure 1", "on_success 2", "on_recovery 2", "on_failure 1"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected notifications %v, got %v", want, got)
+	}
+}
+
+// historyList is a History over fixed runs
+type historyList []parser.WorkflowExecution
+
+func (h historyList) List(workflowName string) ([]parser.WorkflowExecution, error) {
+	return h, nil
+}
+
+func TestNotifier_RecoveryAfterRestart(t *testing.T) {
+	stub := newWebhookStub(t)
+	webhook := []parser.NotificationChannel{{
+		Webhook: &parser.WebhookChannel{URL: stub.URL},
+		Subject: "{{ .Trigger }} {{ .Failures }}",
+	}}
+	notifier := NewNotifier(&parser.Notifications{OnRecovery: webhook}, nil)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(id, status string, hours int) parser.WorkflowExecution {
+		return parser.WorkflowExecution{ID: id, WorkflowID: "backup", Status: status, StartTime: start.Add(time.Duration(hours) * time.Hour)}
+	}
+	// Runs recorded by an earlier process; the last one started after the
+	// run being handled and is counted when its own event arrives
+	notifier.SetHistory(historyList{
+		run("r1", "failed", 0),
+		run("r2", "completed", 1),
+		run("r3", "failed", 2),
+		run("r4", "cancelled", 3),
+		run("r5", "failed", 4),
+		run("r7", "failed", 6),
+	})
+
+	event := finished("backup", "completed", "")
+	event.Execution.ID, event.Execution.StartTime = "r6", start.Add(5*time.Hour)
+	notifier.Handle(event)
+
+	bodies := stub.received()
+	if len(bodies) != 1 || bodies[0]["subject"] != "on_recovery 2" {
+		t.Errorf("Expected a recovery after 2 stored failures, got %v", bodies)
+	}
+}
+
+func TestNotifier_ScopesAndRetry(t *testing.T) {
+	stub := newWebhookStub(t)
+	channel := func(scope string) []parser.NotificationChannel {
+		return []parser.NotificationChannel{{Webhook: &parser.WebhookChannel{URL: stub.URL}, Subject: scope + " {{ .Task }} {{ .Attempt }}"}}
+	}
+	notifier := NewNotifier(&parser.Notifications{OnRetry: channel("config"), Dedup: "0s"}, workflowMap{
+		"child": {Name: "child", Notifications: &parser.Notifications{OnRetry: channel("workflow"), Dedup: "0s"}},
+	})
+
+	retry := events.Event{Type: events.TaskRetrying, Workflow: "child", Task: "fetch", Attempt: 2, Error: "timeout"}
+	notifier.Handle(retry)
+	retry.ParentRunID = "parent-run" // a sub-workflow run only notifies its own channels
+	notifier.Handle(retry)
+
+	var got []string
+	for _, body := range stub.received() {
+		got = append(got, body["subject"].(string))
+	}
+	want := []string{"config fetch 2", "workflow fetch 2", "workflow fetch 2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected notifications %v, got %v", want, got)
+	}
+}
+
+func TestNotifier_SLAMiss(t *testing.T) {
+	stub := newWebhookStub(t)
+	notifier := NewNotifier(&parser.Notifications{OnSLAMiss: []parser.NotificationChannel{{
+		Webhook: &parser.WebhookChannel{URL: stub.URL},
+	}}}, nil)
+
+	for _, kind := range []string{parser.SLAMaxDuration, parser.SLAMustFinishBy, parser.SLAMaxDuration} {
+		violation := parser.SLAViolation{Type: kind, Message: "nightly breached " + kind}
+		notifier.Handle(events.Event{Type: events.SLAMissed, Workflow: "nightly", Reason: violation.Message, Violation: &violation})
+	}
+
+	bodies := stub.received()
+	if len(bodies) != 2 {
+		t.Fatalf("Expected one notification per kind of breach, got %d", len(bodies))
+	}
+	if bodies[0]["subject"] != "[goliteflow] nightly missed its SLA" || bodies[1]["error"] != "nightly breached must_finish_by" {
+		t.Errorf("Unexpected notifications: %v", bodies)
+	}
+	if violation, ok := bodies[0]["violation"].(map[string]interface{}); !ok || violation["type"] != parser.SLAMaxDuration {
+		t.Errorf("Expected the violation in the payload, got %v", bodies[0]["violation"])
+	}
+}
+
+func TestNotifier_Command(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "notify.sh")
+	output := filepath.Join(dir, "out.txt")
+	content := "#!/bin/sh\n{ echo \"$GOLITEFLOW_TRIGGER $GOLITEFLOW_WORKFLOW $GOLITEFLOW_STATUS\"; cat; } > " + output + "\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := NewNotifier(&parser.Notifications{OnSuccess: []parser.NotificationChannel{{
+		Command: script,
+		Message: "{{ .Workflow }} took {{ .Duration }}",
+	}}}, nil)
+	notifier.Handle(finished("report", "completed", ""))
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected the command to run: %v", err)
+	}
+	if got, want := string(data), "on_success report completed\nreport took 2s"; got != want {
+		t.Errorf("Expected command output %q, got %q", want, got)
+	}
+}
+
+func TestNotifier_Email(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(t, listener, received)
+
+	notifier := NewNotifier(&parser.Notifications{OnFailure: []parser.NotificationChannel{{
+		Email: &parser.EmailChannel{
+			SMTP: listener.Addr().String(),
+			From: "goliteflow@example.com",
+			To:   []string{"ops@example.com", "dev@example.com"},
+		},
+		Subject: "{{ .Workflow }} is {{ .Status }}",
+	}}}, nil)
+	notifier.Handle(finished("backup", "failed", "disk full"))
+
+	select {
+	case data := <-received:
+		for _, want := range []string{"RCPT TO:<ops@example.com>", "RCPT TO:<dev@example.com>", "Subject: backup is failed", "disk full"} {
+			if !strings.Contains(data, want) {
+				t.Errorf("Expected %q in the SMTP session, got:\n%s", want, data)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an email")
+	}
+}
+
+// serveSMTP accepts one SMTP session and sends its transcript to received
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var transcript strings.Builder
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP stub")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Errorf("SMTP stub: %v", err)
+			return
+		}
+		transcript.WriteString(line)
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				reply("250 OK")
+			}
+			continue
+		}
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			inData = true
+			reply("354 End data with <CR><LF>.<CR><LF>")
+		case command == "QUIT":
+			reply("221 Bye")
+			received <- transcript.String()
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
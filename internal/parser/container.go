@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Container configures how a task with an image runs in its container
+type Container struct {
+	Runtime string            `yaml:"runtime,omitempty"` // container CLI, e.g. docker (default) or podman
+	Mounts  []string          `yaml:"mounts,omitempty"`  // host:container[:options], relative host paths allowed
+	Env     map[string]string `yaml:"env,omitempty"`
+	Workdir string            `yaml:"workdir,omitempty"` // absolute path inside the container
+}
+
+// SplitMount splits a mount into its host path, container path and options
+func SplitMount(mount string) (host, target, options string, err error) {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("expected host:container[:options]")
+	}
+	if !path.IsAbs(parts[1]) {
+		return "", "", "", fmt.Errorf("container path '%s' must be absolute", parts[1])
+	}
+	if len(parts) == 3 {
+		options = parts[2]
+	}
+	return parts[0], parts[1], options, nil
+}
+
+// validateContainer checks the image and container settings of a task
+func validateContainer(task *Task, node *yaml.Node, pos Position, path string, v *validator) {
+	if task.Image == "" {
+		if task.Container != nil {
+			v.addf(fieldPos(node, pos, "container"), path+".container", "container requires image")
+		}
+		return
+	}
+
+	if task.Workflow != "" {
+		v.addf(fieldPos(node, pos, "image"), path+".image", "image and workflow cannot be used together")
+	}
+	if task.Sandbox != nil {
+		v.addf(fieldPos(node, pos, "sandbox"), path+".sandbox", "sandbox cannot be used with image, the container is already isolated")
+	}
+	if task.Container == nil {
+		return
+	}
+
+	containerNode := mappingValue(node, "container")
+	containerPos := fieldPos(node, pos, "container")
+	for i, mount := range task.Container.Mounts {
+		if _, _, _, err := SplitMount(mount); err != nil {
+			v.addf(itemPos(containerNode, containerPos, "mounts", i), fmt.Sprintf("%s.container.mounts[%d]", path, i),
+				"invalid mount '%s': %v", mount, err)
+		}
+	}
+	if task.Container.Workdir != "" && !strings.HasPrefix(task.Container.Workdir, "/") {
+		v.addf(fieldPos(containerNode, containerPos, "workdir"), path+".container.workdir", "workdir '%s' must be absolute", task.Container.Workdir)
+	}
+}
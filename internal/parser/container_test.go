@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_Container(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: build
+    schedule: "0 0 * * *"
+    tasks:
+      - id: default-command
+        image: alpine:3.20
+      - id: compile
+        image: golang:1.22
+        command: go build ./...
+        container:
+          runtime: podman
+          mounts: ["./src:/src", "cache:/root/.cache:rw"]
+          env: {CGO_ENABLED: "0"}
+          workdir: /src
+`
+
+	config, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	compile := config.Workflows[0].Tasks[1]
+	if compile.Image != "golang:1.22" || compile.Container.Runtime != "podman" || compile.Container.Env["CGO_ENABLED"] != "0" {
+		t.Errorf("Unexpected container task: %+v", compile)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidContainer(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: build
+    schedule: "0 0 * * *"
+    tasks:
+      - id: no-image
+        command: make
+        container:
+          workdir: /src
+      - id: bad
+        image: golang:1.22
+        command: make
+        sandbox:
+          private_tmp: true
+        container:
+          mounts: ["./src", "./src:relative"]
+          workdir: src
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].tasks[0].container",
+		"workflows[0].tasks[1].sandbox",
+		"workflows[0].tasks[1].container.mounts[0]",
+		"workflows[0].tasks[1].container.mounts[1]",
+		"workflows[0].tasks[1].container.workdir",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
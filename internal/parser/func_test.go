@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_Func(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: app
+    schedule: "0 0 * * *"
+    tasks:
+      - id: refresh
+        func: refresh_cache
+        retry: 2
+      - id: both
+        func: refresh_cache
+        command: echo hi
+      - id: sandboxed
+        func: refresh_cache
+        run_as: {user: nobody}
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].tasks[1].func",
+		"workflows[0].tasks[2].func",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTPRequest is a built-in task that sends a request instead of running a command
+type HTTPRequest struct {
+	Method  string            `yaml:"method,omitempty"` // default GET
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Auth    *HTTPAuth         `yaml:"auth,omitempty"`
+	Expect  *HTTPExpect       `yaml:"expect,omitempty"`
+	SaveTo  string            `yaml:"save_to,omitempty"` // file the response body is written to
+	Output  string            `yaml:"output,omitempty"`  // task output the response body is stored in
+	Outputs map[string]string `yaml:"outputs,omitempty"` // task outputs extracted from the JSON response, by path
+}
+
+// HTTPAuth authenticates a request with basic credentials or a bearer token.
+// Values may reference environment variables as $NAME or ${NAME}.
+type HTTPAuth struct {
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Bearer   string `yaml:"bearer,omitempty"`
+}
+
+// HTTPExpect lists the checks a response must pass for the task to succeed
+type HTTPExpect struct {
+	Status []int           `yaml:"status,omitempty"` // default any 2xx status
+	JSON   []JSONAssertion `yaml:"json,omitempty"`
+}
+
+// JSONAssertion checks a value of the JSON response, addressed by a path
+// such as "data.items[0].id"
+type JSONAssertion struct {
+	Path   string  `yaml:"path"`
+	Equals *string `yaml:"equals,omitempty"` // compared with strings as-is and other values as JSON
+	Exists *bool   `yaml:"exists,omitempty"`
+}
+
+// ParseJSONPath splits a path such as "data.items[0].id" into its keys and
+// array indexes ("data", "items", "0", "id")
+func ParseJSONPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []string
+		if open := strings.IndexByte(part, '['); open >= 0 {
+			key = part[:open]
+			rest := part[open:]
+			for rest != "" {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("invalid index in '%s'", part)
+				}
+				index := rest[1:end]
+				if index == "" || strings.Trim(index, "0123456789") != "" {
+					return nil, fmt.Errorf("invalid index '%s' in '%s'", index, part)
+				}
+				indexes = append(indexes, index)
+				rest = rest[end+1:]
+			}
+		}
+		if key == "" && len(indexes) == 0 {
+			return nil, fmt.Errorf("empty segment in '%s'", path)
+		}
+		if key != "" {
+			segments = append(segments, key)
+		}
+		segments = append(segments, indexes...)
+	}
+	return segments, nil
+}
+
+// validateHTTP checks the http settings of a task
+func validateHTTP(task *Task, node *yaml.Node, pos Position, path string, v *validator) {
+	request := task.HTTP
+	if request == nil {
+		return
+	}
+	httpNode := mappingValue(node, "http")
+	httpPos := fieldPos(node, pos, "http")
+
+	if task.Command != "" || task.Workflow != "" || task.Image != "" {
+		v.addf(httpPos, path+".http", "http cannot be used together with command, workflow or image")
+	}
+	if request.URL == "" {
+		v.addf(httpPos, path+".http.url", "url is required")
+	}
+	if request.Method != "" && strings.ContainsAny(request.Method, " \t/()<>@,;:\\\"[]?={}") {
+		v.addf(fieldPos(httpNode, httpPos, "method"), path+".http.method", "invalid method '%s'", request.Method)
+	}
+
+	if auth := request.Auth; auth != nil && auth.Bearer != "" && (auth.Username != "" || auth.Password != "") {
+		v.addf(fieldPos(httpNode, httpPos, "auth"), path+".http.auth", "use either username and password or bearer, not both")
+	}
+
+	if expect := request.Expect; expect != nil {
+		expectNode := mappingValue(httpNode, "expect")
+		expectPos := fieldPos(httpNode, httpPos, "expect")
+		for i, status := range expect.Status {
+			if status < 100 || status > 599 {
+				v.addf(itemPos(expectNode, expectPos, "status", i), fmt.Sprintf("%s.http.expect.status[%d]", path, i),
+					"invalid status code %d", status)
+			}
+		}
+		for i, assertion := range expect.JSON {
+			assertionPath := fmt.Sprintf("%s.http.expect.json[%d]", path, i)
+			assertionPos := itemPos(expectNode, expectPos, "json", i)
+			if _, err := ParseJSONPath(assertion.Path); err != nil {
+				v.addf(assertionPos, assertionPath+".path", "invalid JSON path '%s': %v", assertion.Path, err)
+			}
+			if assertion.Equals == nil && assertion.Exists == nil {
+				v.addf(assertionPos, assertionPath, "equals or exists is required")
+			}
+		}
+	}
+
+	names := make([]string, 0, len(request.Outputs))
+	for name := range request.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := ParseJSONPath(request.Outputs[name]); err != nil {
+			v.addf(fieldPos(httpNode, httpPos, "outputs"), path+".http.outputs."+name, "invalid JSON path '%s': %v", request.Outputs[name], err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_HTTP(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: api
+    schedule: "0 * * * *"
+    tasks:
+      - id: login
+        http:
+          method: POST
+          url: https://api.example.com/login
+          headers: {Content-Type: application/json}
+          body: '{"user": "{{ .Params.user }}"}'
+          auth:
+            bearer: $API_TOKEN
+          expect:
+            status: [200, 201]
+            json:
+              - path: data.ok
+                equals: "true"
+          outputs:
+            token: data.token
+`
+
+	config, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	request := config.Workflows[0].Tasks[0].HTTP
+	if request == nil || request.Method != "POST" || request.Auth.Bearer != "$API_TOKEN" {
+		t.Fatalf("Unexpected http task: %+v", request)
+	}
+	if !reflect.DeepEqual(request.Expect.Status, []int{200, 201}) || *request.Expect.JSON[0].Equals != "true" {
+		t.Errorf("Unexpected expectations: %+v", request.Expect)
+	}
+	if request.Outputs["token"] != "data.token" {
+		t.Errorf("Unexpected outputs: %v", request.Outputs)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidHTTP(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: api
+    schedule: "0 * * * *"
+    tasks:
+      - id: both
+        command: curl example.com
+        http:
+          url: https://example.com
+      - id: bad
+        http:
+          method: "GE T"
+          auth: {username: admin, bearer: token}
+          expect:
+            status: [99]
+            json:
+              - path: items[x]
+                equals: "1"
+              - path: ok
+          outputs:
+            id: ""
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].tasks[0].http",
+		"workflows[0].tasks[1].http.url",
+		"workflows[0].tasks[1].http.method",
+		"workflows[0].tasks[1].http.auth",
+		"workflows[0].tasks[1].http.expect.status[0]",
+		"workflows[0].tasks[1].http.expect.json[0].path",
+		"workflows[0].tasks[1].http.expect.json[1]",
+		"workflows[0].tasks[1].http.outputs.id",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "status", want: []string{"status"}},
+		{path: "data.items[0].id", want: []string{"data", "items", "0", "id"}},
+		{path: "[1][2]", want: []string{"1", "2"}},
+		{path: "", wantErr: true},
+		{path: "data..id", wantErr: true},
+		{path: "items[", wantErr: true},
+		{path: "items[-1]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ParseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJSONPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
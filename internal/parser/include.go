@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfig decodes a configuration document, records the position of each
+// workflow and task, and merges the files it includes. Relative include paths
+// are resolved against baseDir. visited holds the absolute paths of the files
+// on the current include chain to detect cycles. Problems that do not stop
+// decoding, such as schema violations or a missing include, are added to v.
+func (p *YAMLParser) loadConfig(data []byte, file, baseDir string, visited map[string]bool, v *validator) (*WorkflowConfig, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		if file != "" {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", file, err)
+		}
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	var config WorkflowConfig
+	if len(root.Content) == 0 {
+		return &config, nil
+	}
+	doc := root.Content[0]
+
+	if err := doc.Decode(&config); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			if file != "" {
+				return nil, fmt.Errorf("failed to parse YAML in %s: %w", file, err)
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		v.addDecodeErrors(typeErr, file)
+	}
+
+	v.checkSchema(doc, fileSchema, "", file)
+	annotatePositions(doc, &config, file)
+
+	includeNode := mappingValue(doc, "include")
+	for i, pattern := range config.Include {
+		pos := nodePosition(doc, file)
+		if includeNode != nil && includeNode.Kind == yaml.SequenceNode && i < len(includeNode.Content) {
+			pos = nodePosition(includeNode.Content[i], file)
+		}
+		path := fmt.Sprintf("include[%d]", i)
+
+		paths, err := expandInclude(pattern, baseDir)
+		if err != nil {
+			v.addf(pos, path, "%v", err)
+			continue
+		}
+
+		for _, includedPath := range paths {
+			included, err := p.loadIncludedFile(includedPath, visited, v)
+			if err != nil {
+				v.addf(pos, path, "%v", err)
+				continue
+			}
+			mergeConfig(&config, included, v)
+		}
+	}
+
+	return &config, nil
+}
+
+// loadIncludedFile reads and loads a single included file
+func (p *YAMLParser) loadIncludedFile(path string, visited map[string]bool, v *validator) (*WorkflowConfig, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve include %s: %w", path, err)
+	}
+	if visited[absPath] {
+		return nil, fmt.Errorf("include cycle detected: %s is already being loaded", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file %s: %w", path, err)
+	}
+
+	visited[absPath] = true
+	defer delete(visited, absPath)
+
+	config, err := p.loadConfig(data, path, filepath.Dir(path), visited, v)
+	if err == nil && config.Notifications != nil {
+		v.addf(fieldPos(config.node, Position{File: path}, "notifications"), "notifications",
+			"notifications can only be set in the root configuration")
+	}
+	if err == nil && config.Pools != nil {
+		v.addf(fieldPos(config.node, Position{File: path}, "pools"), "pools",
+			"pools can only be set in the root configuration")
+	}
+	if err == nil && config.MaxConcurrentTasks != 0 {
+		v.addf(fieldPos(config.node, Position{File: path}, "max_concurrent_tasks"), "max_concurrent_tasks",
+			"max_concurrent_tasks can only be set in the root configuration")
+	}
+	return config, err
+}
+
+// expandInclude resolves an include entry to the files it names, sorted by path.
+// Plain paths must exist; glob patterns may match nothing.
+func expandInclude(pattern, baseDir string) ([]string, error) {
+	path := pattern
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("included file %s not found: %w", path, err)
+		}
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// mergeConfig appends the workflows and templates of an included config
+func mergeConfig(config, included *WorkflowConfig, v *validator) {
+	names := make([]string, 0, len(included.TaskTemplates))
+	for name := range included.TaskTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		template := included.TaskTemplates[name]
+		if existing, ok := config.TaskTemplates[name]; ok {
+			v.addf(template.Pos, "task_templates."+name, "task template '%s' is already defined at %s", name, existing.Pos)
+			continue
+		}
+		if config.TaskTemplates == nil {
+			config.TaskTemplates = make(map[string]Task)
+		}
+		config.TaskTemplates[name] = template
+	}
+
+	config.Workflows = append(config.Workflows, included.Workflows...)
+}
+
+// annotatePositions records where each workflow, task and task template is defined
+func annotatePositions(doc *yaml.Node, config *WorkflowConfig, file string) {
+	config.node = doc
+	if workflows := mappingValue(doc, "workflows"); workflows != nil && workflows.Kind == yaml.SequenceNode {
+		for i, workflowNode := range workflows.Content {
+			if i >= len(config.Workflows) {
+				break
+			}
+			workflow := &config.Workflows[i]
+			workflow.Pos = nodePosition(workflowNode, file)
+			workflow.node = workflowNode
+
+			tasks := mappingValue(workflowNode, "tasks")
+			if tasks == nil || tasks.Kind != yaml.SequenceNode {
+				continue
+			}
+			for j, taskNode := range tasks.Content {
+				if j >= len(workflow.Tasks) {
+					break
+				}
+				workflow.Tasks[j].Pos = nodePosition(taskNode, file)
+				workflow.Tasks[j].node = taskNode
+			}
+		}
+	}
+
+	if templates := mappingValue(doc, "task_templates"); templates != nil && templates.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(templates.Content); i += 2 {
+			name := templates.Content[i].Value
+			if template, ok := config.TaskTemplates[name]; ok {
+				template.Pos = nodePosition(templates.Content[i+1], file)
+				template.node = templates.Content[i+1]
+				config.TaskTemplates[name] = template
+			}
+		}
+	}
+}
+
+// mappingValue returns the value node for key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func nodePosition(node *yaml.Node, file string) Position {
+	return Position{File: file, Line: node.Line, Column: node.Column}
+}
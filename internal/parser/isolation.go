@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunAs sets the user and group a task's process runs as
+type RunAs struct {
+	User  string `yaml:"user"`            // name or numeric uid
+	Group string `yaml:"group,omitempty"` // name or numeric gid, default the user's primary group
+}
+
+// Sandbox restricts what a task's process can see and change
+type Sandbox struct {
+	PrivateTmp bool     `yaml:"private_tmp,omitempty"` // empty /tmp only visible to the task
+	ReadOnly   []string `yaml:"read_only,omitempty"`   // absolute paths bound read-only
+	NoNetwork  bool     `yaml:"no_network,omitempty"`  // only a loopback interface, which is down
+	Umask      string   `yaml:"umask,omitempty"`       // octal, e.g. "0077"
+}
+
+// NeedsNamespaces reports whether the sandbox needs new mount or network
+// namespaces, which require root
+func (s *Sandbox) NeedsNamespaces() bool {
+	return s != nil && (s.PrivateTmp || len(s.ReadOnly) > 0 || s.NoNetwork)
+}
+
+// ParseUmask converts an octal umask such as "0077"
+func ParseUmask(value string) (int, error) {
+	umask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || umask > 0777 {
+		return 0, fmt.Errorf("expected an octal value between 0000 and 0777")
+	}
+	return int(umask), nil
+}
+
+// validateIsolation checks the run_as and sandbox settings of a task or workflow at path
+func validateIsolation(runAs *RunAs, sandbox *Sandbox, node *yaml.Node, pos Position, path string, v *validator) {
+	if runAs != nil && runAs.User == "" {
+		v.addf(fieldPos(node, pos, "run_as"), path+".run_as.user", "user is required")
+	}
+
+	if sandbox == nil {
+		return
+	}
+	sandboxNode := mappingValue(node, "sandbox")
+	sandboxPos := fieldPos(node, pos, "sandbox")
+
+	for i, dir := range sandbox.ReadOnly {
+		if !filepath.IsAbs(dir) {
+			v.addf(itemPos(sandboxNode, sandboxPos, "read_only", i), fmt.Sprintf("%s.sandbox.read_only[%d]", path, i),
+				"read-only path '%s' must be absolute", dir)
+		}
+	}
+	if sandbox.Umask != "" {
+		if _, err := ParseUmask(sandbox.Umask); err != nil {
+			v.addf(fieldPos(sandboxNode, sandboxPos, "umask"), path+".sandbox.umask", "invalid umask '%s': %v", sandbox.Umask, err)
+		}
+	}
+}
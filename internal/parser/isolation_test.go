@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_InvalidIsolation(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: isolated
+    schedule: "0 0 * * *"
+    run_as:
+      group: staff
+    tasks:
+      - id: task1
+        command: echo hello
+        sandbox:
+          read_only: [/etc, data]
+          umask: "0999"
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].run_as.user",
+		"workflows[0].tasks[0].sandbox.read_only[1]",
+		"workflows[0].tasks[0].sandbox.umask",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
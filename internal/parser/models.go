@@ -1,18 +1,97 @@
 package parser
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 // WorkflowConfig represents the root configuration structure
 type WorkflowConfig struct {
-	Version   string     `yaml:"version"`
-	Workflows []Workflow `yaml:"workflows"`
+	Version       string          `yaml:"version"`
+	Include       []string        `yaml:"include,omitempty"`        // files or globs merged into this config
+	TaskTemplates map[string]Task `yaml:"task_templates,omitempty"` // reusable task definitions for extends
+	Notifications *Notifications  `yaml:"notifications,omitempty"`  // applies to every workflow
+	Workflows     []Workflow      `yaml:"workflows"`
+
+	Pools              map[string]Pool `yaml:"pools,omitempty"`                // named slot limits shared by the tasks with pool
+	MaxConcurrentTasks int             `yaml:"max_concurrent_tasks,omitempty"` // tasks running at once across workflows, 0 for no limit
+
+	node *yaml.Node // source node, used to point errors at individual fields
+}
+
+// Position identifies where a definition appears in a configuration file
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid reports whether the position points at a line
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:column
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
 }
 
 // Workflow represents a single workflow definition
 type Workflow struct {
-	Name     string `yaml:"name"`
-	Schedule string `yaml:"schedule"`
-	Tasks    []Task `yaml:"tasks"`
+	Name        string            `yaml:"name"`
+	Schedule    string            `yaml:"schedule"`
+	TriggeredBy []WorkflowTrigger `yaml:"triggered_by,omitempty"`
+	Params      map[string]string `yaml:"params,omitempty"`    // defaults, available to commands as {{ .Params.name }}
+	Resources   *Resources        `yaml:"resources,omitempty"` // defaults for every task
+	RunAs       *RunAs            `yaml:"run_as,omitempty"`    // default user of every task
+	Sandbox     *Sandbox          `yaml:"sandbox,omitempty"`   // default sandbox of every task
+	RunsOn      []string          `yaml:"runs_on,omitempty"`   // default worker labels of every task
+
+	Notifications *Notifications `yaml:"notifications,omitempty"` // in addition to the configuration's
+	SLA           *SLA           `yaml:"sla,omitempty"`           // expected duration, deadline and reliability of runs
+
+	Tasks []Task `yaml:"tasks"`
+
+	Pos  Position   `yaml:"-" json:"-"` // where the workflow is defined
+	node *yaml.Node // source node, used to point errors at individual fields
+}
+
+// IsEntryPoint reports whether a one-off run starts this workflow directly. Workflows
+// without a schedule run as sub-workflows, and triggered workflows run with their upstream.
+func (w Workflow) IsEntryPoint() bool {
+	return w.Schedule != "" && len(w.TriggeredBy) == 0
+}
+
+// WorkflowTrigger starts a workflow when another workflow finishes
+type WorkflowTrigger struct {
+	Workflow string `yaml:"workflow"`
+	Status   string `yaml:"status,omitempty"` // completed (default), failed or any
+}
+
+// Trigger statuses accepted by WorkflowTrigger
+const (
+	TriggerOnCompleted = "completed"
+	TriggerOnFailed    = "failed"
+	TriggerOnAny       = "any"
+)
+
+// Matches reports whether a finished run with the given status fires the trigger
+func (t WorkflowTrigger) Matches(status string) bool {
+	switch t.Status {
+	case "", TriggerOnCompleted:
+		return status == "completed"
+	case TriggerOnFailed:
+		return status == "failed"
+	case TriggerOnAny:
+		return true
+	default:
+		return false
+	}
 }
 
 // Task represents a single task within a workflow
@@ -22,6 +101,42 @@ type Task struct {
 	Retry     int      `yaml:"retry,omitempty"`
 	DependsOn []string `yaml:"depends_on,omitempty"`
 	Timeout   string   `yaml:"timeout,omitempty"`
+
+	WaitFor  *ExternalDependency `yaml:"wait_for,omitempty"`
+	Workflow string              `yaml:"workflow,omitempty"` // run another workflow as a child run
+	Params   map[string]string   `yaml:"params,omitempty"`   // parameters passed to the child workflow
+	Extends  string              `yaml:"extends,omitempty"`  // name of a task template to inherit from
+
+	Resources *Resources `yaml:"resources,omitempty"` // limits applied to the command's process
+	RunAs     *RunAs     `yaml:"run_as,omitempty"`    // user and group of the command's process
+	Sandbox   *Sandbox   `yaml:"sandbox,omitempty"`   // isolation of the command's process
+	RunsOn    []string   `yaml:"runs_on,omitempty"`   // labels a worker needs to run the task; none runs it on the scheduler
+
+	Pool      string `yaml:"pool,omitempty"`       // pool the task takes slots of while it runs
+	PoolSlots int    `yaml:"pool_slots,omitempty"` // slots of the pool the task takes, default 1
+
+	Image     string     `yaml:"image,omitempty"`     // run the command in a container of this image
+	Container *Container `yaml:"container,omitempty"` // runtime, mounts, env and workdir of the container
+
+	HTTP *HTTPRequest `yaml:"http,omitempty"` // send a request instead of running a command
+	Func string       `yaml:"func,omitempty"` // call a Go function registered by the embedding application
+
+	SLA *SLA `yaml:"sla,omitempty"` // expected duration, deadline and reliability of the task
+
+	Pos  Position   `yaml:"-" json:"-"` // where the task is defined
+	node *yaml.Node // source node, used to point errors at individual fields
+}
+
+// Pool limits how many tasks using it run at once, across workflows
+type Pool struct {
+	Slots int `yaml:"slots"`
+}
+
+// ExternalDependency makes a task wait (sensor style) for a successful run of another workflow
+type ExternalDependency struct {
+	Workflow     string `yaml:"workflow"`
+	Within       string `yaml:"within,omitempty"`        // maximum age of the successful run, e.g. "24h"
+	PollInterval string `yaml:"poll_interval,omitempty"` // how often to check, default 30s
 }
 
 // ExecutionResult represents the result of a task execution
@@ -37,17 +152,60 @@ type ExecutionResult struct {
 	Stdout     string        `json:"stdout"`
 	Stderr     string        `json:"stderr"`
 	Error      string        `json:"error,omitempty"`
+
+	Outputs map[string]string `json:"outputs,omitempty"` // values later tasks reference as {{ .Outputs.task.name }}
+
+	PeakRSS int64         `json:"peak_rss_bytes,omitempty"` // largest resident set of any attempt
+	CPUTime time.Duration `json:"cpu_time,omitempty"`       // user and system time of all attempts
+
+	SubWorkflow *WorkflowExecution `json:"sub_workflow,omitempty"` // child run of a workflow task
+	Worker      string             `json:"worker,omitempty"`       // worker that ran the last attempt, if not the scheduler
+
+	QueueTime time.Duration `json:"queue_time,omitempty"` // time spent waiting for pool and concurrency slots, not part of Duration
+
+	Reused bool `json:"reused,omitempty"` // copied from the run a retry resumed, not run again
 }
 
+// Run triggers, recording what started a WorkflowExecution
+const (
+	RunTriggerCron   = "cron"   // the workflow's schedule
+	RunTriggerManual = "manual" // run on demand, e.g. by the run command or ExecuteWorkflowNow
+	RunTriggerEvent  = "event"  // an upstream workflow's triggered_by
+)
+
 // WorkflowExecution represents the execution state of a workflow
 type WorkflowExecution struct {
+	ID            string    `json:"id,omitempty"`             // sortable unique run ID (ULID)
+	ParentID      string    `json:"parent_id,omitempty"`      // run that invoked this one as a sub-workflow
+	RetryOf       string    `json:"retry_of,omitempty"`       // run this one retried, reusing its succeeded tasks
+	Trigger       string    `json:"trigger,omitempty"`        // cron, manual or event
+	ScheduledTime time.Time `json:"scheduled_time,omitempty"` // schedule time the run is for, if any
+	Attempt       int       `json:"attempt,omitempty"`        // 1 for the first run, one more for each retry
+	Host          string    `json:"host,omitempty"`
+	PID           int       `json:"pid,omitempty"`
+
 	WorkflowID   string            `json:"workflow_id"`
 	StartTime    time.Time         `json:"start_time"`
 	EndTime      time.Time         `json:"end_time"`
 	Duration     time.Duration     `json:"duration"`
-	Status       string            `json:"status"` // running, completed, failed
+	Status       string            `json:"status"` // running, completed, failed, cancelled
 	TaskResults  []ExecutionResult `json:"task_results"`
 	ErrorMessage string            `json:"error_message,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+	TriggeredBy  string            `json:"triggered_by,omitempty"` // upstream workflow that fired this run
+	Chain        []string          `json:"chain,omitempty"`        // upstream workflows, oldest first
+
+	SLAViolations []SLAViolation `json:"sla_violations,omitempty"` // breaches of the workflow's and its tasks' SLAs
+}
+
+// FailedTask returns the ID of the first task that failed in the run, or ""
+func (e WorkflowExecution) FailedTask() string {
+	for _, result := range e.TaskResults {
+		if !result.Success {
+			return result.TaskID
+		}
+	}
+	return ""
 }
 
 // ExecutionReport represents the complete execution report
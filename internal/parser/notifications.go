@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Notifications lists the channels notified when a workflow's runs fail,
+// succeed, recover, retry a task or miss their SLA
+type Notifications struct {
+	OnFailure  []NotificationChannel `yaml:"on_failure,omitempty"`
+	OnSuccess  []NotificationChannel `yaml:"on_success,omitempty"`
+	OnRecovery []NotificationChannel `yaml:"on_recovery,omitempty"` // first success after a failure
+	OnRetry    []NotificationChannel `yaml:"on_retry,omitempty"`    // a task attempt failed and will be retried
+	OnSLAMiss  []NotificationChannel `yaml:"on_sla_miss,omitempty"` // the workflow or a task breached its SLA
+	Dedup      string                `yaml:"dedup,omitempty"`       // suppress repeats on a channel for this long, default 1h
+}
+
+// Notification triggers, as used in Notifications
+const (
+	NotifyOnFailure  = "on_failure"
+	NotifyOnSuccess  = "on_success"
+	NotifyOnRecovery = "on_recovery"
+	NotifyOnRetry    = "on_retry"
+	NotifyOnSLAMiss  = "on_sla_miss"
+)
+
+// DefaultNotificationDedup is how long repeated notifications are suppressed by default
+const DefaultNotificationDedup = time.Hour
+
+// Channels returns the channels of a trigger
+func (n *Notifications) Channels(trigger string) []NotificationChannel {
+	if n == nil {
+		return nil
+	}
+	switch trigger {
+	case NotifyOnFailure:
+		return n.OnFailure
+	case NotifyOnSuccess:
+		return n.OnSuccess
+	case NotifyOnRecovery:
+		return n.OnRecovery
+	case NotifyOnRetry:
+		return n.OnRetry
+	case NotifyOnSLAMiss:
+		return n.OnSLAMiss
+	}
+	return nil
+}
+
+// DedupWindow returns how long repeated notifications are suppressed
+func (n *Notifications) DedupWindow() time.Duration {
+	if n == nil || n.Dedup == "" {
+		return DefaultNotificationDedup
+	}
+	window, err := time.ParseDuration(n.Dedup)
+	if err != nil {
+		return DefaultNotificationDedup
+	}
+	return window
+}
+
+// NotificationChannel is one destination of a notification. Exactly one of
+// email, webhook and command is set.
+type NotificationChannel struct {
+	Email   *EmailChannel   `yaml:"email,omitempty"`
+	Webhook *WebhookChannel `yaml:"webhook,omitempty"`
+	Command string          `yaml:"command,omitempty"` // run with the message on stdin
+
+	Subject string `yaml:"subject,omitempty"` // template of the email subject or webhook title
+	Message string `yaml:"message,omitempty"` // template of the message body
+}
+
+// EmailChannel sends notifications over SMTP. Username and password may
+// reference environment variables as $NAME or ${NAME}.
+type EmailChannel struct {
+	SMTP     string   `yaml:"smtp"` // host:port
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// WebhookChannel posts notifications as JSON. The URL and header values may
+// reference environment variables as $NAME or ${NAME}.
+type WebhookChannel struct {
+	URL     string            `yaml:"url"`
+	Format  string            `yaml:"format,omitempty"` // json (default), slack, teams or discord
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Webhook formats accepted by WebhookChannel
+const (
+	WebhookJSON    = "json"
+	WebhookSlack   = "slack"
+	WebhookTeams   = "teams"
+	WebhookDiscord = "discord"
+)
+
+// validateNotifications checks the notifications of a workflow or the
+// configuration at path
+func validateNotifications(notifications *Notifications, node *yaml.Node, pos Position, path string, v *validator) {
+	if notifications == nil {
+		return
+	}
+	notificationsNode := mappingValue(node, "notifications")
+	notificationsPos := fieldPos(node, pos, "notifications")
+	path = joinPath(path, "notifications")
+
+	if notifications.Dedup != "" {
+		if window, err := time.ParseDuration(notifications.Dedup); err != nil || window < 0 {
+			v.addf(fieldPos(notificationsNode, notificationsPos, "dedup"), path+".dedup", "invalid duration '%s'", notifications.Dedup)
+		}
+	}
+
+	for _, trigger := range []string{NotifyOnFailure, NotifyOnSuccess, NotifyOnRecovery, NotifyOnRetry, NotifyOnSLAMiss} {
+		for i, channel := range notifications.Channels(trigger) {
+			channelPath := fmt.Sprintf("%s.%s[%d]", path, trigger, i)
+			channelNode := itemNode(notificationsNode, trigger, i)
+			channelPos := itemPos(notificationsNode, notificationsPos, trigger, i)
+			validateNotificationChannel(channel, channelNode, channelPos, channelPath, v)
+		}
+	}
+}
+
+func validateNotificationChannel(channel NotificationChannel, node *yaml.Node, pos Position, path string, v *validator) {
+	count := 0
+	if channel.Email != nil {
+		count++
+	}
+	if channel.Webhook != nil {
+		count++
+	}
+	if channel.Command != "" {
+		count++
+	}
+	if count != 1 {
+		v.addf(pos, path, "exactly one of email, webhook or command is required")
+	}
+
+	if email := channel.Email; email != nil {
+		emailPos := fieldPos(node, pos, "email")
+		if email.SMTP == "" {
+			v.addf(emailPos, path+".email.smtp", "smtp is required")
+		}
+		if email.From == "" {
+			v.addf(emailPos, path+".email.from", "from is required")
+		}
+		if len(email.To) == 0 {
+			v.addf(emailPos, path+".email.to", "at least one recipient is required")
+		}
+	}
+
+	if webhook := channel.Webhook; webhook != nil {
+		webhookPos := fieldPos(node, pos, "webhook")
+		if webhook.URL == "" {
+			v.addf(webhookPos, path+".webhook.url", "url is required")
+		}
+		switch webhook.Format {
+		case "", WebhookJSON, WebhookSlack, WebhookTeams, WebhookDiscord:
+		default:
+			v.addf(fieldPos(mappingValue(node, "webhook"), webhookPos, "format"), path+".webhook.format",
+				"invalid format '%s' (expected json, slack, teams or discord)", webhook.Format)
+		}
+	}
+
+	for _, field := range []struct{ key, text string }{{"subject", channel.Subject}, {"message", channel.Message}} {
+		if field.text == "" {
+			continue
+		}
+		if _, err := template.New(field.key).Parse(field.text); err != nil {
+			v.addf(fieldPos(node, pos, field.key), path+"."+field.key, "invalid template: %v", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYAMLParser_ParseBytes_Notifications(t *testing.T) {
+	yamlContent := `version: "1.0"
+notifications:
+  dedup: 30m
+  on_failure:
+    - email:
+        smtp: mail.example.com:587
+        username: $SMTP_USER
+        password: $SMTP_PASSWORD
+        from: goliteflow@example.com
+        to: [ops@example.com]
+workflows:
+  - name: backup
+    schedule: "*/5 * * * *"
+    notifications:
+      on_recovery:
+        - webhook:
+            url: $SLACK_WEBHOOK
+            format: slack
+      on_retry:
+        - command: ./page.sh
+          message: "{{ .Task }} failed: {{ .Error }}"
+    tasks:
+      - id: dump
+        command: pg_dump db
+        retry: 2
+`
+
+	config, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	if got := config.Notifications.DedupWindow(); got != 30*time.Minute {
+		t.Errorf("Expected a 30m dedup window, got %v", got)
+	}
+	if email := config.Notifications.OnFailure[0].Email; email == nil || email.To[0] != "ops@example.com" {
+		t.Errorf("Unexpected email channel: %+v", email)
+	}
+
+	notifications := config.Workflows[0].Notifications
+	if notifications.DedupWindow() != DefaultNotificationDedup {
+		t.Errorf("Expected the default dedup window, got %v", notifications.DedupWindow())
+	}
+	if len(notifications.Channels(NotifyOnRecovery)) != 1 || notifications.OnRecovery[0].Webhook.Format != WebhookSlack {
+		t.Errorf("Unexpected recovery channels: %+v", notifications.OnRecovery)
+	}
+	if len(notifications.Channels(NotifyOnFailure)) != 0 || notifications.OnRetry[0].Command != "./page.sh" {
+		t.Errorf("Unexpected channels: %+v", notifications)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidNotifications(t *testing.T) {
+	yamlContent := `version: "1.0"
+notifications:
+  dedup: soon
+workflows:
+  - name: backup
+    schedule: "*/5 * * * *"
+    notifications:
+      on_failure:
+        - command: ./page.sh
+          webhook:
+            url: https://hooks.example.com
+        - email:
+            smtp: mail.example.com:25
+        - webhook:
+            url: https://hooks.example.com
+          message: "{{ .Workflow "
+    tasks:
+      - id: dump
+        command: pg_dump db
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"notifications.dedup",
+		"workflows[0].notifications.on_failure[0]",
+		"workflows[0].notifications.on_failure[1].email.from",
+		"workflows[0].notifications.on_failure[1].email.to",
+		"workflows[0].notifications.on_failure[2].message",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
+
+func TestYAMLParser_ParseFile_NotificationsInInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("main.yml", `version: "1.0"
+include: [extra.yml]
+workflows: []
+`)
+	writeFile("extra.yml", `version: "1.0"
+notifications:
+  on_failure:
+    - command: ./page.sh
+workflows:
+  - name: backup
+    schedule: "@daily"
+    tasks:
+      - id: dump
+        command: pg_dump db
+`)
+
+	_, err := NewYAMLParser().ParseFile(filepath.Join(dir, "main.yml"))
+	if err == nil || !strings.Contains(err.Error(), "notifications can only be set in the root configuration") {
+		t.Fatalf("Expected notifications in an included file to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validatePools checks the pools and concurrency limit of the root
+// configuration and the pools the tasks take slots of
+func validatePools(config *WorkflowConfig, v *validator) {
+	if config.MaxConcurrentTasks < 0 {
+		v.addf(fieldPos(config.node, Position{}, "max_concurrent_tasks"), "max_concurrent_tasks",
+			"max_concurrent_tasks must not be negative (0 means no limit)")
+	}
+
+	names := make([]string, 0, len(config.Pools))
+	for name := range config.Pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	poolsNode := mappingValue(config.node, "pools")
+	for _, name := range names {
+		pos := fieldPos(poolsNode, fieldPos(config.node, Position{}, "pools"), name)
+		if !ValidLabel(name) {
+			v.addf(pos, "pools."+name, "invalid pool name '%s' (expected letters, digits, '_', '.' and '-')", name)
+		}
+		if config.Pools[name].Slots < 1 {
+			v.addf(fieldPos(mappingValue(poolsNode, name), pos, "slots"), "pools."+name+".slots", "slots must be at least 1")
+		}
+	}
+
+	for i, workflow := range config.Workflows {
+		for j, task := range workflow.Tasks {
+			validateTaskPool(task, config.Pools, task.node, task.Pos, fmt.Sprintf("workflows[%d].tasks[%d]", i, j), v)
+		}
+	}
+}
+
+// validateTaskPool checks that a task takes slots of a pool that exists and
+// has that many
+func validateTaskPool(task Task, pools map[string]Pool, node *yaml.Node, pos Position, path string, v *validator) {
+	if task.Pool == "" {
+		if task.PoolSlots != 0 {
+			v.addf(fieldPos(node, pos, "pool_slots"), path+".pool_slots", "pool_slots requires pool")
+		}
+		return
+	}
+	if task.Workflow != "" {
+		v.addf(fieldPos(node, pos, "pool"), path+".pool", "pool does not apply to sub-workflow tasks; set it on the tasks of the child workflow")
+		return
+	}
+
+	pool, ok := pools[task.Pool]
+	if !ok {
+		v.addf(fieldPos(node, pos, "pool"), path+".pool", "pool '%s' not found in pools", task.Pool)
+		return
+	}
+	if task.PoolSlots < 0 {
+		v.addf(fieldPos(node, pos, "pool_slots"), path+".pool_slots", "pool_slots must be at least 1")
+	} else if task.PoolSlots > pool.Slots && pool.Slots > 0 {
+		v.addf(fieldPos(node, pos, "pool_slots"), path+".pool_slots",
+			"pool_slots %d exceeds the %d slot(s) of pool '%s'", task.PoolSlots, pool.Slots, task.Pool)
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_Pools(t *testing.T) {
+	yamlContent := `version: "1.0"
+max_concurrent_tasks: 8
+pools:
+  db: {slots: 4}
+workflows:
+  - name: hourly-sync
+    schedule: "0 * * * *"
+    tasks:
+      - id: load
+        command: ./load.sh
+        pool: db
+        pool_slots: 2
+`
+
+	config, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	if config.MaxConcurrentTasks != 8 || config.Pools["db"].Slots != 4 {
+		t.Errorf("Unexpected limits: max_concurrent_tasks %d, pools %v", config.MaxConcurrentTasks, config.Pools)
+	}
+	if load := config.Workflows[0].Tasks[0]; load.Pool != "db" || load.PoolSlots != 2 {
+		t.Errorf("Unexpected task pool: %+v", load)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidPools(t *testing.T) {
+	yamlContent := `version: "1.0"
+max_concurrent_tasks: -1
+pools:
+  db: {slots: 0}
+  api: {slots: 2}
+workflows:
+  - name: child
+    tasks:
+      - id: step
+        command: echo step
+  - name: hourly-sync
+    schedule: "0 * * * *"
+    tasks:
+      - id: unknown
+        command: ./load.sh
+        pool: cache
+      - id: too-many
+        command: ./call.sh
+        pool: api
+        pool_slots: 3
+      - id: no-pool
+        command: ./call.sh
+        pool_slots: 1
+      - id: nested
+        workflow: child
+        pool: api
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"max_concurrent_tasks",
+		"pools.db.slots",
+		"workflows[1].tasks[0].pool",
+		"workflows[1].tasks[1].pool_slots",
+		"workflows[1].tasks[2].pool_slots",
+		"workflows[1].tasks[3].pool",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resources limits what a task's process may consume. Zero values mean no limit.
+type Resources struct {
+	MemoryLimit  string `yaml:"memory_limit,omitempty"`  // e.g. "512Mi" or "2G"
+	CPUTime      string `yaml:"cpu_time,omitempty"`      // total CPU time, e.g. "10m"
+	MaxProcesses int    `yaml:"max_processes,omitempty"` // processes and threads
+	Nofile       int    `yaml:"nofile,omitempty"`        // open file descriptors
+}
+
+// IsZero reports whether no limit is set
+func (r *Resources) IsZero() bool {
+	return r == nil || *r == Resources{}
+}
+
+// WithDefaults returns the limits of r, falling back field by field to
+// defaults, typically the workflow-level resources
+func (r *Resources) WithDefaults(defaults *Resources) *Resources {
+	if defaults.IsZero() {
+		return r
+	}
+	if r.IsZero() {
+		merged := *defaults
+		return &merged
+	}
+
+	merged := *r
+	if merged.MemoryLimit == "" {
+		merged.MemoryLimit = defaults.MemoryLimit
+	}
+	if merged.CPUTime == "" {
+		merged.CPUTime = defaults.CPUTime
+	}
+	if merged.MaxProcesses == 0 {
+		merged.MaxProcesses = defaults.MaxProcesses
+	}
+	if merged.Nofile == 0 {
+		merged.Nofile = defaults.Nofile
+	}
+	return &merged
+}
+
+// memoryPattern matches a byte count with an optional decimal (K, M, G, T)
+// or binary (Ki, Mi, Gi, Ti) suffix
+var memoryPattern = regexp.MustCompile(`^([0-9]+)(K|M|G|T|Ki|Mi|Gi|Ti)?$`)
+
+var memoryUnits = map[string]int64{
+	"":   1,
+	"K":  1000,
+	"M":  1000 * 1000,
+	"G":  1000 * 1000 * 1000,
+	"T":  1000 * 1000 * 1000 * 1000,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// ParseMemory converts a memory size such as "512Mi" to bytes
+func ParseMemory(value string) (int64, error) {
+	match := memoryPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("expected a number of bytes with an optional K, M, G, T, Ki, Mi, Gi or Ti suffix")
+	}
+	amount, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	unit := memoryUnits[match[2]]
+	if amount > (1<<63-1)/unit {
+		return 0, fmt.Errorf("value out of range")
+	}
+	return amount * unit, nil
+}
+
+// validateResources checks the limits of a task or workflow at path
+func validateResources(resources *Resources, node *yaml.Node, pos Position, path string, v *validator) {
+	if resources == nil {
+		return
+	}
+	resNode := mappingValue(node, "resources")
+	resPos := fieldPos(node, pos, "resources")
+
+	if resources.MemoryLimit != "" {
+		if bytes, err := ParseMemory(resources.MemoryLimit); err != nil {
+			v.addf(fieldPos(resNode, resPos, "memory_limit"), path+".resources.memory_limit", "invalid memory limit '%s': %v", resources.MemoryLimit, err)
+		} else if bytes == 0 {
+			v.addf(fieldPos(resNode, resPos, "memory_limit"), path+".resources.memory_limit", "memory limit must be greater than zero")
+		}
+	}
+	if resources.CPUTime != "" {
+		if cpu, err := time.ParseDuration(resources.CPUTime); err != nil {
+			v.addf(fieldPos(resNode, resPos, "cpu_time"), path+".resources.cpu_time", "invalid duration '%s': %v", resources.CPUTime, err)
+		} else if cpu < time.Second {
+			v.addf(fieldPos(resNode, resPos, "cpu_time"), path+".resources.cpu_time", "cpu time must be at least 1s")
+		}
+	}
+	if resources.MaxProcesses < 0 {
+		v.addf(fieldPos(resNode, resPos, "max_processes"), path+".resources.max_processes", "max_processes cannot be negative")
+	}
+	if resources.Nofile < 0 {
+		v.addf(fieldPos(resNode, resPos, "nofile"), path+".resources.nofile", "nofile cannot be negative")
+	}
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"512Mi", 512 << 20, false},
+		{"2G", 2000000000, false},
+		{"1Ti", 1 << 40, false},
+		{"64K", 64000, false},
+		{"1.5G", 0, true},
+		{"512MB", 0, true},
+		{"-1", 0, true},
+		{"99999999999Ti", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseMemory(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMemory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMemory() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResources_WithDefaults(t *testing.T) {
+	defaults := &Resources{MemoryLimit: "1Gi", Nofile: 256}
+
+	merged := (&Resources{MemoryLimit: "256Mi", CPUTime: "1m"}).WithDefaults(defaults)
+	expected := Resources{MemoryLimit: "256Mi", CPUTime: "1m", Nofile: 256}
+	if *merged != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *merged)
+	}
+
+	var none *Resources
+	if merged := none.WithDefaults(defaults); *merged != *defaults || merged == defaults {
+		t.Errorf("Expected a copy of the defaults, got %+v", merged)
+	}
+	if merged := none.WithDefaults(nil); merged != nil {
+		t.Errorf("Expected nil, got %+v", merged)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidResources(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: limited
+    schedule: "0 0 * * *"
+    resources:
+      memory_limit: 1.5G
+    tasks:
+      - id: task1
+        command: echo hello
+        resources:
+          cpu_time: 500ms
+          nofile: -1
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].resources.memory_limit",
+		"workflows[0].tasks[0].resources.cpu_time",
+		"workflows[0].tasks[0].resources.nofile",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is a JSON Schema (draft-07) document or subschema
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false or *Schema
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+
+	pattern *regexp.Regexp // Pattern compiled once when the schema is generated
+}
+
+// schemaHint documents a field and narrows the values it accepts
+type schemaHint struct {
+	description string
+	enum        []string
+	pattern     string
+	minimum     *int
+}
+
+// durationPattern matches Go duration strings such as "30s" or "1h30m"
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+var zero, one = 0, 1
+
+// schemaHints are keyed by struct name and yaml key
+var schemaHints = map[string]schemaHint{
+	"WorkflowConfig.version":              {description: "Configuration format version, e.g. \"1.0\""},
+	"WorkflowConfig.include":              {description: "Files or glob patterns merged into this configuration, relative to this file"},
+	"WorkflowConfig.task_templates":       {description: "Reusable task definitions that tasks inherit from with extends"},
+	"WorkflowConfig.workflows":            {description: "Workflow definitions"},
+	"WorkflowConfig.notifications":        {description: "Notifications for every workflow; workflow-level notifications are sent in addition"},
+	"WorkflowConfig.pools":                {description: "Named pools of slots, e.g. db: {slots: 4}; tasks with pool take slots while they run"},
+	"WorkflowConfig.max_concurrent_tasks": {description: "Tasks running at once across all workflows; 0 means no limit", minimum: &zero},
+	"Pool.slots":                          {description: "Number of slots; tasks wait while the pool is full", minimum: &one},
+
+	"Workflow.name":          {description: "Unique workflow name"},
+	"Workflow.schedule":      {description: "Cron expression (minute hour day month weekday) or descriptor such as @daily"},
+	"Workflow.triggered_by":  {description: "Upstream workflows whose completion starts this workflow"},
+	"Workflow.params":        {description: "Default parameters, available to commands as {{ .Params.name }}"},
+	"Workflow.resources":     {description: "Default resource limits for every task; task-level values take precedence"},
+	"Workflow.run_as":        {description: "Default user and group of every task; a task-level run_as replaces it"},
+	"Workflow.sandbox":       {description: "Default sandbox of every task; a task-level sandbox replaces it"},
+	"Workflow.runs_on":       {description: "Default worker labels of every task; a task-level runs_on replaces it"},
+	"Workflow.notifications": {description: "Channels notified when runs fail, succeed, recover or retry a task"},
+	"Workflow.sla":           {description: "Expected duration, deadline and reliability of runs; breaches fire events and on_sla_miss notifications"},
+	"Workflow.tasks":         {description: "Tasks executed in dependency order"},
+
+	"WorkflowTrigger.workflow": {description: "Name of the upstream workflow"},
+	"WorkflowTrigger.status": {
+		description: "Upstream status that fires the trigger (default completed)",
+		enum:        []string{TriggerOnCompleted, TriggerOnFailed, TriggerOnAny},
+	},
+
+	"Task.id":         {description: "Task identifier, unique within the workflow"},
+	"Task.command":    {description: "Shell command to execute"},
+	"Task.retry":      {description: "Total number of attempts; 0 and 1 both run the task once", minimum: &zero},
+	"Task.depends_on": {description: "IDs of tasks that must succeed first"},
+	"Task.timeout":    {description: "Maximum duration of all attempts together, e.g. \"30s\" or \"5m\"", pattern: durationPattern},
+	"Task.wait_for":   {description: "Wait for a successful run of another workflow before running"},
+	"Task.workflow":   {description: "Run another workflow as a child run instead of a command"},
+	"Task.params":     {description: "Parameters passed to the child workflow"},
+	"Task.extends":    {description: "Name of a task template to inherit from"},
+	"Task.resources":  {description: "Limits applied to the command's process"},
+	"Task.run_as":     {description: "User and group the command runs as; requires root unless it is the current user"},
+	"Task.sandbox":    {description: "Isolate the command's process; namespaces require root"},
+	"Task.runs_on":    {description: "Labels a worker must have to run the task, e.g. [gpu-free, etl]; without labels the scheduler runs it"},
+	"Task.pool":       {description: "Pool from pools the task takes slots of while it runs"},
+	"Task.pool_slots": {description: "Slots of the pool the task takes, default 1", minimum: &one},
+
+	"Task.image":     {description: "Run the command in a container of this image; without a command the image's default command runs"},
+	"Task.container": {description: "Runtime, mounts, env and working directory of the container"},
+
+	"Task.http": {description: "Send an HTTP request instead of running a command"},
+	"Task.func": {description: "Call a Go function registered with RegisterTaskFunc by the embedding application"},
+	"Task.sla":  {description: "Expected duration, deadline and reliability of the task"},
+
+	"SLA.max_duration":             {description: "Longest a run may take, e.g. \"45m\"", pattern: durationPattern},
+	"SLA.must_finish_by":           {description: "Local time of day (HH:MM) by which a run must finish; on the day the run is scheduled for, or the next day for a later schedule time", pattern: "^([01]?[0-9]|2[0-3]):[0-5][0-9]$"},
+	"SLA.max_consecutive_failures": {description: "Failed runs in a row before the SLA is breached", minimum: &zero},
+
+	"HTTPRequest.method":  {description: "Request method (default GET)"},
+	"HTTPRequest.url":     {description: "Request URL; {{ .Params.name }} and {{ .Outputs.task.name }} are expanded"},
+	"HTTPRequest.headers": {description: "Request headers"},
+	"HTTPRequest.body":    {description: "Request body"},
+	"HTTPRequest.auth":    {description: "Basic credentials or a bearer token; $NAME references environment variables"},
+	"HTTPRequest.expect":  {description: "Checks the response must pass (default any 2xx status)"},
+	"HTTPRequest.save_to": {description: "File the response body is written to"},
+	"HTTPRequest.output":  {description: "Task output the response body is stored in"},
+	"HTTPRequest.outputs": {description: "Task outputs extracted from the JSON response, by path"},
+
+	"HTTPAuth.username": {description: "Basic auth user"},
+	"HTTPAuth.password": {description: "Basic auth password"},
+	"HTTPAuth.bearer":   {description: "Bearer token"},
+
+	"HTTPExpect.status": {description: "Accepted status codes"},
+	"HTTPExpect.json":   {description: "Assertions on the JSON response"},
+
+	"JSONAssertion.path":   {description: "Path such as data.items[0].id"},
+	"JSONAssertion.equals": {description: "Expected value; strings compare as-is, other values as JSON"},
+	"JSONAssertion.exists": {description: "Whether the path must exist"},
+
+	"Notifications.on_failure":  {description: "Channels notified when a run fails"},
+	"Notifications.on_success":  {description: "Channels notified when a run succeeds"},
+	"Notifications.on_recovery": {description: "Channels notified on the first success after a failure"},
+	"Notifications.on_retry":    {description: "Channels notified when a task attempt fails and will be retried"},
+	"Notifications.on_sla_miss": {description: "Channels notified when the workflow or a task breaches its SLA"},
+	"Notifications.dedup":       {description: "Suppress repeated notifications on a channel for this long, default 1h; 0s disables", pattern: durationPattern},
+
+	"NotificationChannel.email":   {description: "Send an email over SMTP"},
+	"NotificationChannel.webhook": {description: "POST to a webhook"},
+	"NotificationChannel.command": {description: "Run a command with the message on stdin"},
+	"NotificationChannel.subject": {description: "Template of the subject, e.g. \"{{ .Workflow }} {{ .Status }}\""},
+	"NotificationChannel.message": {description: "Template of the message; fields include .Workflow, .Status, .Error, .Duration and .Execution"},
+
+	"EmailChannel.smtp":     {description: "SMTP server as host:port"},
+	"EmailChannel.username": {description: "SMTP user; $NAME references environment variables"},
+	"EmailChannel.password": {description: "SMTP password; $NAME references environment variables"},
+	"EmailChannel.from":     {description: "Sender address"},
+	"EmailChannel.to":       {description: "Recipient addresses"},
+
+	"WebhookChannel.url":     {description: "Webhook URL; $NAME references environment variables"},
+	"WebhookChannel.format":  {description: "Payload format (default json)", enum: []string{WebhookJSON, WebhookSlack, WebhookTeams, WebhookDiscord}},
+	"WebhookChannel.headers": {description: "Request headers"},
+
+	"Container.runtime": {description: "Container CLI, e.g. docker (default) or podman"},
+	"Container.mounts":  {description: "Bind mounts as host:container[:options]; relative host paths are resolved from the working directory"},
+	"Container.env":     {description: "Environment variables set in the container"},
+	"Container.workdir": {description: "Absolute working directory inside the container"},
+
+	"RunAs.user":  {description: "User name or numeric uid"},
+	"RunAs.group": {description: "Group name or numeric gid, default the user's primary group"},
+
+	"Sandbox.private_tmp": {description: "Mount an empty /tmp only visible to the task"},
+	"Sandbox.read_only":   {description: "Absolute paths bound read-only"},
+	"Sandbox.no_network":  {description: "Run in a network namespace with no connectivity"},
+	"Sandbox.umask":       {description: "Octal file mode creation mask, e.g. \"0077\"", pattern: "^0?[0-7]{1,3}$"},
+
+	"Resources.memory_limit":  {description: "Maximum memory, e.g. \"512Mi\" or \"2G\"", pattern: memoryPattern.String()},
+	"Resources.cpu_time":      {description: "Total CPU time before the process is killed, e.g. \"10m\"", pattern: durationPattern},
+	"Resources.max_processes": {description: "Maximum number of processes", minimum: &zero},
+	"Resources.nofile":        {description: "Maximum number of open files", minimum: &zero},
+
+	"ExternalDependency.workflow":      {description: "Name of the workflow to wait for"},
+	"ExternalDependency.within":        {description: "Maximum age of the successful run, e.g. \"24h\"", pattern: durationPattern},
+	"ExternalDependency.poll_interval": {description: "How often to check, default 30s", pattern: durationPattern},
+}
+
+// schemaRequired lists the fields every file must set. Fields whose presence
+// depends on other fields, such as command, are left to ValidateConfig.
+var schemaRequired = map[string][]string{
+	"WorkflowConfig":     {"version", "workflows"},
+	"Workflow":           {"name", "tasks"},
+	"WorkflowTrigger":    {"workflow"},
+	"ExternalDependency": {"workflow"},
+	"RunAs":              {"user"},
+	"HTTPRequest":        {"url"},
+	"JSONAssertion":      {"path"},
+	"EmailChannel":       {"smtp", "from", "to"},
+	"WebhookChannel":     {"url"},
+	"Pool":               {"slots"},
+}
+
+// GenerateSchema builds the JSON Schema of the configuration file from the
+// WorkflowConfig, Workflow and Task structs
+func GenerateSchema() *Schema {
+	definitions := make(map[string]*Schema)
+	root := structSchema(reflect.TypeOf(WorkflowConfig{}), definitions)
+	root.Schema = "http://json-schema.org/draft-07/schema#"
+	root.Title = "GoliteFlow workflow configuration"
+	root.Definitions = definitions
+	return root
+}
+
+// SchemaJSON returns the configuration schema as indented JSON
+func SchemaJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(GenerateSchema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func structSchema(t reflect.Type, definitions map[string]*Schema) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		Required:             schemaRequired[t.Name()],
+		AdditionalProperties: false,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		property := typeSchema(field.Type, definitions)
+		if hint, ok := schemaHints[t.Name()+"."+name]; ok {
+			property.Description = hint.description
+			property.Enum = hint.enum
+			property.Pattern = hint.pattern
+			if hint.pattern != "" {
+				property.pattern = regexp.MustCompile(hint.pattern)
+			}
+			property.Minimum = hint.minimum
+		}
+		schema.Properties[name] = property
+	}
+
+	return schema
+}
+
+func typeSchema(t reflect.Type, definitions map[string]*Schema) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			definitions[t.Name()] = &Schema{} // placeholder for recursive types
+			definitions[t.Name()] = structSchema(t, definitions)
+		}
+		return &Schema{Ref: "#/definitions/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+// configSchema is the schema every loaded file is checked against
+var configSchema = GenerateSchema()
+
+// fileSchema checks a single file. Workflows are checked one by one after
+// includes are merged, so their paths match the merged configuration.
+var fileSchema = func() *Schema {
+	schema := *configSchema
+	schema.Properties = make(map[string]*Schema, len(configSchema.Properties))
+	for name, property := range configSchema.Properties {
+		schema.Properties[name] = property
+	}
+	schema.Properties["workflows"] = &Schema{Type: "array"}
+	return &schema
+}()
+
+// workflowSchema is the definition each workflow is checked against
+var workflowSchema = configSchema.Definitions["Workflow"]
+
+// checkSchema reports nodes that do not match schema: unknown fields, wrong
+// types, values outside an enum or pattern. Required fields are not checked
+// here since templates and includes may supply them.
+func (v *validator) checkSchema(node *yaml.Node, schema *Schema, path, file string) {
+	if node == nil || schema == nil {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if schema.Ref != "" {
+		schema = configSchema.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	pos := nodePosition(node, file)
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.schemaMismatch(pos, path, schema.Type, node)
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := joinPath(path, key.Value)
+
+			if property, ok := schema.Properties[key.Value]; ok {
+				v.checkSchema(value, property, fieldPath, file)
+				continue
+			}
+			if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+				v.checkSchema(value, additional, fieldPath, file)
+				continue
+			}
+
+			message := fmt.Sprintf("unknown field '%s'", key.Value)
+			if suggestion := closestKey(key.Value, schema.Properties); suggestion != "" {
+				message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+			}
+			v.addSchemaError(nodePosition(key, file), fieldPath, "%s", message)
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.schemaMismatch(pos, path, schema.Type, node)
+			return
+		}
+		for i, item := range node.Content {
+			v.checkSchema(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), file)
+		}
+	case "string":
+		// Like the decoder, any scalar is accepted as a string
+		if node.Kind != yaml.ScalarNode {
+			v.schemaMismatch(pos, path, schema.Type, node)
+			return
+		}
+		if len(schema.Enum) > 0 && !containsValue(schema.Enum, node.Value) {
+			v.addSchemaError(pos, path, "invalid value '%s' (expected one of %s)", node.Value, strings.Join(schema.Enum, ", "))
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(node.Value) {
+			v.addSchemaError(pos, path, "value '%s' does not match pattern %s", node.Value, schema.Pattern)
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			v.schemaMismatch(pos, path, schema.Type, node)
+			return
+		}
+		var value int
+		if err := node.Decode(&value); err == nil && schema.Minimum != nil && value < *schema.Minimum {
+			v.addSchemaError(pos, path, "value %d is less than the minimum of %d", value, *schema.Minimum)
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			v.schemaMismatch(pos, path, schema.Type, node)
+		}
+	}
+}
+
+func (v *validator) schemaMismatch(pos Position, path, expected string, node *yaml.Node) {
+	v.addSchemaError(pos, path, "expected %s, got %s", expected, nodeTypeName(node))
+}
+
+func (v *validator) addSchemaError(pos Position, path, format string, args ...interface{}) {
+	v.schemaErrors++
+	v.addf(pos, path, format, args...)
+}
+
+// nodeTypeName names the JSON type of a yaml node
+func nodeTypeName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSchemaJSON_MatchesShippedSchema(t *testing.T) {
+	generated, err := SchemaJSON()
+	if err != nil {
+		t.Fatalf("SchemaJSON() error = %v", err)
+	}
+
+	shipped, err := os.ReadFile("../../schema/goliteflow.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read shipped schema: %v", err)
+	}
+
+	if !bytes.Equal(generated, shipped) {
+		t.Error("schema/goliteflow.schema.json is out of date, run `make schema`")
+	}
+}
+
+func TestGenerateSchema_CoversModelFields(t *testing.T) {
+	schema := GenerateSchema()
+
+	tests := []struct {
+		definition string
+		fields     []string
+	}{
+		{"Workflow", []string{"name", "schedule", "triggered_by", "params", "resources", "run_as", "sandbox", "runs_on", "notifications", "sla", "tasks"}},
+		{"Task", []string{"id", "command", "retry", "depends_on", "timeout", "wait_for", "workflow", "params", "extends", "resources", "run_as", "sandbox", "image", "container", "http", "func", "sla", "runs_on", "pool", "pool_slots"}},
+		{"Pool", []string{"slots"}},
+		{"WorkflowTrigger", []string{"workflow", "status"}},
+		{"ExternalDependency", []string{"workflow", "within", "poll_interval"}},
+		{"Resources", []string{"memory_limit", "cpu_time", "max_processes", "nofile"}},
+		{"RunAs", []string{"user", "group"}},
+		{"Container", []string{"runtime", "mounts", "env", "workdir"}},
+		{"HTTPRequest", []string{"method", "url", "headers", "body", "auth", "expect", "save_to", "output", "outputs"}},
+		{"Sandbox", []string{"private_tmp", "read_only", "no_network", "umask"}},
+		{"Notifications", []string{"on_failure", "on_success", "on_recovery", "on_retry", "on_sla_miss", "dedup"}},
+		{"NotificationChannel", []string{"email", "webhook", "command", "subject", "message"}},
+		{"EmailChannel", []string{"smtp", "username", "password", "from", "to"}},
+		{"WebhookChannel", []string{"url", "format", "headers"}},
+		{"SLA", []string{"max_duration", "must_finish_by", "max_consecutive_failures"}},
+	}
+
+	for _, tt := range tests {
+		definition, ok := schema.Definitions[tt.definition]
+		if !ok {
+			t.Errorf("Expected definition %s", tt.definition)
+			continue
+		}
+		if len(definition.Properties) != len(tt.fields) {
+			t.Errorf("%s: expected %d properties, got %d", tt.definition, len(tt.fields), len(definition.Properties))
+		}
+		for _, field := range tt.fields {
+			if _, ok := definition.Properties[field]; !ok {
+				t.Errorf("%s: expected property %s", tt.definition, field)
+			}
+		}
+	}
+}
+
+func TestYAMLParser_ParseBytes_SchemaViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		path    string
+		message string
+	}{
+		{
+			name: "wrong type",
+			yaml: `version: "1.0"
+workflows:
+  - name: test
+    schedule: "0 0 * * *"
+    tasks:
+      - id: task1
+        command: echo
+        retry: lots
+`,
+			path:    "workflows[0].tasks[0].retry",
+			message: "expected integer, got string",
+		},
+		{
+			name: "string instead of list",
+			yaml: `version: "1.0"
+workflows:
+  - name: test
+    schedule: "0 0 * * *"
+    tasks:
+      - id: task1
+        command: echo
+      - id: task2
+        command: echo
+        depends_on: task1
+`,
+			path:    "workflows[0].tasks[1].depends_on",
+			message: "expected array, got string",
+		},
+		{
+			name: "unknown template field",
+			yaml: `version: "1.0"
+task_templates:
+  base:
+    comand: echo
+workflows:
+  - name: test
+    schedule: "0 0 * * *"
+    tasks:
+      - id: task1
+        command: echo
+`,
+			path:    "task_templates.base.comand",
+			message: "unknown field 'comand', did you mean 'command'?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewYAMLParser().ParseBytes([]byte(tt.yaml))
+
+			var problems ValidationErrors
+			if !errors.As(err, &problems) {
+				t.Fatalf("Expected ValidationErrors, got %v", err)
+			}
+			if len(problems) != 1 {
+				t.Fatalf("Expected exactly one problem, got %v", problems)
+			}
+			if problems[0].Path != tt.path || problems[0].Message != tt.message {
+				t.Errorf("Expected %s: %s, got %s: %s", tt.path, tt.message, problems[0].Path, problems[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SLA sets the service level a workflow or task is expected to meet.
+// Deadlines are checked while the run is going, so a breach is reported as
+// soon as it happens rather than when the run ends.
+type SLA struct {
+	MaxDuration            string `yaml:"max_duration,omitempty"`             // e.g. "45m"
+	MustFinishBy           string `yaml:"must_finish_by,omitempty"`           // local time of day as HH:MM
+	MaxConsecutiveFailures int    `yaml:"max_consecutive_failures,omitempty"` // failed runs in a row
+}
+
+// SLA violation types, as recorded in SLAViolation
+const (
+	SLAMaxDuration            = "max_duration"
+	SLAMustFinishBy           = "must_finish_by"
+	SLAMaxConsecutiveFailures = "max_consecutive_failures"
+)
+
+// SLAViolation records a breached SLA of a run or one of its tasks
+type SLAViolation struct {
+	Type    string    `json:"type"`
+	Task    string    `json:"task,omitempty"` // empty for the workflow's own SLA
+	Time    time.Time `json:"time"`           // when the breach was detected
+	Message string    `json:"message"`
+}
+
+// ParseTimeOfDay parses a 24-hour HH:MM time
+func ParseTimeOfDay(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day '%s' (expected HH:MM)", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Deadlines returns the deadlines of a run started at start for the schedule
+// time scheduled: start plus max_duration, and must_finish_by on the day of
+// scheduled. A run that starts late is therefore still held to the deadline
+// of its own day; only a deadline earlier in the day than scheduled, as for a
+// run that crosses midnight, moves to the next day. Runs without a schedule
+// time are anchored to their start.
+func (s *SLA) Deadlines(start, scheduled time.Time) map[string]time.Time {
+	deadlines := make(map[string]time.Time)
+	if s == nil {
+		return deadlines
+	}
+	if max, err := time.ParseDuration(s.MaxDuration); err == nil && max > 0 {
+		deadlines[SLAMaxDuration] = start.Add(max)
+	}
+	if hour, minute, err := ParseTimeOfDay(s.MustFinishBy); err == nil {
+		anchor := scheduled
+		if anchor.IsZero() {
+			anchor = start
+		}
+		deadline := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), hour, minute, 0, 0, anchor.Location())
+		if deadline.Before(anchor) {
+			deadline = deadline.AddDate(0, 0, 1)
+		}
+		deadlines[SLAMustFinishBy] = deadline
+	}
+	return deadlines
+}
+
+// validateSLA checks the sla of a workflow or task at path
+func validateSLA(sla *SLA, node *yaml.Node, pos Position, path string, v *validator) {
+	if sla == nil {
+		return
+	}
+	slaNode := mappingValue(node, "sla")
+	slaPos := fieldPos(node, pos, "sla")
+	path = joinPath(path, "sla")
+
+	if sla.MaxDuration == "" && sla.MustFinishBy == "" && sla.MaxConsecutiveFailures == 0 {
+		v.addf(slaPos, path, "at least one of max_duration, must_finish_by or max_consecutive_failures is required")
+	}
+	if sla.MaxDuration != "" {
+		if max, err := time.ParseDuration(sla.MaxDuration); err != nil || max <= 0 {
+			v.addf(fieldPos(slaNode, slaPos, "max_duration"), path+".max_duration", "invalid duration '%s'", sla.MaxDuration)
+		}
+	}
+	if sla.MustFinishBy != "" {
+		if _, _, err := ParseTimeOfDay(sla.MustFinishBy); err != nil {
+			v.addf(fieldPos(slaNode, slaPos, "must_finish_by"), path+".must_finish_by", "%v", err)
+		}
+	}
+	if sla.MaxConsecutiveFailures < 0 {
+		v.addf(fieldPos(slaNode, slaPos, "max_consecutive_failures"), path+".max_consecutive_failures",
+			"must not be negative, got %d", sla.MaxConsecutiveFailures)
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestYAMLParser_ParseBytes_InvalidSLA(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: nightly
+    schedule: "0 2 * * *"
+    sla:
+      max_duration: forever
+      must_finish_by: "25:00"
+    tasks:
+      - id: load
+        command: ./load.sh
+        sla: {}
+      - id: report
+        command: ./report.sh
+        sla:
+          max_consecutive_failures: -1
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[0].sla.max_duration",
+		"workflows[0].sla.must_finish_by",
+		"workflows[0].tasks[0].sla",
+		"workflows[0].tasks[1].sla.max_consecutive_failures",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
+
+func TestSLA_Deadlines(t *testing.T) {
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		sla       *SLA
+		scheduled time.Time
+		want      map[string]time.Time
+	}{
+		{name: "nil", sla: nil, want: map[string]time.Time{}},
+		{
+			name: "max duration",
+			sla:  &SLA{MaxDuration: "45m"},
+			want: map[string]time.Time{SLAMaxDuration: start.Add(45 * time.Minute)},
+		},
+		{
+			name: "later today",
+			sla:  &SLA{MustFinishBy: "06:00"},
+			want: map[string]time.Time{SLAMustFinishBy: time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "tomorrow",
+			sla:  &SLA{MustFinishBy: "01:30", MaxConsecutiveFailures: 3},
+			want: map[string]time.Time{SLAMustFinishBy: time.Date(2024, 3, 2, 1, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:      "started after the deadline of its day",
+			sla:       &SLA{MustFinishBy: "01:30"},
+			scheduled: time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC),
+			want:      map[string]time.Time{SLAMustFinishBy: time.Date(2024, 3, 1, 1, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:      "crosses midnight",
+			sla:       &SLA{MustFinishBy: "01:30"},
+			scheduled: time.Date(2024, 2, 29, 23, 0, 0, 0, time.UTC),
+			want:      map[string]time.Time{SLAMustFinishBy: time.Date(2024, 3, 1, 1, 30, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sla.Deadlines(start, tt.scheduled)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Deadlines() = %v, want %v", got, tt.want)
+			}
+			for kind, deadline := range tt.want {
+				if !got[kind].Equal(deadline) {
+					t.Errorf("Deadlines()[%s] = %v, want %v", kind, got[kind], deadline)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ResolveTemplates applies task_templates to every task that extends one.
+// Fields set on the task override the template field by field, params are
+// merged key by key. Templates may extend other templates. Every unresolvable
+// reference is reported; such tasks keep their extends field.
+func (p *YAMLParser) ResolveTemplates(config *WorkflowConfig) error {
+	v := &validator{}
+	resolved := make(map[string]Task)
+
+	var resolve func(name string, stack []string) (Task, error)
+	resolve = func(name string, stack []string) (Task, error) {
+		if task, ok := resolved[name]; ok {
+			return task, nil
+		}
+		for _, onStack := range stack {
+			if onStack == name {
+				return Task{}, fmt.Errorf("circular task template inheritance: %s", strings.Join(append(stack, name), " -> "))
+			}
+		}
+
+		template, ok := config.TaskTemplates[name]
+		if !ok {
+			return Task{}, fmt.Errorf("task template '%s' not found", name)
+		}
+
+		if template.Extends != "" {
+			base, err := resolve(template.Extends, append(stack, name))
+			if err != nil {
+				return Task{}, err
+			}
+			template = mergeTask(base, template)
+		}
+		template.Extends = ""
+
+		resolved[name] = template
+		return template, nil
+	}
+
+	names := make([]string, 0, len(config.TaskTemplates))
+	for name := range config.TaskTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := resolve(name, nil); err != nil {
+			template := config.TaskTemplates[name]
+			v.addf(fieldPos(template.node, template.Pos, "extends"), "task_templates."+name+".extends", "%v", err)
+		}
+	}
+
+	for i := range config.Workflows {
+		for j := range config.Workflows[i].Tasks {
+			task := &config.Workflows[i].Tasks[j]
+			if task.Extends == "" {
+				continue
+			}
+
+			template, err := resolve(task.Extends, nil)
+			if err != nil {
+				v.addf(fieldPos(task.node, task.Pos, "extends"), fmt.Sprintf("workflows[%d].tasks[%d].extends", i, j), "%v", err)
+				continue
+			}
+			*task = mergeTask(template, *task)
+			task.Extends = ""
+		}
+	}
+
+	return v.err()
+}
+
+// mergeTask returns base with every non-zero field of override applied on top
+func mergeTask(base, override Task) Task {
+	merged := base
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	overrideValue := reflect.ValueOf(override)
+
+	for i := 0; i < overrideValue.NumField(); i++ {
+		field := overrideValue.Field(i)
+		if overrideValue.Type().Field(i).PkgPath != "" || field.IsZero() {
+			continue // unexported fields are handled below
+		}
+
+		target := mergedValue.Field(i)
+		if field.Kind() == reflect.Map && !target.IsNil() {
+			combined := reflect.MakeMap(field.Type())
+			for _, key := range target.MapKeys() {
+				combined.SetMapIndex(key, target.MapIndex(key))
+			}
+			for _, key := range field.MapKeys() {
+				combined.SetMapIndex(key, field.MapIndex(key))
+			}
+			target.Set(combined)
+			continue
+		}
+
+		target.Set(field)
+	}
+
+	if override.node != nil {
+		merged.node = override.node
+	}
+
+	return merged
+}
@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem found in a configuration
+type ValidationError struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"` // e.g. workflows[2].tasks[1].timeout
+	Message string `json:"message"`
+}
+
+// Position returns where the problem was found
+func (e ValidationError) Position() Position {
+	return Position{File: e.File, Line: e.Line, Column: e.Column}
+}
+
+// Error formats the problem as file:line:column: path: message
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if pos := e.Position(); pos.IsValid() {
+		b.WriteString(pos.String())
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors lists every problem found in a configuration
+type ValidationErrors []ValidationError
+
+// Error joins all problems, one per line
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// validator collects validation errors instead of stopping at the first one
+type validator struct {
+	errs         ValidationErrors
+	decodeErrors ValidationErrors // only reported when the schema finds nothing
+	schemaErrors int
+}
+
+func (v *validator) addf(pos Position, path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{
+		File:    pos.File,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// merge adds the problems held by err, or err itself when it is not a validation error
+func (v *validator) merge(err error) {
+	if err == nil {
+		return
+	}
+	if errs, ok := err.(ValidationErrors); ok {
+		v.errs = append(v.errs, errs...)
+		return
+	}
+	v.addf(Position{}, "", "%v", err)
+}
+
+// err returns the collected problems ordered by file position, or nil. When
+// the schema and the Go checks report the same field, the first report wins.
+func (v *validator) err() error {
+	if v.schemaErrors == 0 {
+		v.errs = append(v.errs, v.decodeErrors...)
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	type key struct {
+		pos  Position
+		path string
+	}
+	seen := make(map[key]bool)
+	unique := v.errs[:0]
+	for _, e := range v.errs {
+		k := key{e.Position(), e.Path}
+		if e.Path != "" && e.Position().IsValid() && seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, e)
+	}
+	v.errs = unique
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i], v.errs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.errs
+}
+
+// fieldPos returns the position of a field's value inside a definition,
+// falling back to the position of the definition itself
+func fieldPos(node *yaml.Node, pos Position, key string) Position {
+	if value := mappingValue(node, key); value != nil {
+		return nodePosition(value, pos.File)
+	}
+	return pos
+}
+
+// itemPos returns the position of the index-th element of a sequence field
+func itemPos(node *yaml.Node, pos Position, key string, index int) Position {
+	if item := itemNode(node, key, index); item != nil {
+		return nodePosition(item, pos.File)
+	}
+	return fieldPos(node, pos, key)
+}
+
+// itemNode returns the index-th element of a sequence field
+func itemNode(node *yaml.Node, key string, index int) *yaml.Node {
+	if value := mappingValue(node, key); value != nil && value.Kind == yaml.SequenceNode && index < len(value.Content) {
+		return value.Content[index]
+	}
+	return nil
+}
+
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// addDecodeErrors converts yaml type errors ("line 7: cannot unmarshal ...") into
+// validation errors. The schema reports the same problems with more detail, so
+// these are kept as a fallback.
+func (v *validator) addDecodeErrors(err *yaml.TypeError, file string) {
+	for _, message := range err.Errors {
+		e := ValidationError{File: file, Message: message}
+		if match := typeErrorLine.FindStringSubmatch(message); match != nil {
+			e.Line, _ = strconv.Atoi(match[1])
+			e.Message = match[2]
+		}
+		v.decodeErrors = append(v.decodeErrors, e)
+	}
+}
+
+// closestKey returns the known key within two edits of key, if any
+func closestKey(key string, fields map[string]*Schema) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// labelPattern is what a worker label may contain
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidLabel reports whether label can be used in runs_on and by workers
+func ValidLabel(label string) bool {
+	return labelPattern.MatchString(label)
+}
+
+// validateRunsOn checks the worker labels of a task or workflow at path
+func validateRunsOn(labels []string, node *yaml.Node, pos Position, path string, v *validator) {
+	if labels != nil && len(labels) == 0 {
+		v.addf(fieldPos(node, pos, "runs_on"), path+".runs_on", "runs_on needs at least one label")
+	}
+	for i, label := range labels {
+		if !ValidLabel(label) {
+			v.addf(itemPos(node, pos, "runs_on", i), fmt.Sprintf("%s.runs_on[%d]", path, i),
+				"invalid label '%s' (expected letters, digits, '_', '.' and '-')", label)
+		}
+	}
+}
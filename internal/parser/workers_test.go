@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYAMLParser_ParseBytes_RunsOn(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: train
+    schedule: "0 0 * * *"
+    runs_on: [etl]
+    tasks:
+      - id: extract
+        command: ./extract.sh
+      - id: train
+        command: ./train.sh
+        runs_on: [gpu-free, etl]
+`
+
+	config, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	workflow := config.Workflows[0]
+	if len(workflow.RunsOn) != 1 || workflow.RunsOn[0] != "etl" {
+		t.Errorf("Unexpected workflow runs_on: %v", workflow.RunsOn)
+	}
+	if train := workflow.Tasks[1]; len(train.RunsOn) != 2 || train.RunsOn[0] != "gpu-free" {
+		t.Errorf("Unexpected task runs_on: %v", train.RunsOn)
+	}
+}
+
+func TestYAMLParser_ParseBytes_InvalidRunsOn(t *testing.T) {
+	yamlContent := `version: "1.0"
+workflows:
+  - name: child
+    tasks:
+      - id: step
+        command: echo step
+  - name: parent
+    schedule: "0 0 * * *"
+    runs_on: []
+    tasks:
+      - id: bad-label
+        command: echo hi
+        runs_on: [etl, "gpu free"]
+      - id: in-process
+        func: cleanup
+        runs_on: [etl]
+      - id: nested
+        workflow: child
+        runs_on: [etl]
+`
+
+	_, err := NewYAMLParser().ParseBytes([]byte(yamlContent))
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"workflows[1].runs_on",
+		"workflows[1].tasks[0].runs_on[1]",
+		"workflows[1].tasks[1].func",
+		"workflows[1].tasks[2].runs_on",
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(validationErrs), validationErrs)
+	}
+	for i, path := range expected {
+		if validationErrs[i].Path != path {
+			t.Errorf("Error %d: expected path %s, got %s (%v)", i, path, validationErrs[i].Path, validationErrs[i])
+		}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robfig/cron/v3"
 )
 
 // YAMLParser handles parsing of workflow configuration files
@@ -17,15 +19,20 @@ func NewYAMLParser() *YAMLParser {
 	return &YAMLParser{}
 }
 
-// ParseFile parses a YAML configuration file
+// ParseFile parses a YAML configuration file. Included files are resolved
+// relative to the directory of the file that includes them.
 func (p *YAMLParser) ParseFile(filename string) (*WorkflowConfig, error) {
-	file, err := os.Open(filename)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
 	}
-	defer file.Close()
 
-	return p.ParseReader(file)
+	visited := make(map[string]bool)
+	if absPath, err := filepath.Abs(filename); err == nil {
+		visited[absPath] = true
+	}
+
+	return p.parse(data, filename, filepath.Dir(filename), visited)
 }
 
 // ParseReader parses YAML from an io.Reader
@@ -38,97 +45,291 @@ func (p *YAMLParser) ParseReader(reader io.Reader) (*WorkflowConfig, error) {
 	return p.ParseBytes(data)
 }
 
-// ParseBytes parses YAML from byte data
+// ParseBytes parses YAML from byte data. Included files are resolved
+// relative to the working directory.
 func (p *YAMLParser) ParseBytes(data []byte) (*WorkflowConfig, error) {
-	var config WorkflowConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	return p.parse(data, "", ".", make(map[string]bool))
+}
+
+// parse loads, resolves and validates a configuration. Every problem found is
+// returned together as ValidationErrors.
+func (p *YAMLParser) parse(data []byte, file, baseDir string, visited map[string]bool) (*WorkflowConfig, error) {
+	v := &validator{}
+
+	config, err := p.loadConfig(data, file, baseDir, visited, v)
+	if err != nil {
+		return nil, err
+	}
+
+	v.merge(p.ResolveTemplates(config))
+	p.validateConfig(config, v)
+
+	// Workflows are checked against the schema once merged, so that errors
+	// use the same paths as the checks above
+	for i, workflow := range config.Workflows {
+		v.checkSchema(workflow.node, workflowSchema, fmt.Sprintf("workflows[%d]", i), workflow.Pos.File)
 	}
 
-	if err := p.ValidateConfig(&config); err != nil {
+	if err := v.err(); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
-// ValidateConfig validates the workflow configuration
+// ValidateConfig validates the workflow configuration and returns every
+// problem found as ValidationErrors
 func (p *YAMLParser) ValidateConfig(config *WorkflowConfig) error {
+	v := &validator{}
+	p.validateConfig(config, v)
+	return v.err()
+}
+
+func (p *YAMLParser) validateConfig(config *WorkflowConfig, v *validator) {
 	if config.Version == "" {
-		return fmt.Errorf("version is required")
+		v.addf(Position{}, "version", "version is required")
 	}
 
 	if len(config.Workflows) == 0 {
-		return fmt.Errorf("at least one workflow is required")
+		v.addf(Position{}, "workflows", "at least one workflow is required")
+		return
 	}
 
-	// Validate each workflow
+	validateNotifications(config.Notifications, config.node, Position{}, "", v)
+	validatePools(config, v)
+
+	// Workflows invoked as sub-workflows do not need a schedule of their own
+	invoked := make(map[string]bool)
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			if task.Workflow != "" {
+				invoked[task.Workflow] = true
+			}
+		}
+	}
+
+	// Validate each workflow and check for duplicate names
+	names := make(map[string]int)
 	for i, workflow := range config.Workflows {
-		if err := p.ValidateWorkflow(&workflow, i); err != nil {
-			return err
+		p.validateWorkflow(&workflow, i, invoked[workflow.Name], v)
+
+		if workflow.Name == "" {
+			continue
 		}
+		if first, exists := names[workflow.Name]; exists {
+			v.addf(fieldPos(workflow.node, workflow.Pos, "name"), fmt.Sprintf("workflows[%d].name", i),
+				"duplicate workflow name '%s' (first defined at %s)", workflow.Name, definedAt(config.Workflows[first].Pos, fmt.Sprintf("workflows[%d]", first)))
+			continue
+		}
+		names[workflow.Name] = i
 	}
 
-	return nil
+	// Validate references between workflows
+	for i, workflow := range config.Workflows {
+		for j, trigger := range workflow.TriggeredBy {
+			if _, ok := names[trigger.Workflow]; !ok && trigger.Workflow != "" {
+				v.addf(itemPos(workflow.node, workflow.Pos, "triggered_by", j), fmt.Sprintf("workflows[%d].triggered_by[%d].workflow", i, j),
+					"workflow '%s' not found", trigger.Workflow)
+			}
+		}
+		for j, task := range workflow.Tasks {
+			path := fmt.Sprintf("workflows[%d].tasks[%d]", i, j)
+			if task.WaitFor != nil && task.WaitFor.Workflow != "" {
+				if _, ok := names[task.WaitFor.Workflow]; !ok {
+					v.addf(fieldPos(task.node, task.Pos, "wait_for"), path+".wait_for.workflow", "workflow '%s' not found", task.WaitFor.Workflow)
+				}
+			}
+			if task.Workflow != "" {
+				if _, ok := names[task.Workflow]; !ok {
+					v.addf(fieldPos(task.node, task.Pos, "workflow"), path+".workflow", "sub-workflow '%s' not found", task.Workflow)
+				}
+			}
+		}
+	}
+
+	if _, err := p.SortWorkflows(config); err != nil {
+		v.addf(Position{}, "workflows", "%v", err)
+	}
+
+	if err := p.checkSubWorkflowRecursion(config); err != nil {
+		v.addf(Position{}, "workflows", "%v", err)
+	}
 }
 
 // ValidateWorkflow validates a single workflow
 func (p *YAMLParser) ValidateWorkflow(workflow *Workflow, index int) error {
+	v := &validator{}
+	p.validateWorkflow(workflow, index, false, v)
+	return v.err()
+}
+
+// ValidateSubWorkflow validates a workflow that only runs when invoked by a
+// workflow task, and so needs no schedule
+func (p *YAMLParser) ValidateSubWorkflow(workflow *Workflow, index int) error {
+	v := &validator{}
+	p.validateWorkflow(workflow, index, true, v)
+	return v.err()
+}
+
+// validateWorkflow validates a single workflow; sub-workflows may omit the schedule.
+// Errors point at the file, line and column of the offending field.
+func (p *YAMLParser) validateWorkflow(workflow *Workflow, index int, subWorkflow bool, v *validator) {
+	path := fmt.Sprintf("workflows[%d]", index)
+	node, pos := workflow.node, workflow.Pos
+
 	if workflow.Name == "" {
-		return fmt.Errorf("workflow[%d]: name is required", index)
+		v.addf(pos, path+".name", "name is required")
+	}
+
+	if workflow.Schedule == "" && len(workflow.TriggeredBy) == 0 && !subWorkflow {
+		v.addf(pos, path+".schedule", "schedule or triggered_by is required")
+	}
+
+	if workflow.Schedule != "" {
+		if _, err := cron.ParseStandard(workflow.Schedule); err != nil {
+			v.addf(fieldPos(node, pos, "schedule"), path+".schedule", "invalid cron expression '%s': %v", workflow.Schedule, err)
+		}
 	}
 
-	if workflow.Schedule == "" {
-		return fmt.Errorf("workflow[%d]: schedule is required", index)
+	for i, trigger := range workflow.TriggeredBy {
+		triggerPath := fmt.Sprintf("%s.triggered_by[%d]", path, i)
+		if trigger.Workflow == "" {
+			v.addf(itemPos(node, pos, "triggered_by", i), triggerPath+".workflow", "workflow is required")
+		}
+		switch trigger.Status {
+		case "", TriggerOnCompleted, TriggerOnFailed, TriggerOnAny:
+		default:
+			v.addf(fieldPos(itemNode(node, "triggered_by", i), itemPos(node, pos, "triggered_by", i), "status"), triggerPath+".status",
+				"invalid status '%s' (expected completed, failed or any)", trigger.Status)
+		}
 	}
 
+	validateResources(workflow.Resources, node, pos, path, v)
+	validateIsolation(workflow.RunAs, workflow.Sandbox, node, pos, path, v)
+	validateRunsOn(workflow.RunsOn, node, pos, path, v)
+	validateNotifications(workflow.Notifications, node, pos, path, v)
+	validateSLA(workflow.SLA, node, pos, path, v)
+
 	if len(workflow.Tasks) == 0 {
-		return fmt.Errorf("workflow[%d]: at least one task is required", index)
+		v.addf(pos, path+".tasks", "at least one task is required")
+		return
 	}
 
-	// Validate tasks and their dependencies
-	taskIDs := make(map[string]bool)
+	// Validate tasks and check for duplicate IDs
+	taskIDs := make(map[string]int)
 	for i, task := range workflow.Tasks {
-		if err := p.ValidateTask(&task, i, index); err != nil {
-			return err
+		p.validateTask(&task, i, index, v)
+
+		if task.ID == "" {
+			continue
 		}
-		taskIDs[task.ID] = true
+		if first, exists := taskIDs[task.ID]; exists {
+			v.addf(fieldPos(task.node, task.Pos, "id"), fmt.Sprintf("%s.tasks[%d].id", path, i),
+				"duplicate task id '%s' (first defined at %s)", task.ID, definedAt(workflow.Tasks[first].Pos, fmt.Sprintf("%s.tasks[%d]", path, first)))
+			continue
+		}
+		taskIDs[task.ID] = i
 	}
 
 	// Validate dependencies
+	missing := false
 	for i, task := range workflow.Tasks {
-		for _, depID := range task.DependsOn {
-			if !taskIDs[depID] {
-				return fmt.Errorf("workflow[%d].task[%d]: dependency '%s' not found", index, i, depID)
+		for j, depID := range task.DependsOn {
+			if _, ok := taskIDs[depID]; !ok {
+				missing = true
+				v.addf(itemPos(task.node, task.Pos, "depends_on", j), fmt.Sprintf("%s.tasks[%d].depends_on[%d]", path, i, j),
+					"dependency '%s' not found", depID)
 			}
 		}
 	}
 
-	return nil
+	// Detect dependency cycles, which were previously only found at run time
+	if !missing {
+		if _, err := p.TopologicalSort(workflow); err != nil {
+			v.addf(fieldPos(node, pos, "tasks"), path+".tasks", "%v", err)
+		}
+	}
 }
 
 // ValidateTask validates a single task
 func (p *YAMLParser) ValidateTask(task *Task, taskIndex, workflowIndex int) error {
+	v := &validator{}
+	p.validateTask(task, taskIndex, workflowIndex, v)
+	return v.err()
+}
+
+func (p *YAMLParser) validateTask(task *Task, taskIndex, workflowIndex int, v *validator) {
+	path := fmt.Sprintf("workflows[%d].tasks[%d]", workflowIndex, taskIndex)
+	node, pos := task.node, task.Pos
+
 	if task.ID == "" {
-		return fmt.Errorf("workflow[%d].task[%d]: id is required", workflowIndex, taskIndex)
+		v.addf(pos, path+".id", "id is required")
 	}
 
-	if task.Command == "" {
-		return fmt.Errorf("workflow[%d].task[%d]: command is required", workflowIndex, taskIndex)
+	// A task with an unresolved extends has already been reported
+	if task.Command == "" && task.WaitFor == nil && task.Workflow == "" && task.Extends == "" && task.Image == "" && task.HTTP == nil && task.Func == "" {
+		v.addf(pos, path+".command", "command is required")
+	}
+
+	if task.Command != "" && task.Workflow != "" {
+		v.addf(fieldPos(node, pos, "workflow"), path+".workflow", "command and workflow cannot be used together")
+	}
+	if task.Workflow != "" && task.RunsOn != nil {
+		v.addf(fieldPos(node, pos, "runs_on"), path+".runs_on", "runs_on does not apply to sub-workflow tasks; set it on the tasks of the child workflow")
+	}
+
+	if task.Func != "" {
+		if task.Command != "" || task.Workflow != "" || task.Image != "" || task.HTTP != nil {
+			v.addf(fieldPos(node, pos, "func"), path+".func", "func cannot be used together with command, workflow, image or http")
+		}
+		if task.Resources != nil || task.RunAs != nil || task.Sandbox != nil || task.RunsOn != nil {
+			v.addf(fieldPos(node, pos, "func"), path+".func", "resources, run_as, sandbox and runs_on do not apply to func tasks, which run in-process")
+		}
 	}
 
 	if task.Retry < 0 {
-		return fmt.Errorf("workflow[%d].task[%d]: retry count cannot be negative", workflowIndex, taskIndex)
+		v.addf(fieldPos(node, pos, "retry"), path+".retry", "retry count cannot be negative")
 	}
 
 	// Validate timeout format if provided
 	if task.Timeout != "" {
 		if _, err := time.ParseDuration(task.Timeout); err != nil {
-			return fmt.Errorf("workflow[%d].task[%d]: invalid timeout format '%s': %w", workflowIndex, taskIndex, task.Timeout, err)
+			v.addf(fieldPos(node, pos, "timeout"), path+".timeout", "invalid timeout format '%s': %v", task.Timeout, err)
 		}
 	}
 
-	return nil
+	validateResources(task.Resources, node, pos, path, v)
+	validateIsolation(task.RunAs, task.Sandbox, node, pos, path, v)
+	validateRunsOn(task.RunsOn, node, pos, path, v)
+	validateContainer(task, node, pos, path, v)
+	validateHTTP(task, node, pos, path, v)
+	validateSLA(task.SLA, node, pos, path, v)
+
+	if task.WaitFor != nil {
+		waitNode := mappingValue(node, "wait_for")
+		waitPos := fieldPos(node, pos, "wait_for")
+		if task.WaitFor.Workflow == "" {
+			v.addf(waitPos, path+".wait_for.workflow", "workflow is required")
+		}
+		if task.WaitFor.Within != "" {
+			if _, err := time.ParseDuration(task.WaitFor.Within); err != nil {
+				v.addf(fieldPos(waitNode, waitPos, "within"), path+".wait_for.within", "invalid duration '%s': %v", task.WaitFor.Within, err)
+			}
+		}
+		if task.WaitFor.PollInterval != "" {
+			if _, err := time.ParseDuration(task.WaitFor.PollInterval); err != nil {
+				v.addf(fieldPos(waitNode, waitPos, "poll_interval"), path+".wait_for.poll_interval", "invalid duration '%s': %v", task.WaitFor.PollInterval, err)
+			}
+		}
+	}
+}
+
+// definedAt describes where a definition lives, by position when known
+func definedAt(pos Position, path string) string {
+	if pos.IsValid() {
+		return pos.String()
+	}
+	return path
 }
 
 // GetTaskDependencies returns a map of task dependencies for topological sorting
@@ -142,6 +343,12 @@ func (p *YAMLParser) GetTaskDependencies(workflow *Workflow) map[string][]string
 
 // TopologicalSort sorts tasks by their dependencies
 func (p *YAMLParser) TopologicalSort(workflow *Workflow) ([]Task, error) {
+	for _, task := range workflow.Tasks {
+		if task.Workflow != "" && task.Workflow == workflow.Name {
+			return nil, fmt.Errorf("recursive sub-workflow detected: task '%s' invokes its own workflow '%s'", task.ID, workflow.Name)
+		}
+	}
+
 	deps := p.GetTaskDependencies(workflow)
 	visited := make(map[string]bool)
 	temp := make(map[string]bool)
@@ -185,3 +392,123 @@ func (p *YAMLParser) TopologicalSort(workflow *Workflow) ([]Task, error) {
 
 	return result, nil
 }
+
+// GetWorkflowDependencies returns, for each workflow, the workflows that must finish
+// before it runs: the ones that trigger it and the ones its tasks wait for
+func (p *YAMLParser) GetWorkflowDependencies(config *WorkflowConfig) map[string][]string {
+	deps := make(map[string][]string)
+	for _, workflow := range config.Workflows {
+		seen := make(map[string]bool)
+		add := func(name string) {
+			if !seen[name] {
+				seen[name] = true
+				deps[workflow.Name] = append(deps[workflow.Name], name)
+			}
+		}
+		for _, trigger := range workflow.TriggeredBy {
+			add(trigger.Workflow)
+		}
+		for _, task := range workflow.Tasks {
+			if task.WaitFor != nil {
+				add(task.WaitFor.Workflow)
+			}
+		}
+	}
+	return deps
+}
+
+// SortWorkflows sorts workflows so that upstream workflows come first and
+// reports cycles across workflows with the full chain
+func (p *YAMLParser) SortWorkflows(config *WorkflowConfig) ([]Workflow, error) {
+	deps := p.GetWorkflowDependencies(config)
+	byName := make(map[string]Workflow)
+	for _, workflow := range config.Workflows {
+		byName[workflow.Name] = workflow
+	}
+
+	visited := make(map[string]bool)
+	var stack []string
+	result := []Workflow{}
+
+	var visit func(string) error
+	visit = func(name string) error {
+		for i, onStack := range stack {
+			if onStack == name {
+				chain := append(append([]string{}, stack[i:]...), name)
+				return fmt.Errorf("circular workflow dependency detected: %s", strings.Join(chain, " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+
+		stack = append(stack, name)
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		visited[name] = true
+
+		if workflow, ok := byName[name]; ok {
+			result = append(result, workflow)
+		}
+		return nil
+	}
+
+	for _, workflow := range config.Workflows {
+		if err := visit(workflow.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// checkSubWorkflowRecursion reports workflows that invoke themselves through
+// a chain of sub-workflow tasks
+func (p *YAMLParser) checkSubWorkflowRecursion(config *WorkflowConfig) error {
+	calls := make(map[string][]string)
+	for _, workflow := range config.Workflows {
+		for _, task := range workflow.Tasks {
+			if task.Workflow != "" {
+				calls[workflow.Name] = append(calls[workflow.Name], task.Workflow)
+			}
+		}
+	}
+
+	visited := make(map[string]bool)
+	var stack []string
+
+	var visit func(string) error
+	visit = func(name string) error {
+		for i, onStack := range stack {
+			if onStack == name {
+				chain := append(append([]string{}, stack[i:]...), name)
+				return fmt.Errorf("recursive sub-workflow detected: %s", strings.Join(chain, " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+
+		stack = append(stack, name)
+		for _, callee := range calls[name] {
+			if err := visit(callee); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		visited[name] = true
+		return nil
+	}
+
+	for _, workflow := range config.Workflows {
+		if err := visit(workflow.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -150,3 +152,331 @@ func TestYAMLParser_TopologicalSort_CircularDependency(t *testing.T) {
 		t.Error("Expected error for circular dependency, got nil")
 	}
 }
+
+func TestYAMLParser_ValidateConfig_WorkflowChains(t *testing.T) {
+	parser := NewYAMLParser()
+
+	tests := []struct {
+		name    string
+		config  *WorkflowConfig
+		wantErr bool
+	}{
+		{
+			name: "triggered workflow without schedule",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "extract", Schedule: "0 0 * * *", Tasks: []Task{{ID: "t1", Command: "echo extract"}}},
+					{Name: "load", TriggeredBy: []WorkflowTrigger{{Workflow: "extract"}}, Tasks: []Task{{ID: "t1", Command: "echo load"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "sensor task without command",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "extract", Schedule: "0 0 * * *", Tasks: []Task{{ID: "t1", Command: "echo extract"}}},
+					{Name: "report", Schedule: "0 6 * * *", Tasks: []Task{{ID: "wait", WaitFor: &ExternalDependency{Workflow: "extract", Within: "24h"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown upstream workflow",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "load", TriggeredBy: []WorkflowTrigger{{Workflow: "missing"}}, Tasks: []Task{{ID: "t1", Command: "echo load"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid trigger status",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "extract", Schedule: "0 0 * * *", Tasks: []Task{{ID: "t1", Command: "echo extract"}}},
+					{Name: "load", TriggeredBy: []WorkflowTrigger{{Workflow: "extract", Status: "done"}}, Tasks: []Task{{ID: "t1", Command: "echo load"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle across workflows",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "a", Schedule: "0 0 * * *", TriggeredBy: []WorkflowTrigger{{Workflow: "b"}}, Tasks: []Task{{ID: "t1", Command: "echo a"}}},
+					{Name: "b", TriggeredBy: []WorkflowTrigger{{Workflow: "a"}}, Tasks: []Task{{ID: "t1", Command: "echo b"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sensor waiting on downstream workflow",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "a", Schedule: "0 0 * * *", Tasks: []Task{{ID: "wait", WaitFor: &ExternalDependency{Workflow: "b"}}}},
+					{Name: "b", TriggeredBy: []WorkflowTrigger{{Workflow: "a"}}, Tasks: []Task{{ID: "t1", Command: "echo b"}}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parser.ValidateConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("YAMLParser.ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestYAMLParser_SortWorkflows(t *testing.T) {
+	parser := NewYAMLParser()
+
+	config := &WorkflowConfig{
+		Version: "1.0",
+		Workflows: []Workflow{
+			{Name: "load", TriggeredBy: []WorkflowTrigger{{Workflow: "transform"}}},
+			{Name: "transform", TriggeredBy: []WorkflowTrigger{{Workflow: "extract"}}},
+			{Name: "extract", Schedule: "0 0 * * *"},
+		},
+	}
+
+	sorted, err := parser.SortWorkflows(config)
+	if err != nil {
+		t.Fatalf("SortWorkflows() error = %v", err)
+	}
+
+	expectedOrder := []string{"extract", "transform", "load"}
+	for i, workflow := range sorted {
+		if workflow.Name != expectedOrder[i] {
+			t.Errorf("Expected workflow %s at position %d, got %s", expectedOrder[i], i, workflow.Name)
+		}
+	}
+}
+
+func TestYAMLParser_ValidateConfig_SubWorkflows(t *testing.T) {
+	parser := NewYAMLParser()
+
+	notify := Workflow{Name: "notify", Tasks: []Task{{ID: "send", Command: "echo {{ .Params.message }}"}}}
+
+	valid := &WorkflowConfig{
+		Version: "1.0",
+		Workflows: []Workflow{
+			{Name: "etl", Schedule: "0 0 * * *", Tasks: []Task{{ID: "notify", Workflow: "notify", Params: map[string]string{"message": "done"}}}},
+			notify,
+		},
+	}
+	if err := parser.ValidateConfig(valid); err != nil {
+		t.Errorf("Expected sub-workflow without schedule to be valid, got %v", err)
+	}
+
+	recursive := &WorkflowConfig{
+		Version: "1.0",
+		Workflows: []Workflow{
+			{Name: "a", Schedule: "0 0 * * *", Tasks: []Task{{ID: "call_b", Workflow: "b"}}},
+			{Name: "b", Tasks: []Task{{ID: "call_a", Workflow: "a"}}},
+		},
+	}
+	if err := parser.ValidateConfig(recursive); err == nil {
+		t.Error("Expected error for recursive sub-workflows, got nil")
+	}
+}
+
+func TestYAMLParser_TopologicalSort_SelfInvocation(t *testing.T) {
+	parser := NewYAMLParser()
+
+	workflow := &Workflow{
+		Name:  "loop",
+		Tasks: []Task{{ID: "again", Workflow: "loop"}},
+	}
+
+	if _, err := parser.TopologicalSort(workflow); err == nil {
+		t.Error("Expected error for a workflow invoking itself, got nil")
+	}
+}
+
+func TestYAMLParser_ParseFile_IncludesAndTemplates(t *testing.T) {
+	parser := NewYAMLParser()
+
+	config, err := parser.ParseFile("../../testdata/includes/main.yml")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(config.Workflows) != 3 {
+		t.Fatalf("Expected 3 workflows after includes, got %d", len(config.Workflows))
+	}
+
+	workflows := make(map[string]Workflow)
+	for _, workflow := range config.Workflows {
+		workflows[workflow.Name] = workflow
+	}
+
+	step1 := workflows["main_workflow"].Tasks[0]
+	if step1.Command != "python --version" || step1.Retry != 2 || step1.Timeout != "5m" {
+		t.Errorf("Expected step1 to inherit python_step, got %+v", step1)
+	}
+
+	backup := workflows["backup_workflow"].Tasks[0]
+	if backup.Command != "echo backup" || backup.Retry != 2 {
+		t.Errorf("Expected backup to override command and keep retry, got %+v", backup)
+	}
+	if backup.Pos.File != "../../testdata/includes/workflows.d/backup.yml" || backup.Pos.Line != 5 {
+		t.Errorf("Expected backup position in backup.yml line 5, got %s", backup.Pos)
+	}
+
+	notify := workflows["backup_workflow"].Tasks[1]
+	if notify.Params["channel"] != "ops" || notify.Params["message"] != "backup done" {
+		t.Errorf("Expected params to be merged, got %v", notify.Params)
+	}
+}
+
+func TestYAMLParser_ParseFile_IncludedValidationError(t *testing.T) {
+	parser := NewYAMLParser()
+
+	_, err := parser.ParseFile("../../testdata/includes/broken/main.yml")
+	if err == nil {
+		t.Fatal("Expected validation error from included file, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "bad.yml:7:") {
+		t.Errorf("Expected error to report bad.yml line 7, got %v", err)
+	}
+}
+
+func TestYAMLParser_ResolveTemplates_UnknownTemplate(t *testing.T) {
+	parser := NewYAMLParser()
+
+	config := &WorkflowConfig{
+		Version: "1.0",
+		Workflows: []Workflow{
+			{Name: "test", Schedule: "0 0 * * *", Tasks: []Task{{ID: "task1", Extends: "missing"}}},
+		},
+	}
+
+	if err := parser.ResolveTemplates(config); err == nil {
+		t.Error("Expected error for unknown task template, got nil")
+	}
+}
+
+func TestYAMLParser_ParseBytes_CollectsAllErrors(t *testing.T) {
+	parser := NewYAMLParser()
+
+	data := []byte(`version: "1.0"
+workflows:
+  - name: etl
+    schedule: "61 * * * *"
+    tasks:
+      - id: extract
+        command: echo extract
+        depend_on: [load]
+      - id: extract
+        command: echo again
+        timeout: 5x
+  - name: etl
+    schedule: "0 1 * * *"
+    tasks:
+      - id: a
+        command: echo a
+        depends_on: [b]
+      - id: b
+        command: echo b
+        depends_on: [a]
+`)
+
+	_, err := parser.ParseBytes(data)
+	if err == nil {
+		t.Fatal("Expected validation errors, got nil")
+	}
+
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	expected := []ValidationError{
+		{Line: 4, Column: 15, Path: "workflows[0].schedule"},
+		{Line: 8, Column: 9, Path: "workflows[0].tasks[0].depend_on", Message: "unknown field 'depend_on', did you mean 'depends_on'?"},
+		{Line: 9, Column: 13, Path: "workflows[0].tasks[1].id"},
+		{Line: 11, Column: 18, Path: "workflows[0].tasks[1].timeout"},
+		{Line: 12, Column: 11, Path: "workflows[1].name"},
+		{Line: 15, Column: 7, Path: "workflows[1].tasks"},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d:\n%v", len(expected), len(problems), problems)
+	}
+
+	for i, want := range expected {
+		got := problems[i]
+		if got.Line != want.Line || got.Column != want.Column || got.Path != want.Path {
+			t.Errorf("Problem %d: expected %d:%d %s, got %d:%d %s", i, want.Line, want.Column, want.Path, got.Line, got.Column, got.Path)
+		}
+		if want.Message != "" && got.Message != want.Message {
+			t.Errorf("Problem %d: expected message %q, got %q", i, want.Message, got.Message)
+		}
+	}
+}
+
+func TestYAMLParser_ValidateConfig_Duplicates(t *testing.T) {
+	parser := NewYAMLParser()
+
+	tests := []struct {
+		name    string
+		config  *WorkflowConfig
+		message string
+	}{
+		{
+			name: "duplicate workflow name",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "test", Schedule: "0 0 * * *", Tasks: []Task{{ID: "task1", Command: "echo"}}},
+					{Name: "test", Schedule: "0 1 * * *", Tasks: []Task{{ID: "task1", Command: "echo"}}},
+				},
+			},
+			message: "duplicate workflow name 'test'",
+		},
+		{
+			name: "duplicate task id",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "test", Schedule: "0 0 * * *", Tasks: []Task{{ID: "task1", Command: "echo"}, {ID: "task1", Command: "echo"}}},
+				},
+			},
+			message: "duplicate task id 'task1'",
+		},
+		{
+			name: "invalid cron expression",
+			config: &WorkflowConfig{
+				Version: "1.0",
+				Workflows: []Workflow{
+					{Name: "test", Schedule: "every day", Tasks: []Task{{ID: "task1", Command: "echo"}}},
+				},
+			},
+			message: "invalid cron expression 'every day'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parser.ValidateConfig(tt.config)
+			if err == nil {
+				t.Fatal("Expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
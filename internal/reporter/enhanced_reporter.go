@@ -18,12 +18,14 @@ type EnhancedHTMLReporter struct {
 	template      *template.Template
 	reportManager *ReportManager
 	config        ReportConfig
+	workflows     map[string]parser.Workflow
 }
 
 // NewEnhancedHTMLReporter creates a new enhanced HTML reporter
 func NewEnhancedHTMLReporter(config ReportConfig) (*EnhancedHTMLReporter, error) {
 	tmpl, err := template.New("report").Funcs(template.FuncMap{
 		"formatDuration": formatDuration,
+		"formatBytes":    formatBytes,
 		"formatTime":     formatTime,
 		"statusColor":    statusColor,
 		"truncateString": truncateString,
@@ -39,6 +41,11 @@ func NewEnhancedHTMLReporter(config ReportConfig) (*EnhancedHTMLReporter, error)
 	}, nil
 }
 
+// SetWorkflows provides the workflow definitions used to draw each execution's task graph
+func (ehr *EnhancedHTMLReporter) SetWorkflows(workflows []parser.Workflow) {
+	ehr.workflows = workflowsByName(workflows)
+}
+
 // GenerateManagedReport generates a managed HTML report with rotation and archival
 func (ehr *EnhancedHTMLReporter) GenerateManagedReport(executions map[string][]parser.WorkflowExecution, outputPath string) error {
 	// Load or create report index
@@ -95,7 +102,7 @@ func (ehr *EnhancedHTMLReporter) addExecutionsToIndex(executions map[string][]pa
 	for workflowName, workflowExecutions := range executions {
 		for _, execution := range workflowExecutions {
 			// Generate unique execution ID
-			execID := generateExecutionID(workflowName, execution.StartTime)
+			execID := executionID(execution)
 
 			// Check if execution already exists
 			exists := false
@@ -120,7 +127,13 @@ func (ehr *EnhancedHTMLReporter) addExecutionsToIndex(executions map[string][]pa
 					StartTime:   execution.StartTime,
 					Status:      execution.Status,
 					FilePath:    execFilePath,
+					Chain:       execution.Chain,
+					Trigger:     execution.Trigger,
+					RetryOf:     execution.RetryOf,
+
+					SLAViolations: execution.SLAViolations,
 				}
+				indexEntry.PeakRSS, indexEntry.CPUTime = resourceUsage(execution)
 				index.Executions = append(index.Executions, indexEntry)
 			}
 		}
@@ -158,6 +171,27 @@ func (ehr *EnhancedHTMLReporter) buildManagedReport(recentExecutions []Execution
 		RecentExecutions: recentExecutions,
 		Config:           ehr.config,
 		WorkflowSummary:  make(map[string]WorkflowSummary),
+		Graphs:           make(map[string]string),
+	}
+
+	// Draw the task graph of each recent execution, from memory or its stored file
+	byID := make(map[string]parser.WorkflowExecution)
+	for _, executions := range allExecutions {
+		for _, execution := range executions {
+			byID[executionID(execution)] = execution
+		}
+	}
+	for _, entry := range recentExecutions {
+		execution, ok := byID[entry.ExecutionID]
+		if !ok {
+			data, err := os.ReadFile(entry.FilePath)
+			if err != nil || json.Unmarshal(data, &execution) != nil {
+				continue
+			}
+		}
+		if g := executionGraph(ehr.workflows, execution); g != "" {
+			report.Graphs[entry.ExecutionID] = g
+		}
 	}
 
 	// Build workflow summaries
@@ -188,9 +222,13 @@ func (ehr *EnhancedHTMLReporter) buildManagedReport(recentExecutions []Execution
 	return report
 }
 
-// generateExecutionID generates a unique ID for an execution
-func generateExecutionID(workflowName string, startTime time.Time) string {
-	data := fmt.Sprintf("%s-%s", workflowName, startTime.Format("2006-01-02T15:04:05.000Z"))
+// executionID returns the run ID of an execution. Runs recorded before runs
+// had IDs get the hash of their workflow and start time they were indexed by.
+func executionID(execution parser.WorkflowExecution) string {
+	if execution.ID != "" {
+		return execution.ID
+	}
+	data := fmt.Sprintf("%s-%s", execution.WorkflowID, execution.StartTime.Format("2006-01-02T15:04:05.000Z"))
 	hash := md5.Sum([]byte(data))
 	return fmt.Sprintf("%x", hash)[:16]
 }
@@ -202,6 +240,7 @@ type ManagedReportData struct {
 	RecentExecutions []ExecutionIndex           `json:"recent_executions"`
 	Config           ReportConfig               `json:"config"`
 	WorkflowSummary  map[string]WorkflowSummary `json:"workflow_summary"`
+	Graphs           map[string]string          `json:"graphs,omitempty"` // Mermaid task graphs by execution ID
 }
 
 // WorkflowSummary represents a summary of workflow executions
@@ -227,6 +266,41 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fh", d.Hours())
 }
 
+// formatBytes formats a byte count with binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
+
+// resourceUsage returns the largest peak RSS and the total CPU time of the
+// tasks of an execution, including sub-workflow runs
+func resourceUsage(execution parser.WorkflowExecution) (int64, time.Duration) {
+	var peak int64
+	var cpu time.Duration
+	for _, result := range execution.TaskResults {
+		if result.PeakRSS > peak {
+			peak = result.PeakRSS
+		}
+		cpu += result.CPUTime
+		if result.SubWorkflow != nil {
+			childPeak, childCPU := resourceUsage(*result.SubWorkflow)
+			if childPeak > peak {
+				peak = childPeak
+			}
+			cpu += childCPU
+		}
+	}
+	return peak, cpu
+}
+
 func formatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
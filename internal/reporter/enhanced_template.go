@@ -193,6 +193,28 @@ const enhancedHtmlTemplate = `
             color: #667eea;
         }
 
+        .chain {
+            color: #6c757d;
+            font-size: 0.8rem;
+        }
+
+        .sla {
+            color: #856404;
+            font-size: 0.8rem;
+        }
+
+        .task-graph summary {
+            cursor: pointer;
+            color: #667eea;
+            font-size: 0.85rem;
+        }
+
+        .task-graph pre {
+            background: white;
+            margin-top: 0.5rem;
+            overflow-x: auto;
+        }
+
         .pagination {
             display: flex;
             justify-content: center;
@@ -336,6 +358,7 @@ const enhancedHtmlTemplate = `
                         <th>Workflow</th>
                         <th>Start Time</th>
                         <th>Status</th>
+                        <th>Resources</th>
                         <th>Execution ID</th>
                     </tr>
                 </thead>
@@ -344,15 +367,28 @@ const enhancedHtmlTemplate = `
                     <tr>
                         <td>
                             <span class="workflow-name">{{.WorkflowID}}</span>
+                            {{if .Chain}}<div class="chain">⛓ {{range .Chain}}{{.}} → {{end}}{{.WorkflowID}}</div>{{end}}
+                            {{with index $.Graphs .ExecutionID}}
+                            <details class="task-graph" ontoggle="renderGraphs(this)">
+                                <summary>Task graph</summary>
+                                <pre class="mermaid">{{.}}</pre>
+                            </details>
+                            {{end}}
                         </td>
                         <td>
                             <span class="timestamp">{{formatTime .StartTime}}</span>
                         </td>
                         <td>
                             <span class="status-badge status-{{statusColor .Status}}">{{.Status}}</span>
+                            {{range .SLAViolations}}<div class="sla">⏱ {{.Message}}</div>{{end}}
+                        </td>
+                        <td>
+                            {{if .PeakRSS}}<span class="timestamp">{{formatBytes .PeakRSS}} peak · {{formatDuration .CPUTime}} CPU</span>{{else}}-{{end}}
                         </td>
                         <td>
-                            <code>{{truncateString .ExecutionID 12}}</code>
+                            <code title="{{.ExecutionID}}">{{.ExecutionID}}</code>
+                            {{if .Trigger}}<div class="timestamp">{{.Trigger}}</div>{{end}}
+                            {{if .RetryOf}}<div class="timestamp" title="Retry of run {{.RetryOf}}">↻ {{truncateString .RetryOf 12}}</div>{{end}}
                         </td>
                     </tr>
                     {{end}}
@@ -433,6 +469,20 @@ const enhancedHtmlTemplate = `
             </p>
         </div>
     </div>
+
+    <script src="https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.min.js"></script>
+    <script>
+        if (window.mermaid) {
+            mermaid.initialize({ startOnLoad: false });
+        }
+
+        // Graphs are drawn when their section is opened, hidden ones cannot be measured
+        function renderGraphs(container) {
+            if (window.mermaid && container.open) {
+                mermaid.run({ nodes: container.querySelectorAll('.mermaid:not([data-processed])') });
+            }
+        }
+    </script>
 </body>
 </html>
 `
@@ -8,26 +8,38 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/sintakaridina/goliteflow/internal/graph"
 	"github.com/sintakaridina/goliteflow/internal/parser"
 )
 
 // HTMLReporter generates HTML reports for workflow executions
 type HTMLReporter struct {
-	template *template.Template
+	template  *template.Template
+	workflows map[string]parser.Workflow
 }
 
 // NewHTMLReporter creates a new HTML reporter
 func NewHTMLReporter() (*HTMLReporter, error) {
-	tmpl, err := template.New("report").Parse(htmlTemplate)
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"formatBytes": formatBytes,
+	}).Parse(htmlTemplate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML template: %w", err)
 	}
+	if _, err := tmpl.Parse(subWorkflowTemplate); err != nil {
+		return nil, fmt.Errorf("failed to parse sub-workflow template: %w", err)
+	}
 
 	return &HTMLReporter{
 		template: tmpl,
 	}, nil
 }
 
+// SetWorkflows provides the workflow definitions used to draw each execution's task graph
+func (hr *HTMLReporter) SetWorkflows(workflows []parser.Workflow) {
+	hr.workflows = workflowsByName(workflows)
+}
+
 // GenerateReport generates an HTML report from execution data
 func (hr *HTMLReporter) GenerateReport(executions map[string][]parser.WorkflowExecution, outputPath string) error {
 	report := hr.buildReport(executions)
@@ -79,31 +91,8 @@ func (hr *HTMLReporter) buildReport(executions map[string][]parser.WorkflowExecu
 				workflowReport.LastRun = execution.StartTime
 			}
 
-			execReport := ExecutionReport{
-				StartTime:    execution.StartTime,
-				EndTime:      execution.EndTime,
-				Duration:     execution.Duration,
-				Status:       execution.Status,
-				ErrorMessage: execution.ErrorMessage,
-				TaskResults:  []TaskReport{},
-			}
-
-			for _, taskResult := range execution.TaskResults {
-				taskReport := TaskReport{
-					TaskID:     taskResult.TaskID,
-					StartTime:  taskResult.StartTime,
-					EndTime:    taskResult.EndTime,
-					Duration:   taskResult.Duration,
-					ExitCode:   taskResult.ExitCode,
-					Success:    taskResult.Success,
-					RetryCount: taskResult.RetryCount,
-					Stdout:     taskResult.Stdout,
-					Stderr:     taskResult.Stderr,
-					Error:      taskResult.Error,
-				}
-				execReport.TaskResults = append(execReport.TaskResults, taskReport)
-			}
-
+			execReport := buildExecutionReport(execution)
+			execReport.Graph = executionGraph(hr.workflows, execution)
 			workflowReport.Executions = append(workflowReport.Executions, execReport)
 		}
 
@@ -123,6 +112,74 @@ func (hr *HTMLReporter) buildReport(executions map[string][]parser.WorkflowExecu
 	return report
 }
 
+// buildExecutionReport converts an execution, including nested sub-workflow runs, for the report
+func buildExecutionReport(execution parser.WorkflowExecution) ExecutionReport {
+	execReport := ExecutionReport{
+		ID:           execution.ID,
+		Key:          executionID(execution),
+		Trigger:      execution.Trigger,
+		Attempt:      execution.Attempt,
+		RetryOf:      execution.RetryOf,
+		WorkflowID:   execution.WorkflowID,
+		StartTime:    execution.StartTime,
+		EndTime:      execution.EndTime,
+		Duration:     execution.Duration,
+		Status:       execution.Status,
+		ErrorMessage: execution.ErrorMessage,
+		Chain:        execution.Chain,
+		SLA:          execution.SLAViolations,
+		TaskResults:  []TaskReport{},
+	}
+
+	for _, taskResult := range execution.TaskResults {
+		taskReport := TaskReport{
+			TaskID:     taskResult.TaskID,
+			StartTime:  taskResult.StartTime,
+			EndTime:    taskResult.EndTime,
+			Duration:   taskResult.Duration,
+			ExitCode:   taskResult.ExitCode,
+			Success:    taskResult.Success,
+			RetryCount: taskResult.RetryCount,
+			Stdout:     taskResult.Stdout,
+			Stderr:     taskResult.Stderr,
+			Error:      taskResult.Error,
+			PeakRSS:    taskResult.PeakRSS,
+			CPUTime:    taskResult.CPUTime,
+			Reused:     taskResult.Reused,
+		}
+		if taskResult.SubWorkflow != nil {
+			child := buildExecutionReport(*taskResult.SubWorkflow)
+			taskReport.SubWorkflow = &child
+		}
+		execReport.TaskResults = append(execReport.TaskResults, taskReport)
+	}
+
+	return execReport
+}
+
+// workflowsByName indexes workflow definitions by name
+func workflowsByName(workflows []parser.Workflow) map[string]parser.Workflow {
+	byName := make(map[string]parser.Workflow, len(workflows))
+	for _, workflow := range workflows {
+		byName[workflow.Name] = workflow
+	}
+	return byName
+}
+
+// executionGraph returns the Mermaid task graph of an execution coloured by
+// task status, or an empty string when the workflow definition is unknown
+func executionGraph(workflows map[string]parser.Workflow, execution parser.WorkflowExecution) string {
+	workflow, ok := workflows[execution.WorkflowID]
+	if !ok {
+		return ""
+	}
+	g, err := graph.Build(workflow, &execution)
+	if err != nil {
+		return ""
+	}
+	return g.Mermaid()
+}
+
 // ReportData represents the data structure for the HTML report
 type ReportData struct {
 	GeneratedAt     time.Time
@@ -143,11 +200,20 @@ type WorkflowReport struct {
 
 // ExecutionReport represents an execution in the report
 type ExecutionReport struct {
+	ID           string // run ID, empty for runs recorded before runs had IDs
+	Key          string // identifies the run's elements in the page
+	Trigger      string
+	Attempt      int
+	RetryOf      string // run this one retried
+	WorkflowID   string
 	StartTime    time.Time
 	EndTime      time.Time
 	Duration     time.Duration
 	Status       string
 	ErrorMessage string
+	Chain        []string // upstream workflows that triggered this run, oldest first
+	Graph        string   // Mermaid flowchart of the tasks coloured by status
+	SLA          []parser.SLAViolation
 	TaskResults  []TaskReport
 }
 
@@ -163,6 +229,11 @@ type TaskReport struct {
 	Stdout     string
 	Stderr     string
 	Error      string
+	PeakRSS    int64         // bytes, 0 when not measured
+	CPUTime    time.Duration // user and system time
+	Reused     bool          // copied from the run a retry resumed
+
+	SubWorkflow *ExecutionReport // child run of a workflow task
 }
 
 // HTML template with embedded CSS and JavaScript
@@ -403,6 +474,42 @@ const htmlTemplate = `<!DOCTYPE html>
             font-weight: bold;
         }
         
+        .subworkflow {
+            border-left: 3px solid #667eea;
+            margin: 8px 0;
+            padding-left: 10px;
+        }
+        
+        .subworkflow summary {
+            cursor: pointer;
+            padding: 6px 0;
+        }
+        
+        .chain-badge {
+            background: #e3f2fd;
+            color: #1976d2;
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 0.75em;
+        }
+        
+        .sla-badge {
+            background: #fff3cd;
+            color: #856404;
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 0.75em;
+        }
+        
+        .task-graph {
+            background: white;
+            border: 1px solid #e9ecef;
+            border-radius: 4px;
+            padding: 10px;
+            margin-bottom: 10px;
+            overflow-x: auto;
+        }
+        
         .toggle-icon {
             transition: transform 0.3s ease;
         }
@@ -449,35 +556,49 @@ const htmlTemplate = `<!DOCTYPE html>
                 {{$workflowName := .Name}}
                 {{range .Executions}}
                 <div class="execution">
-                    <div class="execution-header" onclick="toggleExecution('{{$workflowName}}-{{.StartTime.Unix}}')">
+                    <div class="execution-header" onclick="toggleExecution('{{.Key}}')">
                         <div>
                             <span class="status {{.Status}}">{{.Status}}</span>
                             <span class="timestamp">{{.StartTime.Format "2006-01-02 15:04:05"}}</span>
                             <span class="duration">{{.Duration}}</span>
+                            {{if .ID}}<span class="timestamp" title="Run ID">{{.ID}}</span>{{end}}
+                            {{if .Trigger}}<span class="timestamp">{{.Trigger}}{{if gt .Attempt 1}} · attempt {{.Attempt}}{{end}}</span>{{end}}
+                            {{if .RetryOf}}<span class="retry-badge" title="Retry of run {{.RetryOf}}">↻ retry of {{.RetryOf}}</span>{{end}}
+                            {{if .Chain}}<span class="chain-badge">⛓ {{range .Chain}}{{.}} → {{end}}{{$workflowName}}</span>{{end}}
+                            {{if .SLA}}<span class="sla-badge">⏱ SLA missed</span>{{end}}
                         </div>
-                        <span class="toggle-icon" id="exec-icon-{{$workflowName}}-{{.StartTime.Unix}}">▼</span>
+                        <span class="toggle-icon" id="exec-icon-{{.Key}}">▼</span>
                     </div>
-                    <div class="execution-content" id="exec-content-{{$workflowName}}-{{.StartTime.Unix}}">
+                    <div class="execution-content" id="exec-content-{{.Key}}">
                         {{if .ErrorMessage}}
                         <div style="color: #721c24; background: #f8d7da; padding: 10px; border-radius: 4px; margin-bottom: 10px;">
                             <strong>Error:</strong> {{.ErrorMessage}}
                         </div>
                         {{end}}
-                        {{$executionStartTime := .StartTime}}
+                        {{range .SLA}}
+                        <div style="color: #856404; background: #fff3cd; padding: 10px; border-radius: 4px; margin-bottom: 10px;">
+                            <strong>SLA {{.Type}}:</strong> {{.Message}} ({{.Time.Format "2006-01-02 15:04:05"}})
+                        </div>
+                        {{end}}
+                        {{if .Graph}}
+                        <pre class="mermaid task-graph">{{.Graph}}</pre>
+                        {{end}}
+                        {{$executionKey := .Key}}
                         {{range .TaskResults}}
                         <div class="task">
-                            <div class="task-header" onclick="toggleTask('{{$workflowName}}-{{$executionStartTime.Unix}}-{{.TaskID}}')">
+                            <div class="task-header" onclick="toggleTask('{{$executionKey}}-{{.TaskID}}')">
                                 <div>
                                     <span class="status {{if .Success}}completed{{else}}failed{{end}}">{{.TaskID}}</span>
                                     {{if .RetryCount}}<span class="retry-badge">{{.RetryCount}} retries</span>{{end}}
                                     <span class="duration">{{.Duration}}</span>
                                 </div>
-                                <span class="toggle-icon" id="task-icon-{{$workflowName}}-{{$executionStartTime.Unix}}-{{.TaskID}}">▼</span>
+                                <span class="toggle-icon" id="task-icon-{{$executionKey}}-{{.TaskID}}">▼</span>
                             </div>
-                            <div class="task-content" id="task-content-{{$workflowName}}-{{$executionStartTime.Unix}}-{{.TaskID}}">
+                            <div class="task-content" id="task-content-{{$executionKey}}-{{.TaskID}}">
                                 <div><strong>Exit Code:</strong> {{.ExitCode}}</div>
                                 <div><strong>Start Time:</strong> {{.StartTime.Format "2006-01-02 15:04:05"}}</div>
                                 <div><strong>End Time:</strong> {{.EndTime.Format "2006-01-02 15:04:05"}}</div>
+                                {{if .PeakRSS}}<div><strong>Peak Memory:</strong> {{formatBytes .PeakRSS}} · <strong>CPU Time:</strong> {{.CPUTime}}</div>{{end}}
                                 {{if .Error}}
                                 <div class="log-section">
                                     <h4>Error:</h4>
@@ -496,6 +617,12 @@ const htmlTemplate = `<!DOCTYPE html>
                                     <div class="log-content">{{.Stderr}}</div>
                                 </div>
                                 {{end}}
+                                {{if .SubWorkflow}}
+                                <div class="log-section">
+                                    <h4>Sub-workflow:</h4>
+                                    {{template "subworkflow" .SubWorkflow}}
+                                </div>
+                                {{end}}
                             </div>
                         </div>
                         {{end}}
@@ -507,7 +634,19 @@ const htmlTemplate = `<!DOCTYPE html>
         {{end}}
     </div>
     
+    <script src="https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.min.js"></script>
     <script>
+        if (window.mermaid) {
+            mermaid.initialize({ startOnLoad: false });
+        }
+        
+        // Graphs are drawn when they become visible, hidden ones cannot be measured
+        function renderGraphs(container) {
+            if (window.mermaid) {
+                mermaid.run({ nodes: container.querySelectorAll('.mermaid:not([data-processed])') });
+            }
+        }
+        
         function toggleWorkflow(workflowName) {
             const content = document.getElementById('content-' + workflowName);
             const icon = document.getElementById('icon-' + workflowName);
@@ -531,6 +670,7 @@ const htmlTemplate = `<!DOCTYPE html>
             } else {
                 content.classList.add('expanded');
                 icon.classList.add('rotated');
+                renderGraphs(content);
             }
         }
         
@@ -549,3 +689,34 @@ const htmlTemplate = `<!DOCTYPE html>
     </script>
 </body>
 </html>`
+
+// subWorkflowTemplate renders a nested child run; it recurses for sub-workflows of sub-workflows
+const subWorkflowTemplate = `{{define "subworkflow"}}
+<details class="subworkflow">
+    <summary>
+        <span class="status {{.Status}}">{{.Status}}</span>
+        <strong>{{.WorkflowID}}</strong>
+        <span class="duration">{{.Duration}}</span>
+    </summary>
+    {{if .ErrorMessage}}
+    <div style="color: #721c24; background: #f8d7da; padding: 10px; border-radius: 4px; margin-bottom: 10px;">
+        <strong>Error:</strong> {{.ErrorMessage}}
+    </div>
+    {{end}}
+    {{range .TaskResults}}
+    <details class="subworkflow">
+        <summary>
+            <span class="status {{if .Success}}completed{{else}}failed{{end}}">{{.TaskID}}</span>
+            {{if .RetryCount}}<span class="retry-badge">{{.RetryCount}} retries</span>{{end}}
+            <span class="duration">{{.Duration}}</span>
+        </summary>
+        <div><strong>Exit Code:</strong> {{.ExitCode}}</div>
+        {{if .PeakRSS}}<div><strong>Peak Memory:</strong> {{formatBytes .PeakRSS}} · <strong>CPU Time:</strong> {{.CPUTime}}</div>{{end}}
+        {{if .Error}}<div class="log-section"><h4>Error:</h4><div class="log-content">{{.Error}}</div></div>{{end}}
+        {{if .Stdout}}<div class="log-section"><h4>Stdout:</h4><div class="log-content">{{.Stdout}}</div></div>{{end}}
+        {{if .Stderr}}<div class="log-section"><h4>Stderr:</h4><div class="log-content">{{.Stderr}}</div></div>{{end}}
+        {{if .SubWorkflow}}{{template "subworkflow" .SubWorkflow}}{{end}}
+    </details>
+    {{end}}
+</details>
+{{end}}`
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sort"
 	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
 )
 
 // ReportConfig holds configuration for report management
@@ -52,6 +54,14 @@ type ExecutionIndex struct {
 	StartTime   time.Time `json:"start_time"`
 	Status      string    `json:"status"`
 	FilePath    string    `json:"file_path"`
+	Chain       []string  `json:"chain,omitempty"`    // upstream workflows that triggered the run
+	Trigger     string    `json:"trigger,omitempty"`  // cron, manual or event
+	RetryOf     string    `json:"retry_of,omitempty"` // run this one retried
+
+	SLAViolations []parser.SLAViolation `json:"sla_violations,omitempty"` // breached SLAs of the run and its tasks
+
+	PeakRSS int64         `json:"peak_rss_bytes,omitempty"` // largest peak RSS of any task
+	CPUTime time.Duration `json:"cpu_time,omitempty"`       // CPU time of all tasks
 }
 
 // ReportIndex manages the index of all executions
@@ -0,0 +1,130 @@
+// Package runid generates run IDs: 26-character ULIDs that sort by the time
+// the run started and are unique across processes and hosts
+package runid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Length is the number of characters of a run ID
+const Length = 26
+
+// encoding is Crockford's base32 alphabet, which sorts like the values it encodes
+const encoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	mu       sync.Mutex
+	lastMS   uint64
+	lastRand [10]byte
+)
+
+// New returns the ID of a run starting now
+func New() string {
+	return NewAt(time.Now())
+}
+
+// NewAt returns a run ID for time t. IDs generated in the same millisecond
+// by this process increase monotonically.
+func NewAt(t time.Time) string {
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+
+	mu.Lock()
+	var entropy [10]byte
+	if ms == lastMS {
+		entropy = lastRand
+		increment(&entropy)
+	} else if _, err := rand.Read(entropy[:]); err != nil {
+		// Fall back to the clock rather than fail a run
+		binary.BigEndian.PutUint64(entropy[2:], uint64(time.Now().UnixNano()))
+	}
+	lastMS, lastRand = ms, entropy
+	mu.Unlock()
+
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	copy(id[6:], entropy[:])
+	return encode(id)
+}
+
+// increment adds one to the 80-bit random part
+func increment(entropy *[10]byte) {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return
+		}
+	}
+}
+
+// encode writes the 128 bits of id as 26 base32 characters, 5 bits each,
+// with the 2 spare bits at the front
+func encode(id [16]byte) string {
+	var sb strings.Builder
+	sb.Grow(Length)
+	for i := 0; i < Length; i++ {
+		shift := uint(5 * (Length - 1 - i))
+		sb.WriteByte(encoding[bitsAt(id, shift)])
+	}
+	return sb.String()
+}
+
+// bitsAt returns the 5 bits of id starting shift bits from the least significant end
+func bitsAt(id [16]byte, shift uint) byte {
+	var value uint16
+	for bit := uint(0); bit < 5; bit++ {
+		position := shift + bit
+		if position >= 128 {
+			break
+		}
+		b := id[15-position/8]
+		if b&(1<<(position%8)) != 0 {
+			value |= 1 << bit
+		}
+	}
+	return byte(value)
+}
+
+// Time returns the time encoded in a run ID, to the millisecond
+func Time(id string) (time.Time, error) {
+	if err := Validate(id); err != nil {
+		return time.Time{}, err
+	}
+	var ms uint64
+	for i := 0; i < 10; i++ {
+		ms = ms<<5 | uint64(strings.IndexByte(encoding, upper(id[i])))
+	}
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)), nil
+}
+
+// Validate checks that id is a well-formed run ID
+func Validate(id string) error {
+	if len(id) != Length {
+		return fmt.Errorf("invalid run ID '%s': expected %d characters", id, Length)
+	}
+	if upper(id[0]) > '7' {
+		return fmt.Errorf("invalid run ID '%s': out of range", id)
+	}
+	for i := 0; i < len(id); i++ {
+		if strings.IndexByte(encoding, upper(id[i])) < 0 {
+			return fmt.Errorf("invalid run ID '%s': unexpected character %q", id, id[i])
+		}
+	}
+	return nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
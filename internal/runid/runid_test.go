@@ -0,0 +1,69 @@
+package runid
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewAt_SortsByTime(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i := 0; i < 1000; i++ {
+		// Several IDs share each millisecond
+		ids = append(ids, NewAt(start.Add(time.Duration(i/10)*time.Millisecond)))
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Error("Expected IDs to sort in the order they were generated")
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("Duplicate ID %s", id)
+		}
+		seen[id] = true
+		if err := Validate(id); err != nil {
+			t.Fatalf("Validate(%s) error = %v", id, err)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 34, 56, 789000000, time.UTC)
+	got, err := Time(NewAt(at))
+	if err != nil {
+		t.Fatalf("Time() error = %v", err)
+	}
+	if !got.Equal(at) {
+		t.Errorf("Time() = %v, want %v", got, at)
+	}
+
+	// The ULID specification's example
+	got, err = Time("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if err != nil || got.UnixNano()/int64(time.Millisecond) != 1469922850259 {
+		t.Errorf("Time() = %v, %v; want 1469922850259ms", got, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{id: "01arz3ndektsv4rrffq69g5fav"},
+		{id: "01ARZ3NDEK", wantErr: true},
+		{id: "01ARZ3NDEKTSV4RRFFQ69G5FAU", wantErr: true},
+		{id: "81ARZ3NDEKTSV4RRFFQ69G5FAV", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if err := Validate(tt.id); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%s) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
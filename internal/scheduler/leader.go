@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/leader"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+)
+
+// Roles of a scheduler with leader election
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// SetElector makes the scheduler fire cron runs only while elector elects it
+// as the leader. Followers skip their cron runs with a WorkflowSkipped event;
+// manual, triggered and sub-workflow runs are not affected. Call it before Start.
+func (s *Scheduler) SetElector(elector leader.Elector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elector = elector
+	s.leading = false
+}
+
+// IsLeader reports whether the scheduler fires cron runs: always without
+// leader election, and while it is the leader with it
+func (s *Scheduler) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.elector == nil || s.leading
+}
+
+// Role returns RoleLeader or RoleFollower, or "" without leader election
+func (s *Scheduler) Role() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch {
+	case s.elector == nil:
+		return ""
+	case s.leading:
+		return RoleLeader
+	default:
+		return RoleFollower
+	}
+}
+
+// campaign takes and renews the lease three times per TTL until the
+// scheduler stops, then releases it
+func (s *Scheduler) campaign(elector leader.Elector, done chan<- struct{}) {
+	defer close(done)
+	log := logger.GetGlobalLogger()
+
+	interval := elector.TTL() / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(s.ctx, interval)
+		leading, err := elector.Acquire(ctx)
+		cancel()
+		if err != nil && s.ctx.Err() == nil {
+			// Without a lease this instance may no longer be the leader
+			log.Errorf("Leader election failed: %v", err)
+		}
+
+		s.mu.Lock()
+		changed := s.leading != leading
+		s.leading = leading
+		s.mu.Unlock()
+		if changed && leading {
+			log.Info("Became the leader; firing cron runs")
+		} else if changed {
+			log.Info("No longer the leader; skipping cron runs")
+		}
+
+		select {
+		case <-s.ctx.Done():
+			s.mu.Lock()
+			s.leading = false
+			s.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := elector.Release(ctx); err != nil {
+				log.Errorf("Failed to release the leadership: %v", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PauseWorkflow stops the cron and triggered runs of a workflow until it is
+// resumed; they are skipped with a WorkflowSkipped event. Runs in progress,
+// manual runs and sub-workflow calls are not affected. With a store the pause
+// outlasts restarts.
+func (s *Scheduler) PauseWorkflow(name string) error {
+	return s.setPaused(name, true)
+}
+
+// ResumeWorkflow lets a paused workflow run on its schedule and triggers again
+func (s *Scheduler) ResumeWorkflow(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	if _, ok := s.GetWorkflow(name); !ok {
+		return fmt.Errorf("workflow '%s' not found", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.history != nil {
+		if err := s.history.SetPaused(name, paused); err != nil {
+			return err
+		}
+	}
+	if paused {
+		s.paused[name] = true
+	} else {
+		delete(s.paused, name)
+	}
+	return nil
+}
+
+// IsPaused reports whether a workflow is paused
+func (s *Scheduler) IsPaused(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused[name]
+}
+
+// GetPausedWorkflows returns the names of the paused workflows, sorted
+func (s *Scheduler) GetPausedWorkflows() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.paused))
+	for name := range s.paused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// Plan describes what each workflow would run, without running anything.
+// Scheduled workflows include their next runs after from.
+func (s *Scheduler) Plan(from time.Time, runs int) []executor.WorkflowPlan {
+	workflows := s.GetWorkflows()
+
+	plans := make([]executor.WorkflowPlan, 0, len(workflows))
+	for _, workflow := range workflows {
+		plan := s.runner.PlanWorkflow(&workflow, executor.RunOptions{})
+		plan.NextRuns = NextRunTimes(workflow.Schedule, from, runs)
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
+
+// PlanWorkflows plans workflows without adding them to a scheduler, so tasks
+// that need privileges or functions this process lacks are planned with
+// warnings instead of failing AddWorkflows
+func PlanWorkflows(workflows []parser.Workflow, from time.Time, runs int) []executor.WorkflowPlan {
+	s := NewScheduler()
+	defer s.Stop()
+
+	s.mu.Lock()
+	s.workflows = append([]parser.Workflow(nil), workflows...)
+	s.mu.Unlock()
+	return s.Plan(from, runs)
+}
+
+// NextRunTimes returns up to count fire times of a cron schedule after from
+func NextRunTimes(schedule string, from time.Time, count int) []time.Time {
+	if schedule == "" {
+		return nil
+	}
+
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil
+	}
+
+	var times []time.Time
+	next := from
+	for len(times) < count {
+		next = parsed.Next(next)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+	}
+	return times
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// RetryExecution runs a finished run of a workflow again from a task: from,
+// or the task that failed when from is empty. That task and the tasks
+// downstream of it run again with the original run's params; the other tasks
+// that succeeded are reused along with their outputs. The new run records the
+// original in RetryOf.
+func (s *Scheduler) RetryExecution(runID, from string) (*parser.WorkflowExecution, error) {
+	original, err := s.findExecution(runID)
+	if err != nil {
+		return nil, err
+	}
+	if original.ParentID != "" {
+		return nil, fmt.Errorf("run '%s' is a sub-workflow run of '%s'; retry that run instead", runID, original.ParentID)
+	}
+
+	workflow, ok := s.GetWorkflow(original.WorkflowID)
+	if !ok {
+		return nil, fmt.Errorf("workflow '%s' of run '%s' not found", original.WorkflowID, runID)
+	}
+	if from == "" {
+		if from = original.FailedTask(); from == "" {
+			return nil, fmt.Errorf("run '%s' has no failed task; choose a task to retry from", runID)
+		}
+	}
+	found := false
+	for _, task := range workflow.Tasks {
+		found = found || task.ID == from
+	}
+	if !found {
+		return nil, fmt.Errorf("task '%s' not found in workflow '%s'", from, workflow.Name)
+	}
+
+	attempt := original.Attempt
+	if attempt == 0 {
+		attempt = 1
+	}
+	execution := s.runWorkflow(workflow, executor.RunOptions{
+		Params:        original.Params,
+		Trigger:       parser.RunTriggerManual,
+		ScheduledTime: original.ScheduledTime,
+		Attempt:       attempt + 1,
+		Retry:         original,
+		From:          from,
+	}, false)
+	return &execution, nil
+}
+
+// findExecution returns a finished run from memory or the store
+func (s *Scheduler) findExecution(runID string) (*parser.WorkflowExecution, error) {
+	s.mu.RLock()
+	history := s.history
+	for _, executions := range s.executions {
+		for i := range executions {
+			if executions[i].ID == runID {
+				execution := executions[i]
+				s.mu.RUnlock()
+				return &execution, nil
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	if history != nil {
+		execution, err := history.Get(runID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load run '%s': %w", runID, err)
+		}
+		if execution != nil {
+			return execution, nil
+		}
+	}
+	return nil, fmt.Errorf("run '%s' not found", runID)
+}
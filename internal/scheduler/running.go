@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/events"
+)
+
+// RunningExecution is the live state of a run in progress
+type RunningExecution struct {
+	RunID       string        `json:"run_id"`
+	ParentRunID string        `json:"parent_run_id,omitempty"` // run that invoked this one as a sub-workflow
+	WorkflowID  string        `json:"workflow_id"`
+	Trigger     string        `json:"trigger,omitempty"`
+	StartTime   time.Time     `json:"start_time"`
+	Elapsed     time.Duration `json:"elapsed"`
+
+	CurrentTask string        `json:"current_task,omitempty"` // task being executed, if any
+	Queued      bool          `json:"queued,omitempty"`       // the current task waits for slots
+	Attempt     int           `json:"attempt,omitempty"`      // attempt of the current task, 1-based
+	TaskStarted time.Time     `json:"task_started,omitempty"`
+	TaskElapsed time.Duration `json:"task_elapsed,omitempty"`
+}
+
+// Track follows the runs of the scheduler's workflows as they happen
+func (s *Scheduler) Track(event events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.Type == events.WorkflowStarted {
+		s.running[event.RunID] = &RunningExecution{
+			RunID:       event.RunID,
+			ParentRunID: event.ParentRunID,
+			WorkflowID:  event.Workflow,
+			Trigger:     event.Trigger,
+			StartTime:   event.Time,
+		}
+		return
+	}
+
+	run, ok := s.running[event.RunID]
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case events.TaskQueued:
+		run.CurrentTask, run.Queued, run.TaskStarted = event.Task, true, event.Time
+	case events.TaskStarted:
+		run.CurrentTask, run.Attempt, run.TaskStarted = event.Task, event.Attempt, event.Time
+		run.Queued = false
+	case events.TaskRetrying:
+		run.Attempt = event.Attempt
+	case events.TaskFinished:
+		run.CurrentTask, run.Attempt, run.TaskStarted = "", 0, time.Time{}
+		run.Queued = false
+	case events.WorkflowFinished:
+		delete(s.running, event.RunID)
+	}
+}
+
+// GetRunningExecutions returns the runs in progress, oldest first
+func (s *Scheduler) GetRunningExecutions() []RunningExecution {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]RunningExecution, 0, len(s.running))
+	for _, run := range s.running {
+		r := *run
+		r.Elapsed = now.Sub(r.StartTime)
+		if r.CurrentTask != "" {
+			r.TaskElapsed = now.Sub(r.TaskStarted)
+		}
+		result = append(result, r)
+	}
+	// Run IDs sort by start time
+	sort.Slice(result, func(i, j int) bool { return result[i].RunID < result[j].RunID })
+	return result
+}
+
+// CancelExecution cancels a run in progress: its current task is killed, the
+// remaining tasks are skipped and the run finishes as cancelled. Cancelling a
+// sub-workflow run fails the task that invoked it.
+func (s *Scheduler) CancelExecution(runID string) error {
+	if !s.runner.Cancel(runID) {
+		return fmt.Errorf("run '%s' is not in progress", runID)
+	}
+	return nil
+}
@@ -3,12 +3,18 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/sintakaridina/goliteflow/internal/events"
 	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/leader"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/notify"
 	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/store"
 )
 
 // Scheduler manages workflow execution based on cron schedules
@@ -21,6 +27,16 @@ type Scheduler struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	reportChan chan parser.WorkflowExecution
+	history    store.Store                           // optional persistent run history
+	earlier    map[string][]parser.WorkflowExecution // runs stored before this process, loaded on first use
+	entries    map[string]cron.EntryID               // cron entries of scheduled workflows, by name
+	events     *events.Bus                           // optional, receives scheduling and run events
+	unnotify   func()                                // unsubscribes the notifier
+	running    map[string]*RunningExecution          // runs in progress, by run ID
+	paused     map[string]bool                       // workflows whose cron and triggered runs are skipped
+	elector    leader.Elector                        // optional, elects the scheduler that fires cron runs
+	leading    bool                                  // whether elector elected this scheduler
+	campaigned chan struct{}                         // closed once the election stopped
 }
 
 // NewScheduler creates a new scheduler instance
@@ -30,41 +46,171 @@ func NewScheduler() *Scheduler {
 	// Create cron scheduler without seconds precision (standard cron)
 	c := cron.New()
 
-	return &Scheduler{
+	s := &Scheduler{
 		cron:       c,
 		runner:     executor.NewTaskRunner(),
 		workflows:  []parser.Workflow{},
 		executions: make(map[string][]parser.WorkflowExecution),
+		earlier:    make(map[string][]parser.WorkflowExecution),
+		entries:    make(map[string]cron.EntryID),
+		running:    make(map[string]*RunningExecution),
+		paused:     make(map[string]bool),
 		ctx:        ctx,
 		cancel:     cancel,
 		reportChan: make(chan parser.WorkflowExecution, 100),
 	}
+	s.runner.SetHistory(s)
+	s.runner.SetWorkflowLookup(s)
+	s.runner.SetTracker(s)
+
+	return s
+}
+
+// SetStore persists every finished run and the paused workflows to the
+// given store, pausing the workflows it lists as paused
+func (s *Scheduler) SetStore(history store.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = history
+
+	paused, err := history.Paused()
+	if err != nil {
+		logger.GetGlobalLogger().Errorf("Failed to load paused workflows: %v", err)
+	}
+	for _, name := range paused {
+		s.paused[name] = true
+	}
+}
+
+// RegisterTaskFunc makes a Go function available to tasks with func: name.
+// Functions must be registered before the workflows that call them are added.
+func (s *Scheduler) RegisterTaskFunc(name string, fn executor.TaskFunc) {
+	s.runner.RegisterFunc(name, fn)
+}
+
+// SetWorkQueue hands the attempts of command, container and HTTP tasks to
+// queue, such as a worker dispatcher; nil runs them in this process
+func (s *Scheduler) SetWorkQueue(queue executor.WorkQueue) {
+	s.runner.SetWorkQueue(queue)
+}
+
+// SetLimits caps the tasks running at once across workflows, 0 for no limit,
+// and sets the pools of slots tasks take with pool
+func (s *Scheduler) SetLimits(maxConcurrentTasks int, pools map[string]parser.Pool) {
+	s.runner.SetLimits(maxConcurrentTasks, pools)
+}
+
+// QueueStats returns the number of tasks running and waiting for slots
+func (s *Scheduler) QueueStats() executor.QueueStats {
+	return s.runner.QueueStats()
+}
+
+// SetEventBus publishes scheduling, run and task events to bus
+func (s *Scheduler) SetEventBus(bus *events.Bus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = bus
+	s.runner.SetEventBus(bus)
+}
+
+// SetNotifier sends notifications for the scheduler's runs, creating an
+// event bus if none is set. Stop delivers the pending notifications. With a
+// store set by SetStore, the notifier counts failed runs from before it.
+func (s *Scheduler) SetNotifier(notifier *notify.Notifier) {
+	s.mu.Lock()
+	if s.history != nil {
+		notifier.SetHistory(s.history)
+	}
+	if s.events == nil {
+		s.events = events.NewBus()
+		s.runner.SetEventBus(s.events)
+	}
+	previous := s.unnotify
+	s.unnotify = s.events.Subscribe(notifier.Handle)
+	s.mu.Unlock()
+
+	// Unsubscribing waits for the notifier, which may look up workflows
+	if previous != nil {
+		previous()
+	}
 }
 
 // AddWorkflows adds workflows to the scheduler
-func (s *Scheduler) AddWorkflows(workflows []parser.Workflow) error {
+func (s *Scheduler) AddWorkflows(workflows []parser.Workflow) (err error) {
+	// Published once the lock is released, so handlers may call the scheduler
+	var scheduled []events.Event
+	defer func() {
+		if err == nil {
+			for _, event := range scheduled {
+				s.events.Publish(event)
+			}
+		}
+	}()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Fail early when tasks need privileges this process lacks or call
+	// functions that are not registered
 	for _, workflow := range workflows {
+		if err := executor.CheckPrivileges(workflow); err != nil {
+			return err
+		}
+		if err := s.runner.CheckFuncs(workflow); err != nil {
+			return err
+		}
+	}
+
+	for _, workflow := range workflows {
+		// Workflows without a schedule only run when triggered, invoked as a
+		// sub-workflow or executed manually
+		if workflow.Schedule == "" {
+			continue
+		}
+
 		// Validate cron expression
 		if _, err := cron.ParseStandard(workflow.Schedule); err != nil {
 			return fmt.Errorf("invalid cron expression for workflow '%s': %w", workflow.Name, err)
 		}
 
-		// Add to cron scheduler
-		_, err := s.cron.AddFunc(workflow.Schedule, func() {
-			s.executeWorkflow(workflow)
+		// Add to cron scheduler; each entry runs its own workflow
+		workflow := workflow
+		entry, err := s.cron.AddFunc(workflow.Schedule, func() {
+			s.executeWorkflow(workflow, s.scheduledTime(workflow.Name))
 		})
 		if err != nil {
 			return fmt.Errorf("failed to add workflow '%s' to scheduler: %w", workflow.Name, err)
 		}
+		s.entries[workflow.Name] = entry
+
+		schedule, _ := cron.ParseStandard(workflow.Schedule) // checked above
+		scheduled = append(scheduled, events.Event{Type: events.WorkflowScheduled, Workflow: workflow.Name, Next: schedule.Next(time.Now())})
 	}
 
 	s.workflows = append(s.workflows, workflows...)
 	return nil
 }
 
+// RemoveWorkflow unschedules a workflow and removes it from the scheduler.
+// Runs in progress finish; its execution history is kept.
+func (s *Scheduler) RemoveWorkflow(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, workflow := range s.workflows {
+		if workflow.Name != name {
+			continue
+		}
+		if entry, ok := s.entries[name]; ok {
+			s.cron.Remove(entry)
+			delete(s.entries, name)
+		}
+		s.workflows = append(s.workflows[:i:i], s.workflows[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("workflow '%s' not found", name)
+}
+
 // Start starts the scheduler
 func (s *Scheduler) Start() error {
 	s.mu.Lock()
@@ -75,36 +221,318 @@ func (s *Scheduler) Start() error {
 	}
 
 	s.cron.Start()
+	if s.elector != nil && s.campaigned == nil {
+		s.campaigned = make(chan struct{})
+		go s.campaign(s.elector, s.campaigned)
+	}
 	return nil
 }
 
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if s.cron != nil {
 		s.cron.Stop()
 	}
 	s.cancel()
 	close(s.reportChan)
+	unnotify := s.unnotify
+	s.unnotify = nil
+	campaigned := s.campaigned
+	s.mu.Unlock()
+
+	// Hand the leadership over before returning
+	if campaigned != nil {
+		<-campaigned
+	}
+
+	// Deliver pending notifications; the notifier may look up workflows
+	if unnotify != nil {
+		unnotify()
+	}
+}
+
+// scheduledTime returns the schedule time of the cron run of a workflow in
+// progress: cron sets its entry's previous time before starting the run
+func (s *Scheduler) scheduledTime(name string) time.Time {
+	s.mu.RLock()
+	entry, ok := s.entries[name]
+	s.mu.RUnlock()
+	if !ok {
+		return time.Time{}
+	}
+	return s.cron.Entry(entry).Prev
+}
+
+// executeWorkflow executes a workflow for the schedule time scheduled,
+// unless it is paused or another scheduler is the leader
+func (s *Scheduler) executeWorkflow(workflow parser.Workflow, scheduled time.Time) {
+	s.mu.RLock()
+	paused, follower, bus := s.paused[workflow.Name], s.elector != nil && !s.leading, s.events
+	s.mu.RUnlock()
+	if paused {
+		bus.Publish(events.Event{Type: events.WorkflowSkipped, Workflow: workflow.Name, Reason: "workflow is paused"})
+		return
+	}
+	if follower {
+		bus.Publish(events.Event{Type: events.WorkflowSkipped, Workflow: workflow.Name, Reason: "another scheduler is the leader"})
+		return
+	}
+	s.runWorkflow(workflow, executor.RunOptions{Trigger: parser.RunTriggerCron, ScheduledTime: scheduled}, true)
 }
 
-// executeWorkflow executes a single workflow
-func (s *Scheduler) executeWorkflow(workflow parser.Workflow) {
-	execution := s.runner.ExecuteWorkflow(s.ctx, &workflow)
+// runWorkflow executes a workflow, stores the result and runs the workflows it triggers.
+// opts.Chain lists the upstream workflows that led to this run, oldest first.
+func (s *Scheduler) runWorkflow(workflow parser.Workflow, opts executor.RunOptions, report bool) parser.WorkflowExecution {
+	execution := s.runner.ExecuteWorkflowWithOptions(s.ctx, &workflow, opts)
 
 	// Store execution result
+	earlier := s.earlierRuns(workflow)
 	s.mu.Lock()
+	previous := append(earlier[:len(earlier):len(earlier)], s.executions[workflow.Name]...)
+	missed := checkConsecutiveFailures(workflow, previous, &execution)
 	s.executions[workflow.Name] = append(s.executions[workflow.Name], execution)
+	history := s.history
+	bus := s.events
 	s.mu.Unlock()
 
-	// Send to report channel
-	select {
-	case s.reportChan <- execution:
-	default:
-		// Channel is full, skip this report
+	for _, violation := range missed {
+		violation := violation
+		bus.Publish(events.Event{Type: events.SLAMissed, Workflow: workflow.Name, Task: violation.Task,
+			Reason: violation.Message, Violation: &violation})
 	}
+
+	if history != nil {
+		if err := history.Save(execution); err != nil {
+			logger.GetGlobalLogger().WithExecution(execution.ID).Errorf("Failed to save run of workflow '%s': %v", workflow.Name, err)
+		}
+	}
+
+	if report {
+		// Send to report channel
+		select {
+		case s.reportChan <- execution:
+		default:
+			// Channel is full, skip this report
+		}
+	}
+
+	s.fireTriggers(execution, opts.Chain, report)
+	return execution
+}
+
+// earlierRuns returns the runs of a workflow with a max_consecutive_failures
+// SLA that the store recorded before this process ran it, so failures in a
+// row are counted across restarts
+func (s *Scheduler) earlierRuns(workflow parser.Workflow) []parser.WorkflowExecution {
+	if !hasConsecutiveFailuresSLA(workflow) {
+		return nil
+	}
+	s.mu.RLock()
+	runs, loaded := s.earlier[workflow.Name]
+	history := s.history
+	s.mu.RUnlock()
+	if loaded || history == nil {
+		return runs
+	}
+
+	stored, err := history.List(workflow.Name)
+	if err != nil {
+		logger.GetGlobalLogger().Errorf("Failed to load the runs of workflow '%s': %v", workflow.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runs, loaded := s.earlier[workflow.Name]; loaded {
+		return runs
+	}
+	// Runs of this process are already in memory
+	own := make(map[string]bool)
+	for _, execution := range s.executions[workflow.Name] {
+		own[execution.ID] = true
+	}
+	runs = nil
+	for _, execution := range stored {
+		if !own[execution.ID] {
+			runs = append(runs, execution)
+		}
+	}
+	s.earlier[workflow.Name] = runs
+	return runs
+}
+
+// hasConsecutiveFailuresSLA reports whether a workflow or one of its tasks
+// has a max_consecutive_failures SLA
+func hasConsecutiveFailuresSLA(workflow parser.Workflow) bool {
+	if workflow.SLA != nil && workflow.SLA.MaxConsecutiveFailures > 0 {
+		return true
+	}
+	for _, task := range workflow.Tasks {
+		if task.SLA != nil && task.SLA.MaxConsecutiveFailures > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConsecutiveFailures records on execution the max_consecutive_failures
+// SLAs of the workflow and its tasks that it breaches, given the earlier runs
+func checkConsecutiveFailures(workflow parser.Workflow, previous []parser.WorkflowExecution, execution *parser.WorkflowExecution) []parser.SLAViolation {
+	var missed []parser.SLAViolation
+	check := func(sla *parser.SLA, task string) {
+		if sla == nil || sla.MaxConsecutiveFailures <= 0 {
+			return
+		}
+		failures := consecutiveFailures(append(previous[:len(previous):len(previous)], *execution), task)
+		if failures < sla.MaxConsecutiveFailures {
+			return
+		}
+		subject := fmt.Sprintf("workflow '%s'", workflow.Name)
+		if task != "" {
+			subject = fmt.Sprintf("task '%s' of workflow '%s'", task, workflow.Name)
+		}
+		missed = append(missed, parser.SLAViolation{
+			Type:    parser.SLAMaxConsecutiveFailures,
+			Task:    task,
+			Time:    time.Now(),
+			Message: fmt.Sprintf("%s failed %d times in a row (max_consecutive_failures %d)", subject, failures, sla.MaxConsecutiveFailures),
+		})
+	}
+
+	check(workflow.SLA, "")
+	for _, task := range workflow.Tasks {
+		check(task.SLA, task.ID)
+	}
+	execution.SLAViolations = append(execution.SLAViolations, missed...)
+	return missed
+}
+
+// consecutiveFailures counts the failed runs at the end of executions, or the
+// runs in which task failed; runs in which the task did not run are skipped
+func consecutiveFailures(executions []parser.WorkflowExecution, task string) int {
+	failures := 0
+	for i := len(executions) - 1; i >= 0; i-- {
+		status := executions[i].Status
+		if task != "" {
+			status = ""
+			for _, result := range executions[i].TaskResults {
+				if result.TaskID == task {
+					status = "completed"
+					if !result.Success {
+						status = "failed"
+					}
+				}
+			}
+			if status == "" {
+				continue
+			}
+		}
+		if status == "cancelled" {
+			// Cancelled runs neither fail nor recover
+			continue
+		}
+		if status != "failed" {
+			break
+		}
+		failures++
+	}
+	return failures
+}
+
+// fireTriggers runs the workflows whose triggered_by matches a finished execution
+func (s *Scheduler) fireTriggers(execution parser.WorkflowExecution, chain []string, report bool) {
+	next := append(append([]string{}, chain...), execution.WorkflowID)
+
+	s.mu.RLock()
+	var downstream []parser.Workflow
+	var skipped []events.Event
+	for _, workflow := range s.workflows {
+		triggered, matched := false, false
+		for _, trigger := range workflow.TriggeredBy {
+			if trigger.Workflow == execution.WorkflowID {
+				triggered = true
+				matched = matched || trigger.Matches(execution.Status)
+			}
+		}
+
+		switch {
+		case !triggered:
+		case s.paused[workflow.Name]:
+			skipped = append(skipped, events.Event{Type: events.WorkflowSkipped, Workflow: workflow.Name,
+				Reason: "workflow is paused"})
+		case containsString(next, workflow.Name):
+			// Never re-enter a workflow already in the chain
+			skipped = append(skipped, events.Event{Type: events.WorkflowSkipped, Workflow: workflow.Name,
+				Reason: fmt.Sprintf("already part of the trigger chain %s", strings.Join(next, " -> "))})
+		case matched:
+			downstream = append(downstream, workflow)
+		default:
+			skipped = append(skipped, events.Event{Type: events.WorkflowSkipped, Workflow: workflow.Name,
+				Reason: fmt.Sprintf("upstream workflow '%s' %s", execution.WorkflowID, execution.Status)})
+		}
+	}
+	bus := s.events
+	s.mu.RUnlock()
+
+	for _, event := range skipped {
+		bus.Publish(event)
+	}
+
+	for _, workflow := range downstream {
+		s.runWorkflow(workflow, executor.RunOptions{Chain: next, Trigger: parser.RunTriggerEvent}, report)
+	}
+}
+
+// LatestExecution returns the most recent finished run of a workflow with the given status.
+// An empty status matches any run. Runs of earlier processes are looked up in
+// the store, if any.
+func (s *Scheduler) LatestExecution(workflowName, status string) (parser.WorkflowExecution, bool) {
+	s.mu.RLock()
+	executions := s.executions[workflowName]
+	history := s.history
+	for i := len(executions) - 1; i >= 0; i-- {
+		if status == "" || executions[i].Status == status {
+			execution := executions[i]
+			s.mu.RUnlock()
+			return execution, true
+		}
+	}
+	s.mu.RUnlock()
+
+	if history == nil {
+		return parser.WorkflowExecution{}, false
+	}
+	execution, err := history.Latest(workflowName, status)
+	if err != nil {
+		logger.GetGlobalLogger().Errorf("Failed to load the latest run of workflow '%s': %v", workflowName, err)
+		return parser.WorkflowExecution{}, false
+	}
+	if execution == nil {
+		return parser.WorkflowExecution{}, false
+	}
+	return *execution, true
+}
+
+// GetWorkflow returns a configured workflow by name
+func (s *Scheduler) GetWorkflow(name string) (parser.Workflow, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, workflow := range s.workflows {
+		if workflow.Name == name {
+			return workflow, true
+		}
+	}
+	return parser.Workflow{}, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // GetExecutions returns all executions for a workflow
@@ -149,7 +577,8 @@ func (s *Scheduler) GetWorkflows() []parser.Workflow {
 	return result
 }
 
-// ExecuteWorkflowNow executes a workflow immediately (for testing or manual triggers)
+// ExecuteWorkflowNow executes a workflow immediately (for testing or manual
+// triggers), even when it is paused
 func (s *Scheduler) ExecuteWorkflowNow(workflowName string) (*parser.WorkflowExecution, error) {
 	s.mu.RLock()
 	var targetWorkflow *parser.Workflow
@@ -165,12 +594,7 @@ func (s *Scheduler) ExecuteWorkflowNow(workflowName string) (*parser.WorkflowExe
 		return nil, fmt.Errorf("workflow '%s' not found", workflowName)
 	}
 
-	execution := s.runner.ExecuteWorkflow(s.ctx, targetWorkflow)
-
-	// Store execution result
-	s.mu.Lock()
-	s.executions[workflowName] = append(s.executions[workflowName], execution)
-	s.mu.Unlock()
+	execution := s.runWorkflow(*targetWorkflow, executor.RunOptions{Trigger: parser.RunTriggerManual}, false)
 
 	return &execution, nil
 }
@@ -205,6 +629,7 @@ func (s *Scheduler) GetStats() SchedulerStats {
 		SuccessfulExecutions: 0,
 		FailedExecutions:     0,
 		NextRuns:             make(map[string]time.Time),
+		Queue:                s.QueueStats(),
 	}
 
 	for _, workflow := range s.workflows {
@@ -235,4 +660,5 @@ type SchedulerStats struct {
 	SuccessfulExecutions int                  `json:"successful_executions"`
 	FailedExecutions     int                  `json:"failed_executions"`
 	NextRuns             map[string]time.Time `json:"next_runs"`
+	Queue                executor.QueueStats  `json:"queue"` // tasks running and waiting for slots
 }
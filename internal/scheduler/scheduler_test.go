@@ -1,10 +1,22 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/leader"
 	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
+	"github.com/sintakaridina/goliteflow/internal/store"
 )
 
 func TestScheduler_AddWorkflows(t *testing.T) {
@@ -58,6 +70,47 @@ func TestScheduler_AddWorkflows_InvalidCron(t *testing.T) {
 	}
 }
 
+func TestScheduler_AddWorkflows_UnknownRunAsUser(t *testing.T) {
+	sched := NewScheduler()
+
+	workflows := []parser.Workflow{
+		{
+			Name:     "isolated",
+			Schedule: "0 0 * * *",
+			RunAs:    &parser.RunAs{User: "no-such-user-goliteflow"},
+			Tasks: []parser.Task{
+				{ID: "task1", Command: "echo hello"},
+			},
+		},
+	}
+
+	err := sched.AddWorkflows(workflows)
+	if err == nil || !strings.Contains(err.Error(), "unknown user 'no-such-user-goliteflow'") {
+		t.Errorf("Expected an unknown user error, got %v", err)
+	}
+}
+
+func TestScheduler_RemoveWorkflow(t *testing.T) {
+	sched := NewScheduler()
+	workflows := []parser.Workflow{
+		{Name: "keep", Schedule: "0 0 * * *", Tasks: []parser.Task{{ID: "a", Command: "echo a"}}},
+		{Name: "drop", Schedule: "0 1 * * *", Tasks: []parser.Task{{ID: "b", Command: "echo b"}}},
+	}
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	if err := sched.RemoveWorkflow("drop"); err != nil {
+		t.Fatalf("RemoveWorkflow() error = %v", err)
+	}
+	if len(sched.GetWorkflows()) != 1 || len(sched.cron.Entries()) != 1 {
+		t.Errorf("Expected one workflow and cron entry, got %d and %d", len(sched.GetWorkflows()), len(sched.cron.Entries()))
+	}
+	if err := sched.RemoveWorkflow("drop"); err == nil {
+		t.Error("Expected an error removing an unknown workflow")
+	}
+}
+
 func TestScheduler_ExecuteWorkflowNow(t *testing.T) {
 	sched := NewScheduler()
 
@@ -86,6 +139,118 @@ func TestScheduler_ExecuteWorkflowNow(t *testing.T) {
 	if execution.Status != "completed" {
 		t.Errorf("Expected status completed, got %s", execution.Status)
 	}
+
+	if execution.ID == "" || execution.Trigger != parser.RunTriggerManual {
+		t.Errorf("Expected a manual run with an ID, got ID %q and trigger %s", execution.ID, execution.Trigger)
+	}
+}
+
+func TestScheduler_ConsecutiveFailuresSLA(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ready")
+	sched := NewScheduler()
+	bus := events.NewBus()
+	var missed []string
+	bus.Subscribe(func(e events.Event) {
+		if e.Type == events.SLAMissed {
+			missed = append(missed, e.Task)
+		}
+	}, events.Sync())
+	sched.SetEventBus(bus)
+
+	workflow := parser.Workflow{
+		Name:     "flaky",
+		Schedule: "0 0 * * *",
+		SLA:      &parser.SLA{MaxConsecutiveFailures: 2},
+		Tasks: []parser.Task{
+			{ID: "check", Command: "test -e " + marker, SLA: &parser.SLA{MaxConsecutiveFailures: 3}},
+		},
+	}
+	if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	var violations []int
+	for i := 0; i < 4; i++ {
+		if i == 3 {
+			if err := os.WriteFile(marker, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		execution, err := sched.ExecuteWorkflowNow("flaky")
+		if err != nil {
+			t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+		}
+		violations = append(violations, len(execution.SLAViolations))
+	}
+
+	if fmt.Sprint(violations) != "[0 1 2 0]" {
+		t.Errorf("Expected SLA violations [0 1 2 0] per run, got %v", violations)
+	}
+	if strings.Join(missed, ",") != ",,check" {
+		t.Errorf("Expected SLAMissed events for the workflow, the workflow and the task, got %q", missed)
+	}
+	recorded := sched.GetExecutions("flaky")[2].SLAViolations
+	if recorded[1].Type != parser.SLAMaxConsecutiveFailures || !strings.Contains(recorded[1].Message, "failed 3 times in a row") {
+		t.Errorf("Unexpected recorded violation: %+v", recorded[1])
+	}
+}
+
+func TestScheduler_ConsecutiveFailuresSLAAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	workflow := parser.Workflow{
+		Name:     "flaky",
+		Schedule: "0 0 * * *",
+		SLA:      &parser.SLA{MaxConsecutiveFailures: 2},
+		Tasks:    []parser.Task{{ID: "check", Command: "false"}},
+	}
+
+	// Each scheduler stands for a process restarted between the runs
+	var violations []int
+	for i := 0; i < 2; i++ {
+		sched := NewScheduler()
+		sched.SetStore(store.NewFileStore(dir))
+		if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+			t.Fatalf("AddWorkflows() error = %v", err)
+		}
+		execution, err := sched.ExecuteWorkflowNow("flaky")
+		if err != nil {
+			t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+		}
+		violations = append(violations, len(execution.SLAViolations))
+		sched.Stop()
+	}
+
+	if fmt.Sprint(violations) != "[0 1]" {
+		t.Errorf("Expected the stored failure to count after the restart, got violations %v", violations)
+	}
+}
+
+func TestScheduler_SetStore(t *testing.T) {
+	sched := NewScheduler()
+	history := store.NewFileStore(t.TempDir())
+	sched.SetStore(history)
+
+	workflow := parser.Workflow{
+		Name:     "test",
+		Schedule: "0 0 * * *",
+		Tasks: []parser.Task{
+			{ID: "task1", Command: "echo hello"},
+		},
+	}
+	if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+	if _, err := sched.ExecuteWorkflowNow("test"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+
+	latest, err := history.Latest("test", "")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if latest == nil || latest.Status != "completed" || len(latest.TaskResults) != 1 {
+		t.Errorf("Expected the completed run to be saved, got %+v", latest)
+	}
 }
 
 func TestScheduler_ExecuteWorkflowNow_NotFound(t *testing.T) {
@@ -202,3 +367,578 @@ func TestScheduler_StartStop(t *testing.T) {
 
 	// Scheduler should be stopped (no way to directly test this, but it shouldn't panic)
 }
+
+func TestScheduler_GetRunningExecutions(t *testing.T) {
+	sched := NewScheduler()
+
+	workflow := parser.Workflow{
+		Name: "slow",
+		Tasks: []parser.Task{
+			{ID: "first", Command: "true"},
+			{ID: "wait", Command: "sleep 1", DependsOn: []string{"first"}},
+		},
+	}
+	if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	done := make(chan *parser.WorkflowExecution)
+	go func() {
+		execution, _ := sched.ExecuteWorkflowNow("slow")
+		done <- execution
+	}()
+
+	var running []RunningExecution
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		running = sched.GetRunningExecutions()
+		if len(running) == 1 && running[0].CurrentTask == "wait" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(running) != 1 || running[0].CurrentTask != "wait" {
+		t.Fatalf("Expected slow to be running task wait, got %+v", running)
+	}
+	run := running[0]
+	if run.WorkflowID != "slow" || run.Trigger != parser.RunTriggerManual || run.Attempt != 1 || run.Elapsed <= 0 || run.TaskElapsed > run.Elapsed {
+		t.Errorf("Unexpected live state: %+v", run)
+	}
+
+	execution := <-done
+	if execution.ID != run.RunID {
+		t.Errorf("Expected run ID %s, got %s", execution.ID, run.RunID)
+	}
+	if running := sched.GetRunningExecutions(); len(running) != 0 {
+		t.Errorf("Expected no runs in progress after the run finished, got %+v", running)
+	}
+}
+
+func TestScheduler_SetLimits(t *testing.T) {
+	sched := NewScheduler()
+	sched.SetLimits(0, map[string]parser.Pool{"db": {Slots: 1}})
+
+	release := make(chan struct{})
+	sched.RegisterTaskFunc("block", func(ctx context.Context, tc executor.TaskContext) (map[string]string, error) {
+		<-release
+		return nil, nil
+	})
+	workflows := []parser.Workflow{
+		{Name: "load", Tasks: []parser.Task{{ID: "load", Func: "block", Pool: "db"}}},
+		{Name: "vacuum", Tasks: []parser.Task{{ID: "vacuum", Command: "true", Pool: "db"}}},
+	}
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	done := make(chan *parser.WorkflowExecution, 2)
+	for _, name := range []string{"load", "vacuum"} {
+		go func(name string) {
+			execution, _ := sched.ExecuteWorkflowNow(name)
+			done <- execution
+		}(name)
+		// load takes the pool's slot first
+		for sched.QueueStats().Running == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sched.GetStats().Queue.Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := sched.GetStats().Queue
+	if stats.Running != 1 || stats.Queued != 1 || stats.Pools["db"] != (executor.PoolStats{Slots: 1, Used: 1, Queued: 1}) {
+		t.Errorf("Unexpected queue stats: %+v", stats)
+	}
+	for _, run := range sched.GetRunningExecutions() {
+		if run.Queued != (run.WorkflowID == "vacuum") {
+			t.Errorf("Unexpected live state: %+v", run)
+		}
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		execution := <-done
+		if execution.Status != "completed" {
+			t.Errorf("Expected %s to complete, got %s", execution.WorkflowID, execution.Status)
+		}
+		if execution.WorkflowID == "vacuum" && execution.TaskResults[0].QueueTime <= 0 {
+			t.Errorf("Expected vacuum to record its queue time, got %+v", execution.TaskResults[0])
+		}
+	}
+}
+
+func TestScheduler_CronEntries(t *testing.T) {
+	sched := NewScheduler()
+	workflows := []parser.Workflow{
+		{Name: "first", Schedule: "0 0 * * *", Tasks: []parser.Task{{ID: "task1", Command: "true"}}},
+		{Name: "second", Schedule: "0 1 * * *", Tasks: []parser.Task{{ID: "task1", Command: "true"}}},
+	}
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	// Fire the cron entry of the first workflow as cron would
+	sched.cron.Entry(sched.entries["first"]).Job.Run()
+
+	if runs := sched.GetExecutions("first"); len(runs) != 1 || runs[0].Trigger != parser.RunTriggerCron {
+		t.Errorf("Expected one cron run of first, got %+v", runs)
+	}
+	if runs := sched.GetExecutions("second"); len(runs) != 0 {
+		t.Errorf("Expected second not to run, got %d runs", len(runs))
+	}
+}
+
+func TestScheduler_CancelExecution(t *testing.T) {
+	sched := NewScheduler()
+
+	workflow := parser.Workflow{
+		Name: "runaway",
+		Tasks: []parser.Task{
+			{ID: "hang", Command: "sleep 30"},
+			{ID: "after", Command: "true", DependsOn: []string{"hang"}},
+		},
+	}
+	if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	done := make(chan *parser.WorkflowExecution)
+	go func() {
+		execution, _ := sched.ExecuteWorkflowNow("runaway")
+		done <- execution
+	}()
+
+	var running []RunningExecution
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if running = sched.GetRunningExecutions(); len(running) == 1 && running[0].CurrentTask == "hang" {
+			break
+		}
+	}
+	if len(running) != 1 {
+		t.Fatalf("Expected runaway to be running, got %+v", running)
+	}
+	if err := sched.CancelExecution(running[0].RunID); err != nil {
+		t.Fatalf("CancelExecution() error = %v", err)
+	}
+
+	select {
+	case execution := <-done:
+		if execution.Status != "cancelled" || len(execution.TaskResults) != 1 {
+			t.Errorf("Expected a cancelled run that skipped the remaining task, got %s with %d results", execution.Status, len(execution.TaskResults))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the cancelled run to finish promptly")
+	}
+
+	if err := sched.CancelExecution(running[0].RunID); err == nil {
+		t.Error("Expected an error cancelling a finished run")
+	}
+}
+
+func TestScheduler_PauseWorkflow(t *testing.T) {
+	history := store.NewFileStore(t.TempDir())
+	workflows := []parser.Workflow{
+		{Name: "extract", Schedule: "0 0 * * *", Tasks: []parser.Task{{ID: "task1", Command: "true"}}},
+		{
+			Name:        "load",
+			Schedule:    "0 1 * * *",
+			TriggeredBy: []parser.WorkflowTrigger{{Workflow: "extract"}},
+			Tasks:       []parser.Task{{ID: "task1", Command: "true"}},
+		},
+	}
+
+	sched := NewScheduler()
+	sched.SetStore(history)
+	bus := events.NewBus()
+	var skipped []events.Event
+	bus.Subscribe(func(e events.Event) {
+		if e.Type == events.WorkflowSkipped {
+			skipped = append(skipped, e)
+		}
+	}, events.Sync())
+	sched.SetEventBus(bus)
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	if err := sched.PauseWorkflow("missing"); err == nil {
+		t.Error("Expected an error pausing an unknown workflow")
+	}
+	if err := sched.PauseWorkflow("load"); err != nil {
+		t.Fatalf("PauseWorkflow() error = %v", err)
+	}
+
+	// Neither the trigger nor the schedule runs a paused workflow
+	if _, err := sched.ExecuteWorkflowNow("extract"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	sched.executeWorkflow(workflows[1], time.Now())
+	if len(skipped) != 2 || skipped[0].Workflow != "load" || skipped[1].Reason != "workflow is paused" {
+		t.Errorf("Expected both runs of load to be skipped, got %+v", skipped)
+	}
+	if runs := sched.GetExecutions("load"); len(runs) != 0 {
+		t.Errorf("Expected load not to run while paused, got %d runs", len(runs))
+	}
+
+	// The pause outlasts a restart
+	restarted := NewScheduler()
+	restarted.SetStore(history)
+	if err := restarted.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+	if !restarted.IsPaused("load") || restarted.IsPaused("extract") {
+		t.Fatalf("Expected only load to be paused after a restart, got %v", restarted.GetPausedWorkflows())
+	}
+
+	if err := restarted.ResumeWorkflow("load"); err != nil {
+		t.Fatalf("ResumeWorkflow() error = %v", err)
+	}
+	if _, err := restarted.ExecuteWorkflowNow("extract"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	if runs := restarted.GetExecutions("load"); len(runs) != 1 {
+		t.Errorf("Expected load to run once resumed, got %d runs", len(runs))
+	}
+	if paused, _ := history.Paused(); len(paused) != 0 {
+		t.Errorf("Expected the store to record the resume, got %v", paused)
+	}
+}
+
+func TestScheduler_LeaderElection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lease")
+	workflow := parser.Workflow{Name: "nightly", Schedule: "0 2 * * *", Tasks: []parser.Task{{ID: "task1", Command: "true"}}}
+
+	newScheduler := func() (*Scheduler, *int32) {
+		sched := NewScheduler()
+		sched.SetElector(leader.NewFileLease(path, 150*time.Millisecond))
+		var skipped int32
+		bus := events.NewBus()
+		bus.Subscribe(func(e events.Event) {
+			if e.Type == events.WorkflowSkipped && e.Reason == "another scheduler is the leader" {
+				atomic.AddInt32(&skipped, 1)
+			}
+		}, events.Sync())
+		sched.SetEventBus(bus)
+		if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+			t.Fatalf("AddWorkflows() error = %v", err)
+		}
+		return sched, &skipped
+	}
+	waitForRole := func(sched *Scheduler, role string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for sched.Role() != role {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected role %s, got %s", role, sched.Role())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	first, firstSkipped := newScheduler()
+	if first.Role() != RoleFollower || first.IsLeader() {
+		t.Fatalf("Expected a scheduler to follow until elected, got %s", first.Role())
+	}
+	if err := first.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitForRole(first, RoleLeader)
+
+	second, secondSkipped := newScheduler()
+	if err := second.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer second.Stop()
+	time.Sleep(100 * time.Millisecond)
+	if second.Role() != RoleFollower {
+		t.Fatalf("Expected the second scheduler to follow, got %s", second.Role())
+	}
+
+	// Only the leader fires cron runs
+	first.executeWorkflow(workflow, time.Now())
+	second.executeWorkflow(workflow, time.Now())
+	if runs := len(first.GetExecutions("nightly")); runs != 1 || atomic.LoadInt32(firstSkipped) != 0 {
+		t.Errorf("Expected the leader to run the workflow, got %d runs", runs)
+	}
+	if runs := len(second.GetExecutions("nightly")); runs != 0 || atomic.LoadInt32(secondSkipped) != 1 {
+		t.Errorf("Expected the follower to skip the workflow, got %d runs", runs)
+	}
+
+	// Stopping the leader releases the lease at once
+	first.Stop()
+	waitForRole(second, RoleLeader)
+}
+
+func TestScheduler_RetryExecution(t *testing.T) {
+	history := store.NewFileStore(t.TempDir())
+	var extracts, loads int32
+	newScheduler := func() *Scheduler {
+		sched := NewScheduler()
+		sched.SetStore(history)
+		sched.RegisterTaskFunc("extract", func(ctx context.Context, tc executor.TaskContext) (map[string]string, error) {
+			atomic.AddInt32(&extracts, 1)
+			return map[string]string{"rows": "42"}, nil
+		})
+		sched.RegisterTaskFunc("load", func(ctx context.Context, tc executor.TaskContext) (map[string]string, error) {
+			if atomic.AddInt32(&loads, 1) == 1 {
+				return nil, errors.New("database unavailable")
+			}
+			return nil, nil
+		})
+		workflow := parser.Workflow{
+			Name:   "etl",
+			Params: map[string]string{"env": "dev"},
+			Tasks: []parser.Task{
+				{ID: "extract", Func: "extract"},
+				{ID: "load", Func: "load", DependsOn: []string{"extract"}},
+				{ID: "cleanup", Command: "true"},
+				{ID: "report", Command: "echo {{ .Params.env }} {{ .Outputs.extract.rows }}", DependsOn: []string{"load"}},
+			},
+		}
+		if err := sched.AddWorkflows([]parser.Workflow{workflow}); err != nil {
+			t.Fatalf("AddWorkflows() error = %v", err)
+		}
+		return sched
+	}
+
+	sched := newScheduler()
+	first := sched.runWorkflow(sched.workflows[0], executor.RunOptions{Params: map[string]string{"env": "prod"}}, false)
+	if first.Status != "failed" || first.FailedTask() != "load" {
+		t.Fatalf("Expected the first run to fail at load, got %s (%s)", first.Status, first.ErrorMessage)
+	}
+
+	second, err := sched.RetryExecution(first.ID, "")
+	if err != nil {
+		t.Fatalf("RetryExecution() error = %v", err)
+	}
+	if second.Status != "completed" || second.RetryOf != first.ID || second.Attempt != 2 {
+		t.Fatalf("Expected a completed second attempt of %s, got %s, retry of %s, attempt %d", first.ID, second.Status, second.RetryOf, second.Attempt)
+	}
+	var reused []string
+	for _, result := range second.TaskResults {
+		if result.Reused {
+			reused = append(reused, result.TaskID)
+		}
+	}
+	if strings.Join(reused, ",") != "extract" || extracts != 1 {
+		t.Errorf("Expected only extract to be reused, got %v (%d extract runs)", reused, extracts)
+	}
+	if got := second.TaskResults[3].Stdout; got != "prod 42\n" {
+		t.Errorf("Expected the original params and reused outputs in the report, got %q", got)
+	}
+
+	// A retry after a restart finds the run in the store
+	restarted := newScheduler()
+	third, err := restarted.RetryExecution(second.ID, "report")
+	if err != nil {
+		t.Fatalf("RetryExecution() error = %v", err)
+	}
+	if len(third.TaskResults) != 4 || !third.TaskResults[2].Reused || third.TaskResults[3].Reused || loads != 2 || third.Attempt != 3 {
+		t.Errorf("Expected only report to run again, got %+v", third.TaskResults)
+	}
+
+	for _, tt := range []struct{ runID, from, want string }{
+		{runid.New(), "", "not found"},
+		{second.ID, "", "no failed task"},
+		{first.ID, "missing", "task 'missing' not found"},
+	} {
+		if _, err := restarted.RetryExecution(tt.runID, tt.from); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("RetryExecution(%s, %q) error = %v, want %q", tt.runID, tt.from, err, tt.want)
+		}
+	}
+}
+
+func TestScheduler_TriggeredWorkflows(t *testing.T) {
+	sched := NewScheduler()
+
+	workflows := []parser.Workflow{
+		{
+			Name:     "extract",
+			Schedule: "0 0 * * *",
+			Tasks:    []parser.Task{{ID: "task1", Command: "echo extract"}},
+		},
+		{
+			Name:        "load",
+			TriggeredBy: []parser.WorkflowTrigger{{Workflow: "extract"}},
+			Tasks:       []parser.Task{{ID: "task1", Command: "echo load"}},
+		},
+		{
+			Name:        "alert",
+			TriggeredBy: []parser.WorkflowTrigger{{Workflow: "extract", Status: parser.TriggerOnFailed}},
+			Tasks:       []parser.Task{{ID: "task1", Command: "echo alert"}},
+		},
+	}
+
+	bus := events.NewBus()
+	var got []events.Event
+	bus.Subscribe(func(e events.Event) {
+		if e.Type == events.WorkflowScheduled || e.Type == events.WorkflowSkipped {
+			got = append(got, e)
+		}
+	}, events.Sync())
+	sched.SetEventBus(bus)
+
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	if _, err := sched.ExecuteWorkflowNow("extract"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].Workflow != "extract" || got[0].Next.IsZero() {
+		t.Fatalf("Expected a scheduled and a skipped event, got %+v", got)
+	}
+	if got[1].Workflow != "alert" || got[1].Reason != "upstream workflow 'extract' completed" {
+		t.Errorf("Expected alert to be skipped, got %+v", got[1])
+	}
+
+	loads := sched.GetExecutions("load")
+	if len(loads) != 1 {
+		t.Fatalf("Expected load to run once, got %d", len(loads))
+	}
+	if loads[0].TriggeredBy != "extract" || len(loads[0].Chain) != 1 || loads[0].Trigger != parser.RunTriggerEvent {
+		t.Errorf("Expected load to be triggered by extract, got %q (chain %v, trigger %s)", loads[0].TriggeredBy, loads[0].Chain, loads[0].Trigger)
+	}
+
+	if alerts := sched.GetExecutions("alert"); len(alerts) != 0 {
+		t.Errorf("Expected alert not to run after a successful extract, got %d runs", len(alerts))
+	}
+}
+
+func TestScheduler_WaitForExternalWorkflow(t *testing.T) {
+	sched := NewScheduler()
+
+	workflows := []parser.Workflow{
+		{
+			Name:     "extract",
+			Schedule: "0 0 * * *",
+			Tasks:    []parser.Task{{ID: "task1", Command: "echo extract"}},
+		},
+		{
+			Name:     "report",
+			Schedule: "0 6 * * *",
+			Tasks: []parser.Task{
+				{ID: "wait", Timeout: "200ms", WaitFor: &parser.ExternalDependency{Workflow: "extract", Within: "1h", PollInterval: "50ms"}},
+				{ID: "build", Command: "echo report", DependsOn: []string{"wait"}},
+			},
+		},
+	}
+
+	if err := sched.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	// Without a successful upstream run the sensor times out
+	execution, err := sched.ExecuteWorkflowNow("report")
+	if err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	if execution.Status != "failed" {
+		t.Errorf("Expected report to fail without an upstream run, got %s", execution.Status)
+	}
+
+	if _, err := sched.ExecuteWorkflowNow("extract"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+
+	execution, err = sched.ExecuteWorkflowNow("report")
+	if err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	if execution.Status != "completed" {
+		t.Errorf("Expected report to complete after extract succeeded, got %s (%s)", execution.Status, execution.ErrorMessage)
+	}
+}
+
+func TestScheduler_Plan(t *testing.T) {
+	scheduler := NewScheduler()
+
+	workflows := []parser.Workflow{
+		{Name: "nightly", Schedule: "0 2 * * *", Tasks: []parser.Task{{ID: "task1", Command: "echo nightly"}}},
+		{Name: "manual", Tasks: []parser.Task{{ID: "task1", Command: "echo manual"}}},
+	}
+	if err := scheduler.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+
+	from := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	plans := scheduler.Plan(from, 2)
+	if len(plans) != 2 {
+		t.Fatalf("Expected 2 plans, got %d", len(plans))
+	}
+
+	expected := []time.Time{
+		time.Date(2024, time.March, 2, 2, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 3, 2, 0, 0, 0, time.UTC),
+	}
+	nightly := plans[0]
+	if len(nightly.NextRuns) != 2 || !nightly.NextRuns[0].Equal(expected[0]) || !nightly.NextRuns[1].Equal(expected[1]) {
+		t.Errorf("Expected next runs %v, got %v", expected, nightly.NextRuns)
+	}
+
+	if len(plans[1].NextRuns) != 0 {
+		t.Errorf("Expected no next runs for an unscheduled workflow, got %v", plans[1].NextRuns)
+	}
+}
+
+func TestScheduler_WaitForRunOfEarlierProcess(t *testing.T) {
+	dir := t.TempDir()
+	workflows := []parser.Workflow{
+		{
+			Name:     "extract",
+			Schedule: "0 0 * * *",
+			Tasks:    []parser.Task{{ID: "task1", Command: "echo extract"}},
+		},
+		{
+			Name:     "report",
+			Schedule: "0 6 * * *",
+			Tasks: []parser.Task{
+				{ID: "wait", Timeout: "200ms", WaitFor: &parser.ExternalDependency{Workflow: "extract", Within: "1h", PollInterval: "50ms"}},
+			},
+		},
+	}
+
+	before := NewScheduler()
+	before.SetStore(store.NewFileStore(dir))
+	if err := before.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+	if _, err := before.ExecuteWorkflowNow("extract"); err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	before.Stop()
+
+	// A restarted daemon finds the upstream run in the state directory
+	after := NewScheduler()
+	after.SetStore(store.NewFileStore(dir))
+	if err := after.AddWorkflows(workflows); err != nil {
+		t.Fatalf("AddWorkflows() error = %v", err)
+	}
+	execution, err := after.ExecuteWorkflowNow("report")
+	if err != nil {
+		t.Fatalf("ExecuteWorkflowNow() error = %v", err)
+	}
+	if execution.Status != "completed" {
+		t.Errorf("Expected report to complete after a restart, got %s (%s)", execution.Status, execution.ErrorMessage)
+	}
+}
+
+func TestPlanWorkflows_UnregisteredFunc(t *testing.T) {
+	workflows := []parser.Workflow{
+		{Name: "compute", Schedule: "0 2 * * *", Tasks: []parser.Task{{ID: "task1", Func: "compute"}}},
+	}
+	if err := NewScheduler().AddWorkflows(workflows); err == nil {
+		t.Fatal("Expected AddWorkflows to refuse an unregistered function")
+	}
+
+	plans := PlanWorkflows(workflows, time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC), 1)
+	if len(plans) != 1 || len(plans[0].Tasks) != 1 || len(plans[0].NextRuns) != 1 {
+		t.Fatalf("Expected a plan with one task and one next run, got %+v", plans)
+	}
+	if warnings := plans[0].Tasks[0].Warnings; len(warnings) != 1 || warnings[0] != "function 'compute' is not registered" {
+		t.Errorf("Expected a warning about the function, got %v", warnings)
+	}
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
+)
+
+// DefaultDir is where the CLI keeps run history unless told otherwise
+const DefaultDir = ".goliteflow"
+
+// Store persists finished workflow runs and which workflows are paused
+type Store interface {
+	// Save records a finished run
+	Save(execution parser.WorkflowExecution) error
+	// Latest returns the most recent run of a workflow with the given status,
+	// or nil if there is none. An empty status matches any run.
+	Latest(workflowName, status string) (*parser.WorkflowExecution, error)
+	// List returns every recorded run of a workflow, oldest first
+	List(workflowName string) ([]parser.WorkflowExecution, error)
+	// Get returns the run with the given ID, or nil if it is not recorded
+	Get(runID string) (*parser.WorkflowExecution, error)
+	// Paused returns the names of the paused workflows, sorted
+	Paused() ([]string, error)
+	// SetPaused records whether a workflow is paused
+	SetPaused(workflowName string, paused bool) error
+}
+
+// FileStore keeps one JSON file per run in dir/runs/<workflow>/ and the
+// paused workflows in dir/paused.json
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a file store rooted at dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes the run to its own file, named after its start time and ID
+func (fs *FileStore) Save(execution parser.WorkflowExecution) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	workflowDir := fs.workflowDir(execution.WorkflowID)
+	if err := os.MkdirAll(workflowDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(execution, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution: %w", err)
+	}
+
+	name := runFileName(execution)
+	if err := os.WriteFile(filepath.Join(workflowDir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write execution: %w", err)
+	}
+
+	return nil
+}
+
+// Latest returns the most recent run of a workflow with the given status, or
+// nil if there is none. An empty status matches any run.
+func (fs *FileStore) Latest(workflowName, status string) (*parser.WorkflowExecution, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	files, err := fs.runFiles(workflowName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		execution, err := readExecution(files[i])
+		if err != nil {
+			return nil, err
+		}
+		if status == "" || execution.Status == status {
+			return &execution, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns every recorded run of a workflow, oldest first
+func (fs *FileStore) List(workflowName string) ([]parser.WorkflowExecution, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	files, err := fs.runFiles(workflowName)
+	if err != nil {
+		return nil, err
+	}
+
+	executions := make([]parser.WorkflowExecution, 0, len(files))
+	for _, file := range files {
+		execution, err := readExecution(file)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, execution)
+	}
+	return executions, nil
+}
+
+// Workflows returns the names of the workflows with recorded runs, sorted
+func (fs *FileStore) Workflows() ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	entries, err := os.ReadDir(filepath.Join(fs.dir, "runs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue // not written by the store
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get returns the run with the given ID from the history of any workflow
+func (fs *FileStore) Get(runID string) (*parser.WorkflowExecution, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := runid.Validate(runID); err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(fs.dir, "runs", "*", "*-"+runID+".json"))
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	execution, err := readExecution(matches[0])
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// Paused returns the names of the paused workflows, sorted
+func (fs *FileStore) Paused() ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.readPaused()
+}
+
+// SetPaused adds a workflow to or removes it from paused.json
+func (fs *FileStore) SetPaused(workflowName string, paused bool) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	names, err := fs.readPaused()
+	if err != nil {
+		return err
+	}
+	var updated []string
+	for _, name := range names {
+		if name != workflowName {
+			updated = append(updated, name)
+		}
+	}
+	if paused {
+		updated = append(updated, workflowName)
+		sort.Strings(updated)
+	}
+
+	if err := os.MkdirAll(fs.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal paused workflows: %w", err)
+	}
+	// Replace the file in one step so a crash never leaves it half written
+	path := filepath.Join(fs.dir, pausedFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write paused workflows: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write paused workflows: %w", err)
+	}
+	return nil
+}
+
+// pausedFile lists the paused workflows in the state directory
+const pausedFile = "paused.json"
+
+func (fs *FileStore) readPaused() ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(fs.dir, pausedFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read paused workflows: %w", err)
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pausedFile, err)
+	}
+	return names, nil
+}
+
+// runFileName starts with the start time so that files sort chronologically,
+// including those of runs recorded before runs had IDs
+func runFileName(execution parser.WorkflowExecution) string {
+	name := execution.StartTime.UTC().Format("20060102T150405.000000000Z")
+	if execution.ID != "" {
+		name += "-" + execution.ID
+	}
+	return name + ".json"
+}
+
+// workflowDir escapes the workflow name so any name maps to a single directory
+func (fs *FileStore) workflowDir(workflowName string) string {
+	return filepath.Join(fs.dir, "runs", url.PathEscape(workflowName))
+}
+
+// runFiles lists the run files of a workflow; names sort chronologically
+func (fs *FileStore) runFiles(workflowName string) ([]string, error) {
+	entries, err := os.ReadDir(fs.workflowDir(workflowName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of workflow '%s': %w", workflowName, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(fs.workflowDir(workflowName), entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readExecution(path string) (parser.WorkflowExecution, error) {
+	var execution parser.WorkflowExecution
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return execution, fmt.Errorf("failed to read execution %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &execution); err != nil {
+		return execution, fmt.Errorf("failed to parse execution %s: %w", path, err)
+	}
+	return execution, nil
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/runid"
+)
+
+func TestFileStore_SaveAndLatest(t *testing.T) {
+	fs := NewFileStore(t.TempDir())
+
+	latest, err := fs.Latest("backup/nightly", "")
+	if err != nil || latest != nil {
+		t.Fatalf("Expected no runs for a new store, got %v, %v", latest, err)
+	}
+
+	start := time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)
+	runs := []parser.WorkflowExecution{
+		{WorkflowID: "backup/nightly", StartTime: start.Add(time.Hour), Status: "failed"},
+		{WorkflowID: "backup/nightly", StartTime: start, Status: "completed"},
+		{WorkflowID: "other", StartTime: start.Add(2 * time.Hour), Status: "completed"},
+	}
+	for _, run := range runs {
+		if err := fs.Save(run); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	latest, err = fs.Latest("backup/nightly", "")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if latest == nil || latest.Status != "failed" || !latest.StartTime.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected the failed run at 03:00, got %+v", latest)
+	}
+	latest, err = fs.Latest("backup/nightly", "completed")
+	if err != nil || latest == nil || !latest.StartTime.Equal(start) {
+		t.Errorf("Expected the completed run at 02:00, got %+v, %v", latest, err)
+	}
+
+	all, err := fs.List("backup/nightly")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 2 || all[0].Status != "completed" {
+		t.Errorf("Expected 2 runs oldest first, got %+v", all)
+	}
+}
+
+func TestFileStore_Get(t *testing.T) {
+	fs := NewFileStore(t.TempDir())
+
+	start := time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)
+	first := parser.WorkflowExecution{ID: runid.NewAt(start), WorkflowID: "backup", StartTime: start, Status: "failed"}
+	// Runs starting in the same instant are kept apart by their IDs
+	second := parser.WorkflowExecution{ID: runid.NewAt(start), WorkflowID: "backup", StartTime: start, Status: "completed"}
+	legacy := parser.WorkflowExecution{WorkflowID: "backup", StartTime: start.Add(-time.Hour), Status: "completed"}
+	for _, run := range []parser.WorkflowExecution{first, second, legacy} {
+		if err := fs.Save(run); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	got, err := fs.Get(first.ID)
+	if err != nil || got == nil || got.Status != "failed" {
+		t.Fatalf("Get() = %+v, %v; want the failed run", got, err)
+	}
+	if got, err := fs.Get(runid.New()); err != nil || got != nil {
+		t.Errorf("Expected no run for an unknown ID, got %+v, %v", got, err)
+	}
+	if _, err := fs.Get("../backup"); err == nil {
+		t.Error("Expected an error for a malformed ID")
+	}
+
+	if err := fs.Save(parser.WorkflowExecution{ID: runid.New(), WorkflowID: "a/b c", StartTime: start}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if names, err := fs.Workflows(); err != nil || strings.Join(names, ",") != "a/b c,backup" {
+		t.Errorf("Workflows() = %v, %v; want both workflows", names, err)
+	}
+
+	all, err := fs.List("backup")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 3 || all[0].ID != "" || all[1].ID != first.ID || all[2].ID != second.ID {
+		t.Errorf("Expected the legacy run first, then the runs in ID order, got %+v", all)
+	}
+}
+
+func TestFileStore_Paused(t *testing.T) {
+	dir := t.TempDir()
+	fs := NewFileStore(dir)
+
+	if paused, err := fs.Paused(); err != nil || len(paused) != 0 {
+		t.Fatalf("Paused() = %v, %v; want none", paused, err)
+	}
+	for _, name := range []string{"report", "backup", "report"} {
+		if err := fs.SetPaused(name, true); err != nil {
+			t.Fatalf("SetPaused() error = %v", err)
+		}
+	}
+	if err := fs.SetPaused("cleanup", false); err != nil {
+		t.Fatalf("SetPaused() error = %v", err)
+	}
+
+	// A new store on the same directory sees the pauses
+	paused, err := NewFileStore(dir).Paused()
+	if err != nil || strings.Join(paused, ",") != "backup,report" {
+		t.Fatalf("Paused() = %v, %v; want backup and report", paused, err)
+	}
+
+	if err := fs.SetPaused("backup", false); err != nil {
+		t.Fatalf("SetPaused() error = %v", err)
+	}
+	if paused, _ := fs.Paused(); strings.Join(paused, ",") != "report" {
+		t.Errorf("Expected only report to stay paused, got %v", paused)
+	}
+}
@@ -0,0 +1,65 @@
+// Package worker runs tasks away from the scheduler. The scheduler's
+// Dispatcher queues the attempts of tasks with runs_on labels, and workers
+// with those labels claim them over HTTP, report heartbeats while they run
+// them and post their results. Work of a worker that stops sending heartbeats
+// is handed to another worker.
+package worker
+
+import (
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+)
+
+// Paths of the HTTP API served by a Dispatcher
+const (
+	ClaimPath     = "/v1/claim"
+	HeartbeatPath = "/v1/heartbeat"
+	ResultPath    = "/v1/result"
+	WorkersPath   = "/v1/workers"
+)
+
+// TokenEnv holds the token shared by the scheduler and its workers
+const TokenEnv = "GOLITEFLOW_WORKER_TOKEN"
+
+// Defaults of the dispatcher and workers
+const (
+	DefaultHeartbeatInterval = 5 * time.Second
+	DefaultHeartbeatTimeout  = 30 * time.Second
+	DefaultMaxReassignments  = 2
+	defaultClaimWait         = 30 * time.Second
+)
+
+// claimRequest asks for one attempt whose labels the worker has
+type claimRequest struct {
+	Worker string        `json:"worker"`
+	Labels []string      `json:"labels"`
+	Wait   time.Duration `json:"wait"` // how long to wait for work before answering 204
+}
+
+// heartbeatRequest tells the dispatcher a worker is alive and what it runs
+type heartbeatRequest struct {
+	Worker  string   `json:"worker"`
+	Labels  []string `json:"labels"`
+	Running []string `json:"running"` // IDs of the attempts in progress
+}
+
+// heartbeatResponse lists the attempts the worker is to stop
+type heartbeatResponse struct {
+	Cancel []string `json:"cancel,omitempty"`
+}
+
+// resultRequest reports the result of an attempt
+type resultRequest struct {
+	Worker string                 `json:"worker"`
+	ID     string                 `json:"id"`
+	Result executor.CommandResult `json:"result"`
+}
+
+// Info describes a worker known to a dispatcher
+type Info struct {
+	ID       string    `json:"id"`
+	Labels   []string  `json:"labels"`
+	Running  int       `json:"running"`
+	LastSeen time.Time `json:"last_seen"`
+}
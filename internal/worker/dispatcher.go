@@ -0,0 +1,503 @@
+package worker
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+)
+
+// errClosed answers the claims made after Close
+var errClosed = errors.New("worker dispatcher is closed")
+
+// item is an attempt waiting for a worker or running on one
+type item struct {
+	work        executor.Work
+	ctx         context.Context
+	result      chan executor.CommandResult // receives the result once
+	worker      string                      // worker running it, "" while pending
+	assigned    time.Time
+	reassigned  int
+	cancelled   bool
+	enqueueTime time.Time
+}
+
+// workerState is what the dispatcher knows about a worker
+type workerState struct {
+	labels   []string
+	lastSeen time.Time
+	running  map[string]bool // IDs of the attempts assigned to it
+}
+
+// Dispatcher is a WorkQueue handing attempts of tasks with runs_on labels to
+// workers. Attempts of other tasks run with its local queue. A worker takes an
+// attempt when it has every label of the task, in the order they were queued.
+type Dispatcher struct {
+	local executor.WorkQueue
+	token string
+	log   *logger.Logger
+
+	heartbeatTimeout time.Duration
+	maxReassignments int
+
+	mu      sync.Mutex
+	pending []*item          // waiting for a worker, oldest first
+	items   map[string]*item // pending and running, by work ID
+	workers map[string]*workerState
+	wake    chan struct{} // closed when work is queued
+	closed  bool
+
+	done   chan struct{}
+	reaped chan struct{}
+}
+
+// NewDispatcher creates a dispatcher running attempts without labels with
+// local, or with a runner of its own when local is nil
+func NewDispatcher(local executor.WorkQueue) *Dispatcher {
+	if local == nil {
+		local = executor.NewLocalQueue(executor.NewTaskRunner())
+	}
+	d := &Dispatcher{
+		local:            local,
+		log:              logger.GetGlobalLogger(),
+		heartbeatTimeout: DefaultHeartbeatTimeout,
+		maxReassignments: DefaultMaxReassignments,
+		items:            make(map[string]*item),
+		workers:          make(map[string]*workerState),
+		wake:             make(chan struct{}),
+		done:             make(chan struct{}),
+		reaped:           make(chan struct{}),
+	}
+	go d.reap()
+	return d
+}
+
+// SetToken requires workers to send token as a bearer token
+func (d *Dispatcher) SetToken(token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.token = token
+}
+
+// SetHeartbeatTimeout sets how long a worker may go without a heartbeat
+// before its attempts are handed to other workers. Zero or less restores
+// DefaultHeartbeatTimeout.
+func (d *Dispatcher) SetHeartbeatTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultHeartbeatTimeout
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.heartbeatTimeout = timeout
+}
+
+// SetMaxReassignments sets how often an attempt is handed to another worker
+// after losing its worker, before it fails
+func (d *Dispatcher) SetMaxReassignments(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maxReassignments = n
+}
+
+// Execute queues an attempt for a worker with its labels and waits for its
+// result, or runs it locally when the task has no labels
+func (d *Dispatcher) Execute(ctx context.Context, work executor.Work) executor.CommandResult {
+	if len(work.Task.RunsOn) == 0 {
+		return d.local.Execute(ctx, work)
+	}
+
+	it := &item{work: work, ctx: ctx, result: make(chan executor.CommandResult, 1), enqueueTime: time.Now()}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return executor.CommandResult{ExitCode: 1, Error: errClosed.Error()}
+	}
+	d.items[work.ID] = it
+	d.pending = append(d.pending, it)
+	d.signal()
+	available := d.hasWorker(work.Task.RunsOn)
+	d.mu.Unlock()
+
+	log := d.log.WithWorkflow(work.WorkflowID).WithTask(work.Task.ID)
+	if available {
+		log.Debugf("Queued attempt %d for a worker with labels %s", work.Attempt, strings.Join(work.Task.RunsOn, ", "))
+	} else {
+		log.Warnf("No worker with labels %s is connected; the task waits for one", strings.Join(work.Task.RunsOn, ", "))
+	}
+
+	select {
+	case result := <-it.result:
+		return result
+	case <-ctx.Done():
+	}
+
+	// Stop the attempt: a pending one is dropped, a running one is cancelled
+	// on the worker's next heartbeat
+	d.mu.Lock()
+	d.remove(it)
+	if it.worker != "" {
+		it.cancelled = true
+	}
+	worker := it.worker
+	d.mu.Unlock()
+
+	// The result may have arrived at the same time
+	select {
+	case result := <-it.result:
+		return result
+	default:
+	}
+	return executor.CommandResult{ExitCode: 1, Error: ctx.Err().Error(), Worker: worker}
+}
+
+// Workers lists the workers seen within the heartbeat timeout
+func (d *Dispatcher) Workers() []Info {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	workers := make([]Info, 0, len(d.workers))
+	for id, state := range d.workers {
+		workers = append(workers, Info{ID: id, Labels: state.labels, Running: len(state.running), LastSeen: state.lastSeen})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers
+}
+
+// Pending returns the number of attempts waiting for a worker
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Close fails the attempts waiting for or running on a worker and stops
+// handing out work
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, it := range d.items {
+		d.finish(it, executor.CommandResult{ExitCode: 1, Error: errClosed.Error(), Worker: it.worker})
+	}
+	d.signal()
+	d.mu.Unlock()
+
+	close(d.done)
+	<-d.reaped
+}
+
+// claim assigns the oldest pending attempt whose labels the worker has,
+// waiting up to wait for one
+func (d *Dispatcher) claim(ctx context.Context, req claimRequest) (*executor.Work, error) {
+	timer := time.NewTimer(req.Wait)
+	defer timer.Stop()
+
+	for {
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			return nil, errClosed
+		}
+		state := d.seen(req.Worker, req.Labels)
+		for i, it := range d.pending {
+			if !hasLabels(req.Labels, it.work.Task.RunsOn) || it.ctx.Err() != nil {
+				continue
+			}
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			it.worker = req.Worker
+			it.assigned = time.Now()
+			state.running[it.work.ID] = true
+
+			// The time left is counted from now, not from when it was queued
+			work := it.work
+			if deadline, ok := it.ctx.Deadline(); ok {
+				work.Timeout = time.Until(deadline)
+			}
+			d.mu.Unlock()
+
+			d.log.WithWorkflow(work.WorkflowID).WithTask(work.Task.ID).
+				Infof("Worker %s claimed attempt %d after %v in the queue", req.Worker, work.Attempt, time.Since(it.enqueueTime).Round(time.Millisecond))
+			return &work, nil
+		}
+		wake := d.wake
+		d.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// heartbeat records that a worker is alive and returns the attempts it runs
+// that it is to stop: cancelled ones and ones handed to another worker
+func (d *Dispatcher) heartbeat(req heartbeatRequest) heartbeatResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := d.seen(req.Worker, req.Labels)
+	var response heartbeatResponse
+	running := make(map[string]bool, len(req.Running))
+	for _, id := range req.Running {
+		running[id] = true
+		it, ok := d.items[id]
+		if !ok || it.cancelled || it.worker != req.Worker {
+			response.Cancel = append(response.Cancel, id)
+		}
+	}
+
+	// An attempt the worker has long stopped reporting never reached it,
+	// as when the answer to its claim was lost
+	for id := range state.running {
+		it, ok := d.items[id]
+		if ok && !running[id] && time.Since(it.assigned) > d.heartbeatTimeout {
+			delete(state.running, id)
+			d.requeue(it, req.Worker)
+		}
+	}
+	return response
+}
+
+// report delivers the result of an attempt, unless the worker no longer
+// runs it
+func (d *Dispatcher) report(req resultRequest) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if state, ok := d.workers[req.Worker]; ok {
+		state.lastSeen = time.Now()
+		delete(state.running, req.ID)
+	}
+	it, ok := d.items[req.ID]
+	if !ok || it.worker != req.Worker {
+		return false
+	}
+	result := req.Result
+	result.Worker = req.Worker
+	d.finish(it, result)
+	return true
+}
+
+// reap hands the attempts of workers that stopped sending heartbeats to
+// other workers
+func (d *Dispatcher) reap() {
+	defer close(d.reaped)
+
+	for {
+		d.mu.Lock()
+		interval := d.heartbeatTimeout / 4
+		d.mu.Unlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-d.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		d.mu.Lock()
+		for id, state := range d.workers {
+			if time.Since(state.lastSeen) <= d.heartbeatTimeout {
+				continue
+			}
+			delete(d.workers, id)
+			d.log.Warnf("Worker %s stopped sending heartbeats", id)
+
+			for workID := range state.running {
+				it, ok := d.items[workID]
+				if !ok || it.worker != id {
+					continue
+				}
+				d.requeue(it, id)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// requeue hands an attempt that lost its worker to another worker, or fails
+// it once it lost too many; d.mu is held
+func (d *Dispatcher) requeue(it *item, worker string) {
+	if it.reassigned >= d.maxReassignments {
+		d.finish(it, executor.CommandResult{ExitCode: 1, Worker: worker,
+			Error: fmt.Sprintf("lost worker %s; the attempt lost %d worker(s)", worker, it.reassigned+1)})
+		return
+	}
+	it.reassigned++
+	it.worker = ""
+	d.pending = append([]*item{it}, d.pending...)
+	d.signal()
+	d.log.WithWorkflow(it.work.WorkflowID).WithTask(it.work.Task.ID).
+		Warnf("Handing attempt %d of worker %s to another worker", it.work.Attempt, worker)
+}
+
+// seen records a request of a worker, registering it if needed; d.mu is held
+func (d *Dispatcher) seen(id string, labels []string) *workerState {
+	state, ok := d.workers[id]
+	if !ok {
+		state = &workerState{running: make(map[string]bool)}
+		d.workers[id] = state
+		d.log.Infof("Worker %s connected with labels %s", id, strings.Join(labels, ", "))
+	}
+	state.labels = labels
+	state.lastSeen = time.Now()
+	return state
+}
+
+// hasWorker reports whether a connected worker has the labels; d.mu is held
+func (d *Dispatcher) hasWorker(labels []string) bool {
+	for _, state := range d.workers {
+		if hasLabels(state.labels, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish delivers the result of an attempt; d.mu is held
+func (d *Dispatcher) finish(it *item, result executor.CommandResult) {
+	d.remove(it)
+	select {
+	case it.result <- result:
+	default:
+	}
+}
+
+// remove forgets an attempt; d.mu is held
+func (d *Dispatcher) remove(it *item) {
+	delete(d.items, it.work.ID)
+	for i, pending := range d.pending {
+		if pending == it {
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			break
+		}
+	}
+	if state, ok := d.workers[it.worker]; ok {
+		delete(state.running, it.work.ID)
+	}
+}
+
+// signal wakes the workers waiting for work; d.mu is held
+func (d *Dispatcher) signal() {
+	close(d.wake)
+	d.wake = make(chan struct{})
+}
+
+// hasLabels reports whether have contains every label of want
+func hasLabels(have, want []string) bool {
+	for _, label := range want {
+		found := false
+		for _, h := range have {
+			if h == label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler serves the API workers use
+func (d *Dispatcher) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ClaimPath, d.post(func(w http.ResponseWriter, r *http.Request) {
+		var req claimRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.Wait <= 0 || req.Wait > defaultClaimWait {
+			req.Wait = defaultClaimWait
+		}
+		work, err := d.claim(r.Context(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if work == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, work)
+	}))
+	mux.HandleFunc(HeartbeatPath, d.post(func(w http.ResponseWriter, r *http.Request) {
+		var req heartbeatRequest
+		if decode(w, r, &req) {
+			writeJSON(w, d.heartbeat(req))
+		}
+	}))
+	mux.HandleFunc(ResultPath, d.post(func(w http.ResponseWriter, r *http.Request) {
+		var req resultRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if !d.report(req) {
+			http.Error(w, fmt.Sprintf("attempt %s is not assigned to worker %s", req.ID, req.Worker), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc(WorkersPath, d.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Workers())
+	}))
+	return mux
+}
+
+// post accepts only authorized POST requests
+func (d *Dispatcher) post(handler http.HandlerFunc) http.HandlerFunc {
+	return d.authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// authorized checks the bearer token, if one is set
+func (d *Dispatcher) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		token := d.token
+		d.mu.Unlock()
+
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "invalid worker token", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,280 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/logger"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// Config configures a Worker
+type Config struct {
+	Server            string        // base URL of the scheduler's worker API, e.g. http://scheduler:7070
+	ID                string        // default host:pid
+	Labels            []string      // labels of the tasks the worker runs
+	Concurrency       int           // attempts run at once, default 1
+	Token             string        // bearer token the scheduler expects, if any
+	HeartbeatInterval time.Duration // default DefaultHeartbeatInterval
+}
+
+// Worker claims attempts from a scheduler's Dispatcher and runs them
+type Worker struct {
+	config Config
+	runner *executor.TaskRunner
+	client *http.Client
+	log    *logger.Logger
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // attempts in progress, by work ID
+}
+
+// errConflict is returned when the scheduler no longer expects a result
+var errConflict = errors.New("the scheduler no longer expects this result")
+
+// New creates a worker from config
+func New(config Config) (*Worker, error) {
+	if config.Server == "" {
+		return nil, fmt.Errorf("worker needs the URL of a scheduler")
+	}
+	config.Server = strings.TrimSuffix(config.Server, "/")
+	if !strings.HasPrefix(config.Server, "http://") && !strings.HasPrefix(config.Server, "https://") {
+		config.Server = "http://" + config.Server
+	}
+	for _, label := range config.Labels {
+		if !parser.ValidLabel(label) {
+			return nil, fmt.Errorf("invalid label '%s' (expected letters, digits, '_', '.' and '-')", label)
+		}
+	}
+	if config.ID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "worker"
+		}
+		config.ID = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	return &Worker{
+		config:  config,
+		runner:  executor.NewTaskRunner(),
+		client:  &http.Client{},
+		log:     logger.GetGlobalLogger(),
+		running: make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// ID returns the ID the worker reports to the scheduler
+func (w *Worker) ID() string {
+	return w.config.ID
+}
+
+// Run claims and runs attempts until ctx is done, then finishes the attempts
+// in progress and reports their results before returning
+func (w *Worker) Run(ctx context.Context) error {
+	w.log.Infof("Worker %s with labels %s claiming work from %s", w.config.ID, strings.Join(w.config.Labels, ", "), w.config.Server)
+
+	// Heartbeats go on while the attempts in progress finish
+	heartbeatCtx, stopHeartbeats := context.WithCancel(context.Background())
+	heartbeatsDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatsDone)
+		w.heartbeats(heartbeatCtx)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.claimLoop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	stopHeartbeats()
+	<-heartbeatsDone
+	w.log.Infof("Worker %s stopped", w.config.ID)
+	return nil
+}
+
+// claimLoop claims one attempt at a time until ctx is done
+func (w *Worker) claimLoop(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		work, err := w.claim(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.log.Warnf("Failed to claim work from %s: %v", w.config.Server, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			continue
+		}
+		backoff = time.Second
+		if work != nil {
+			w.execute(*work)
+		}
+	}
+}
+
+// execute runs an attempt and reports its result. The attempt is stopped
+// only when the scheduler cancels it, not when the worker shuts down.
+func (w *Worker) execute(work executor.Work) {
+	log := w.log.WithWorkflow(work.WorkflowID).WithTask(work.Task.ID).WithExecution(work.RunID)
+	log.Infof("Running attempt %d", work.Attempt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.mu.Lock()
+	w.running[work.ID] = cancel
+	w.mu.Unlock()
+
+	start := time.Now()
+	result := w.runner.RunWork(ctx, work)
+	cancel()
+	if result.ExitCode == 0 {
+		log.Infof("Attempt %d succeeded in %v", work.Attempt, time.Since(start).Round(time.Millisecond))
+	} else {
+		log.Warnf("Attempt %d failed with exit code %d in %v", work.Attempt, result.ExitCode, time.Since(start).Round(time.Millisecond))
+	}
+
+	// The scheduler may be restarting, so the result is sent again for a while
+	delay := time.Second
+	for attempt := 0; attempt < 8; attempt++ {
+		err := w.post(context.Background(), ResultPath, resultRequest{Worker: w.config.ID, ID: work.ID, Result: result}, nil)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, errConflict) {
+			log.Warnf("Result of attempt %d discarded: the attempt was cancelled or handed to another worker", work.Attempt)
+			break
+		}
+		log.Warnf("Failed to report result of attempt %d: %v", work.Attempt, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	w.mu.Lock()
+	delete(w.running, work.ID)
+	w.mu.Unlock()
+}
+
+// claim asks the scheduler for an attempt, waiting for one
+func (w *Worker) claim(ctx context.Context) (*executor.Work, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultClaimWait+10*time.Second)
+	defer cancel()
+
+	var work executor.Work
+	req := claimRequest{Worker: w.config.ID, Labels: w.config.Labels, Wait: defaultClaimWait}
+	if err := w.post(ctx, ClaimPath, req, &work); err != nil {
+		return nil, err
+	}
+	if work.ID == "" {
+		return nil, nil
+	}
+	return &work, nil
+}
+
+// heartbeats tells the scheduler the worker is alive until ctx is done, and
+// stops the attempts it cancels
+func (w *Worker) heartbeats(ctx context.Context) {
+	ticker := time.NewTicker(w.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		running := make([]string, 0, len(w.running))
+		for id := range w.running {
+			running = append(running, id)
+		}
+		w.mu.Unlock()
+
+		var response heartbeatResponse
+		req := heartbeatRequest{Worker: w.config.ID, Labels: w.config.Labels, Running: running}
+		reqCtx, cancel := context.WithTimeout(ctx, w.config.HeartbeatInterval)
+		err := w.post(reqCtx, HeartbeatPath, req, &response)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				w.log.Warnf("Failed to send heartbeat to %s: %v", w.config.Server, err)
+			}
+			continue
+		}
+
+		w.mu.Lock()
+		for _, id := range response.Cancel {
+			if cancelAttempt, ok := w.running[id]; ok {
+				w.log.Infof("Stopping attempt %s at the scheduler's request", id)
+				cancelAttempt()
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+// post sends a JSON request to the scheduler and decodes the response into
+// out, if any. A 204 response leaves out unchanged.
+func (w *Worker) post(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.config.Token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode == http.StatusConflict:
+		return errConflict
+	case resp.StatusCode != http.StatusOK:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+)
+
+// startWorker runs a worker against server until the test ends
+func startWorker(t *testing.T, server, id string, labels ...string) {
+	t.Helper()
+	w, err := New(Config{Server: server, ID: id, Labels: labels, Token: "secret", HeartbeatInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, *httptest.Server) {
+	t.Helper()
+	d := NewDispatcher(nil)
+	d.SetToken("secret")
+	d.SetHeartbeatTimeout(300 * time.Millisecond)
+	server := httptest.NewServer(d.Handler())
+	t.Cleanup(func() {
+		d.Close()
+		server.Close()
+	})
+	return d, server
+}
+
+func work(id, command string, labels ...string) executor.Work {
+	return executor.Work{ID: id, RunID: "run-" + id, WorkflowID: "etl", Attempt: 1,
+		Task: parser.Task{ID: id, Command: command, RunsOn: labels}, Command: command}
+}
+
+func TestDispatcher_Labels(t *testing.T) {
+	d, server := newTestDispatcher(t)
+	startWorker(t, server.URL, "etl-worker", "etl", "gpu-free")
+
+	tests := []struct {
+		name       string
+		work       executor.Work
+		wantWorker string
+		wantStdout string
+		wantError  string
+	}{
+		{"matching labels", work("extract", "echo extracted", "etl"), "etl-worker", "extracted\n", ""},
+		{"all labels needed", work("train", "echo trained", "etl", "gpu-free"), "etl-worker", "trained\n", ""},
+		{"no labels runs locally", work("local", "echo local"), "", "local\n", ""},
+		{"no matching worker", work("render", "echo rendered", "gpu"), "", "", "context deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			result := d.Execute(ctx, tt.work)
+			if result.Worker != tt.wantWorker || result.Stdout != tt.wantStdout {
+				t.Errorf("Execute() = %+v, want stdout %q from worker %q", result, tt.wantStdout, tt.wantWorker)
+			}
+			if tt.wantError != "" && !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Execute() error = %q, want %q", result.Error, tt.wantError)
+			}
+		})
+	}
+
+	if pending := d.Pending(); pending != 0 {
+		t.Errorf("Expected no pending attempts, got %d", pending)
+	}
+	if workers := d.Workers(); len(workers) != 1 || workers[0].ID != "etl-worker" {
+		t.Errorf("Unexpected workers: %+v", workers)
+	}
+}
+
+func TestDispatcher_ReassignsLostWorker(t *testing.T) {
+	d, server := newTestDispatcher(t)
+
+	results := make(chan executor.CommandResult, 1)
+	go func() {
+		results <- d.Execute(context.Background(), work("extract", "echo extracted", "etl"))
+	}()
+
+	// A worker claims the attempt and dies without sending a heartbeat
+	claimed, err := d.claim(context.Background(), claimRequest{Worker: "dead", Labels: []string{"etl"}, Wait: time.Second})
+	if err != nil || claimed == nil {
+		t.Fatalf("claim() = %v, %v", claimed, err)
+	}
+
+	startWorker(t, server.URL, "alive", "etl")
+	select {
+	case result := <-results:
+		if result.Worker != "alive" || result.Stdout != "extracted\n" {
+			t.Errorf("Expected the attempt to run on the live worker, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Attempt of the lost worker was not reassigned")
+	}
+
+	// The dead worker's late result is refused
+	if d.report(resultRequest{Worker: "dead", ID: claimed.ID}) {
+		t.Error("Expected the result of a reassigned attempt to be refused")
+	}
+}
+
+func TestDispatcher_FailsAfterMaxReassignments(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+	d.SetMaxReassignments(0)
+
+	results := make(chan executor.CommandResult, 1)
+	go func() {
+		results <- d.Execute(context.Background(), work("extract", "echo extracted", "etl"))
+	}()
+	if claimed, err := d.claim(context.Background(), claimRequest{Worker: "dead", Labels: []string{"etl"}, Wait: time.Second}); err != nil || claimed == nil {
+		t.Fatalf("claim() = %v, %v", claimed, err)
+	}
+
+	select {
+	case result := <-results:
+		if result.ExitCode == 0 || !strings.Contains(result.Error, "lost worker dead") {
+			t.Errorf("Expected the attempt to fail, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Attempt of the lost worker did not fail")
+	}
+}
+
+func TestDispatcher_Cancel(t *testing.T) {
+	d, server := newTestDispatcher(t)
+	startWorker(t, server.URL, "etl-worker", "etl")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan executor.CommandResult, 1)
+	go func() {
+		results <- d.Execute(ctx, work("slow", "sleep 10", "etl"))
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(d.Workers()) == 0 || d.Workers()[0].Running == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Worker did not claim the attempt")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	result := <-results
+	if result.ExitCode == 0 || result.Error != context.Canceled.Error() {
+		t.Errorf("Expected a cancelled attempt, got %+v", result)
+	}
+
+	// The worker stops the command on its next heartbeat and is free again
+	second := make(chan executor.CommandResult, 1)
+	go func() {
+		second <- d.Execute(context.Background(), work("next", "echo next", "etl"))
+	}()
+	select {
+	case result := <-second:
+		if result.Stdout != "next\n" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Worker kept running the cancelled attempt")
+	}
+}
+
+func TestDispatcher_Token(t *testing.T) {
+	_, server := newTestDispatcher(t)
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"token", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+WorkersPath, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", WorkersPath, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("Status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcher_SetHeartbeatTimeoutDefault(t *testing.T) {
+	d := NewDispatcher(nil)
+	defer d.Close()
+
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		d.SetHeartbeatTimeout(timeout)
+		d.mu.Lock()
+		got := d.heartbeatTimeout
+		d.mu.Unlock()
+		if got != DefaultHeartbeatTimeout {
+			t.Errorf("SetHeartbeatTimeout(%v) = %v, want %v", timeout, got, DefaultHeartbeatTimeout)
+		}
+	}
+}
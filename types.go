@@ -0,0 +1,207 @@
+package goliteflow
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sintakaridina/goliteflow/internal/events"
+	"github.com/sintakaridina/goliteflow/internal/executor"
+	"github.com/sintakaridina/goliteflow/internal/leader"
+	"github.com/sintakaridina/goliteflow/internal/parser"
+	"github.com/sintakaridina/goliteflow/internal/scheduler"
+	"github.com/sintakaridina/goliteflow/internal/worker"
+)
+
+// Configuration types, shared with the YAML parser so that workflows built in
+// Go and loaded from files are interchangeable
+
+// WorkflowConfig is the root of a configuration
+type WorkflowConfig = parser.WorkflowConfig
+
+// Workflow is a named set of tasks with a schedule or triggers
+type Workflow = parser.Workflow
+
+// WorkflowTrigger starts a workflow when another workflow finishes
+type WorkflowTrigger = parser.WorkflowTrigger
+
+// Task is a single step of a workflow
+type Task = parser.Task
+
+// ExternalDependency makes a task wait for a successful run of another workflow
+type ExternalDependency = parser.ExternalDependency
+
+// Resources limits the memory, CPU time, processes and open files of a task
+type Resources = parser.Resources
+
+// RunAs sets the user and group a task's process runs as
+type RunAs = parser.RunAs
+
+// Sandbox restricts what a task's process can see and change
+type Sandbox = parser.Sandbox
+
+// Container configures the container of a task with an image
+type Container = parser.Container
+
+// HTTPRequest is a task that sends a request instead of running a command
+type HTTPRequest = parser.HTTPRequest
+
+// HTTPAuth authenticates an HTTPRequest
+type HTTPAuth = parser.HTTPAuth
+
+// HTTPExpect lists the checks a response must pass
+type HTTPExpect = parser.HTTPExpect
+
+// JSONAssertion checks a value of a JSON response
+type JSONAssertion = parser.JSONAssertion
+
+// Notifications lists the channels notified when runs fail, succeed, recover or retry
+type Notifications = parser.Notifications
+
+// NotificationChannel is an email, webhook or command notified with a message
+type NotificationChannel = parser.NotificationChannel
+
+// EmailChannel sends notifications over SMTP
+type EmailChannel = parser.EmailChannel
+
+// WebhookChannel posts notifications as JSON
+type WebhookChannel = parser.WebhookChannel
+
+// SLA sets the expected duration, deadline and reliability of a workflow or task
+type SLA = parser.SLA
+
+// SLAViolation records a breached SLA of a run or one of its tasks
+type SLAViolation = parser.SLAViolation
+
+// Pool is a named set of slots that tasks take with pool and pool_slots
+type Pool = parser.Pool
+
+// Result types
+
+// WorkflowExecution is the result of a workflow run
+type WorkflowExecution = parser.WorkflowExecution
+
+// ExecutionResult is the result of a task within a run
+type ExecutionResult = parser.ExecutionResult
+
+// SchedulerStats summarises the workflows and runs of a scheduler
+type SchedulerStats = scheduler.SchedulerStats
+
+// RunningExecution is the live state of a run in progress
+type RunningExecution = scheduler.RunningExecution
+
+// QueueStats counts the tasks running and waiting for slots; see SchedulerStats
+type QueueStats = executor.QueueStats
+
+// PoolStats counts the slots of a pool in use and the tasks waiting for them
+type PoolStats = executor.PoolStats
+
+// ValidationError is a problem found in a configuration, with its position
+type ValidationError = parser.ValidationError
+
+// ValidationErrors holds every problem found in a configuration
+type ValidationErrors = parser.ValidationErrors
+
+// Trigger statuses accepted by WorkflowTrigger
+const (
+	TriggerOnCompleted = parser.TriggerOnCompleted
+	TriggerOnFailed    = parser.TriggerOnFailed
+	TriggerOnAny       = parser.TriggerOnAny
+)
+
+// What started a run, recorded in WorkflowExecution.Trigger
+const (
+	RunTriggerCron   = parser.RunTriggerCron
+	RunTriggerManual = parser.RunTriggerManual
+	RunTriggerEvent  = parser.RunTriggerEvent
+)
+
+// Event types
+
+// Event is something that happened to a workflow or task, passed to Subscribe handlers
+type Event = events.Event
+
+// EventType identifies what happened
+type EventType = events.Type
+
+// EventHandler receives events
+type EventHandler = events.Handler
+
+// SubscribeOption configures how a handler receives events
+type SubscribeOption = events.Option
+
+// Event types, in the order they occur for a run
+const (
+	WorkflowScheduled = events.WorkflowScheduled
+	WorkflowStarted   = events.WorkflowStarted
+	TaskQueued        = events.TaskQueued
+	TaskStarted       = events.TaskStarted
+	TaskRetrying      = events.TaskRetrying
+	TaskFinished      = events.TaskFinished
+	WorkflowFinished  = events.WorkflowFinished
+	WorkflowSkipped   = events.WorkflowSkipped
+	SLAMissed         = events.SLAMissed
+)
+
+// SyncDelivery calls the handler in the goroutine running the workflow, so the
+// run waits for it
+func SyncDelivery() SubscribeOption {
+	return events.Sync()
+}
+
+// DefaultEventQueueSize is how many events a handler subscribed without
+// options queues before runs wait for it to catch up
+const DefaultEventQueueSize = events.DefaultQueueSize
+
+// BestEffortDelivery queues up to size events for the handler and drops
+// events while the queue is full, so a slow handler never holds up runs
+func BestEffortDelivery(size int) SubscribeOption {
+	return events.BestEffort(size)
+}
+
+// Leader election
+
+// LeaderElector elects the one instance that fires cron runs among instances
+// running the same workflows; see SetLeaderElector
+type LeaderElector = leader.Elector
+
+// FileLease elects a leader through a lease file on a shared filesystem
+type FileLease = leader.FileLease
+
+// SQLLease elects a leader through a row of a database table
+type SQLLease = leader.SQLLease
+
+// NewFileLease creates a leader elector using the lease file at path, which
+// every instance must reach. A ttl of zero means 30 seconds.
+func NewFileLease(path string, ttl time.Duration) *FileLease {
+	return leader.NewFileLease(path, ttl)
+}
+
+// NewSQLLease creates a leader elector using the row called name of the
+// goliteflow_leases table in db, created when missing. A ttl of zero means
+// 30 seconds.
+func NewSQLLease(db *sql.DB, name string, ttl time.Duration) *SQLLease {
+	return leader.NewSQLLease(db, name, ttl)
+}
+
+// Workers
+
+// WorkQueue runs the attempts of command, container and HTTP tasks on behalf
+// of the scheduler; see SetWorkQueue
+type WorkQueue = executor.WorkQueue
+
+// Work is one attempt of a task handed to a WorkQueue
+type Work = executor.Work
+
+// CommandResult is the result of one attempt of a task
+type CommandResult = executor.CommandResult
+
+// WorkerDispatcher is a WorkQueue handing the attempts of tasks with runs_on
+// labels to workers started with 'goliteflow worker'. Serve its Handler over
+// HTTP where the workers reach it.
+type WorkerDispatcher = worker.Dispatcher
+
+// NewWorkerDispatcher creates a dispatcher running tasks without runs_on
+// labels in this process
+func NewWorkerDispatcher() *WorkerDispatcher {
+	return worker.NewDispatcher(nil)
+}